@@ -0,0 +1,253 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+// Rule grants access to the keys starting with `Prefix`, an empty prefix granting access to all
+// keys. The keys can only be read unless `Write` is set.
+type Rule struct {
+	Prefix []byte
+	Write  bool
+}
+
+// Store wraps a backing store and restricts the keys reachable through it to the prefixes
+// granted by its rules, any other access failing with `store.ErrForbidden` before reaching the
+// backing store.
+//
+// Point operations are checked against the key they touch, batch operations against each of
+// their keys, all of them being checked before anything is done. A `Prefix` scan must be
+// covered by a single readable prefix, which means the scanned prefix must start with it, and a
+// `Scan` must stay within one, from `start` to `exclusiveEnd`. Results are never filtered, a scan
+// reaching outside of the granted prefixes is rejected as a whole.
+type Store struct {
+	dsn     string
+	backing store.KVStore
+	rules   []Rule
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("acl kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "acl",
+		Title:       "ACL",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports acl://?read=<hex prefix>&write=<hex prefix>&backing=<url escaped dsn>,
+// `read` and `write` being repeatable and `write` also granting reads. An empty prefix grants
+// access to all keys, no prefix at all grants access to none.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("acl new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+	var rules []Rule
+	for _, param := range []string{"read", "write"} {
+		for _, raw := range query[param] {
+			prefix, err := hex.DecodeString(raw)
+			if err != nil {
+				return nil, fmt.Errorf("acl new: invalid %s prefix %q, expecting hexadecimal: %w", param, raw, err)
+			}
+			rules = append(rules, Rule{Prefix: prefix, Write: param == "write"})
+		}
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("acl new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("acl new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Int("rule_count", len(rules)))
+
+	s := Wrap(backing, rules)
+	s.dsn = dsnString
+	return s, nil
+}
+
+// Wrap restricts `backing` to `rules`, for callers sharing a backing store between many
+// restricted views of it, like the netkv server does for each of its tokens. Closing the
+// returned store closes `backing`.
+func Wrap(backing store.KVStore, rules []Rule) *Store {
+	return &Store{
+		backing: backing,
+		rules:   rules,
+	}
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+// checkKey returns `store.ErrForbidden` when no rule grants access to `key`, for writing when
+// `write` is set
+func (s *Store) checkKey(key []byte, write bool) error {
+	for _, rule := range s.rules {
+		if (rule.Write || !write) && bytes.HasPrefix(key, rule.Prefix) {
+			return nil
+		}
+	}
+	return store.ErrForbidden
+}
+
+func (s *Store) checkKeys(op string, keys [][]byte, write bool) error {
+	for _, key := range keys {
+		if err := s.checkKey(key, write); err != nil {
+			return store.WrapKeyError(op, key, err)
+		}
+	}
+	return nil
+}
+
+// checkScan returns `store.ErrForbidden` unless a single readable prefix covers all the keys
+// from `start` to `exclusiveEnd`
+func (s *Store) checkScan(start, exclusiveEnd []byte) error {
+	for _, rule := range s.rules {
+		if len(rule.Prefix) == 0 {
+			return nil
+		}
+
+		if bytes.HasPrefix(start, rule.Prefix) && len(exclusiveEnd) > 0 && bytes.Compare(exclusiveEnd, store.Key(rule.Prefix).PrefixNext()) <= 0 {
+			return nil
+		}
+	}
+	return store.ErrForbidden
+}
+
+func forbiddenIterator(ctx context.Context, err error) *store.Iterator {
+	it := store.NewIterator(ctx)
+	it.PushError(err)
+	return it
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return store.WrapKeyError("put", key, err)
+	}
+	return s.backing.Put(ctx, key, value)
+}
+
+// Insert checks the key like `Put` does, the backing store checking its existence.
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return store.WrapKeyError("insert", key, err)
+	}
+	return store.Insert(ctx, s.backing, key, value)
+}
+
+// Increment requires write access to the counter key, a read-only grant cannot increment it.
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return 0, store.WrapKeyError("increment", key, err)
+	}
+	return store.Increment(ctx, s.backing, key, delta)
+}
+
+// CompareAndSwap requires write access to the key, even when the swap ends up not happening.
+func (s *Store) CompareAndSwap(ctx context.Context, key, old, new []byte) (swapped bool, err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return false, store.WrapKeyError("compare and swap", key, err)
+	}
+	return store.CompareAndSwap(ctx, s.backing, key, old, new)
+}
+
+// CompareAndSwapWithTTL is checked like `CompareAndSwap`, the backing store expiring the value.
+func (s *Store) CompareAndSwapWithTTL(ctx context.Context, key, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return false, store.WrapKeyError("compare and swap", key, err)
+	}
+	return store.CompareAndSwapWithTTL(ctx, s.backing, key, old, new, ttl)
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	return s.backing.FlushPuts(ctx)
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	if err := s.checkKey(key, false); err != nil {
+		return nil, store.WrapKeyError("get", key, err)
+	}
+	return s.backing.Get(ctx, key)
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	if err := s.checkKey(key, false); err != nil {
+		return 0, store.WrapKeyError("value size", key, err)
+	}
+	return s.backing.ValueSize(ctx, key)
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	if err := s.checkKey(key, false); err != nil {
+		return false, store.WrapKeyError("exists", key, err)
+	}
+	return s.backing.Exists(ctx, key)
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	if err := s.checkKeys("batch exists", keys, false); err != nil {
+		return nil, err
+	}
+	return s.backing.BatchExists(ctx, keys)
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	if err := s.checkKeys("batch get", keys, false); err != nil {
+		return forbiddenIterator(ctx, err)
+	}
+	return s.backing.BatchGet(ctx, keys, options...)
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	if err := s.checkKeys("batch delete", keys, true); err != nil {
+		return err
+	}
+	return s.backing.BatchDelete(ctx, keys)
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	if err := s.checkKey(key, true); err != nil {
+		return store.WrapKeyError("delete", key, err)
+	}
+	return s.backing.Delete(ctx, key)
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.checkScan(start, exclusiveEnd); err != nil {
+		return forbiddenIterator(ctx, fmt.Errorf("scan %s to %s: %w", store.Key(start), store.Key(exclusiveEnd), err))
+	}
+	return s.backing.Scan(ctx, start, exclusiveEnd, limit, options...)
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.checkKey(prefix, false); err != nil {
+		return forbiddenIterator(ctx, store.WrapKeyError("prefix", prefix, err))
+	}
+	return s.backing.Prefix(ctx, prefix, limit, options...)
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.checkKeys("batch prefix", prefixes, false); err != nil {
+		return forbiddenIterator(ctx, err)
+	}
+	return s.backing.BatchPrefix(ctx, prefixes, limit, options...)
+}
@@ -0,0 +1,95 @@
+package acl
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "ACL", storetest.NewBadgerBackedFactory(t, "acl", "write="))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "acl", "read=00",
+		"read=xyz",
+		"write=0",
+	)
+}
+
+func TestRules(t *testing.T) {
+	ctx := context.Background()
+
+	// Blocks can be read, accounts can be read and written, anything else is forbidden
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "acl", fmt.Sprintf("read=%s&write=%s", hex.EncodeToString([]byte("blk:")), hex.EncodeToString([]byte("acc:"))))
+	defer cleanup()
+
+	backing := kvStore.(*Store).backing
+	require.NoError(t, backing.Put(ctx, []byte("blk:1"), []byte("block")))
+	require.NoError(t, backing.Put(ctx, []byte("trx:1"), []byte("trx")))
+	require.NoError(t, backing.FlushPuts(ctx))
+
+	forbidden := func(err error) {
+		t.Helper()
+		assert.True(t, errors.Is(err, store.ErrForbidden), "got %v", err)
+	}
+	readAll := func(it *store.Iterator) (keys []string, err error) {
+		for it.Next() {
+			keys = append(keys, string(it.Item().Key))
+		}
+		return keys, it.Err()
+	}
+
+	value, err := kvStore.Get(ctx, []byte("blk:1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("block"), value)
+
+	_, err = kvStore.Get(ctx, []byte("trx:1"))
+	forbidden(err)
+	_, err = kvStore.Exists(ctx, []byte("trx:1"))
+	forbidden(err)
+	_, err = kvStore.BatchExists(ctx, [][]byte{[]byte("blk:1"), []byte("trx:1")})
+	forbidden(err)
+
+	// Writes are only granted on accounts
+	forbidden(kvStore.Put(ctx, []byte("blk:2"), []byte("block")))
+	forbidden(kvStore.Delete(ctx, []byte("blk:1")))
+	forbidden(kvStore.BatchDelete(ctx, [][]byte{[]byte("acc:1"), []byte("blk:1")}))
+	forbidden(kvStore.(store.Inserter).Insert(ctx, []byte("trx:2"), []byte("trx")))
+	_, err = kvStore.(store.Incrementer).Increment(ctx, []byte("blk:counter"), 1)
+	forbidden(err)
+
+	require.NoError(t, kvStore.Put(ctx, []byte("acc:1"), []byte("account")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+	value, err = kvStore.Get(ctx, []byte("acc:1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("account"), value)
+
+	// Scans must stay within a granted prefix
+	keys, err := readAll(kvStore.Prefix(ctx, []byte("blk:"), store.Unlimited))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blk:1"}, keys)
+
+	keys, err = readAll(kvStore.Scan(ctx, []byte("blk:"), []byte("blk;"), store.Unlimited))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blk:1"}, keys)
+
+	_, err = readAll(kvStore.Prefix(ctx, []byte("b"), store.Unlimited))
+	forbidden(err)
+	_, err = readAll(kvStore.Scan(ctx, []byte("blk:"), []byte("trx;"), store.Unlimited))
+	forbidden(err)
+	_, err = readAll(kvStore.Scan(ctx, []byte("blk:"), nil, store.Unlimited))
+	forbidden(err)
+	_, err = readAll(kvStore.BatchPrefix(ctx, [][]byte{[]byte("blk:"), []byte("trx:")}, store.Unlimited))
+	forbidden(err)
+	_, err = readAll(kvStore.BatchGet(ctx, [][]byte{[]byte("blk:1"), []byte("trx:1")}))
+	forbidden(err)
+}
@@ -0,0 +1,31 @@
+package acl
+
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) EnableEmpty() {
+	if enabler, ok := s.backing.(store.EmtpyValueEnabler); ok {
+		enabler.EnableEmpty()
+	}
+}
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	if setter, ok := s.backing.(store.LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+// Capabilities reports the capabilities of the backing store for the optional interfaces whose
+// keys the rules check, the other ones are not exposed through the wrapper.
+func (s *Store) Capabilities() store.Capabilities {
+	return s.backing.Capabilities().Intersect(store.Capabilities{
+		EmptyValue:        true,
+		Insert:            true,
+		Increment:         true,
+		CompareAndSwap:    true,
+		CompareAndSwapTTL: true,
+		ReadOnly:          true,
+	})
+}
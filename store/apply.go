@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Apply deletes `deletes` then writes `puts`, see `Applier`. The mutations are applied
+// atomically when `kv` implements `Applier`.
+//
+// Otherwise, they are not atomic: pending puts are flushed first, so that a deleted key is not
+// written back by one of them, then keys are deleted, then the puts are written and flushed. A
+// failure midway can leave only some of the mutations applied, and readers can observe the
+// deleted keys before the puts land.
+func Apply(ctx context.Context, kv KVStore, puts []KV, deletes [][]byte) error {
+	if applier, ok := kv.(Applier); ok {
+		return applier.Apply(ctx, puts, deletes)
+	}
+
+	if err := kv.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("apply: flush pending puts: %w", err)
+	}
+
+	if len(deletes) > 0 {
+		if err := kv.BatchDelete(ctx, deletes); err != nil {
+			return fmt.Errorf("apply: delete: %w", err)
+		}
+	}
+
+	for _, put := range puts {
+		if err := kv.Put(ctx, put.Key, put.Value); err != nil {
+			return fmt.Errorf("apply: put: %w", err)
+		}
+	}
+
+	if err := kv.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("apply: flush: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// Apply deletes `deletes` then writes `puts` in a single badger transaction, see
+// `store.Applier`. Unlike `RenamePrefix`, mutations too big for a single transaction are never
+// split, `badger.ErrTxnTooBig` is returned instead and nothing is applied.
+//
+// Pending puts are flushed first, so that a put made before the mutations never lands after
+// them, a deleted key staying deleted. The transaction is retried when a concurrent write
+// conflicts with it, see `updateWithRetry`.
+func (s *Store) Apply(ctx context.Context, puts []store.KV, deletes [][]byte) error {
+	zlogger := logging.Logger(ctx, s.logger)
+	if ce := zlogger.Check(zap.DebugLevel, "applying mutations"); ce != nil {
+		ce.Write(zap.Int("put_count", len(puts)), zap.Int("delete_count", len(deletes)), store.RequestIDField(ctx))
+	}
+
+	if s.readOnly {
+		return store.ErrReadOnly
+	}
+
+	compressedValues := make([][]byte, len(puts))
+	for i, put := range puts {
+		if err := s.checkKeyLen(put.Key); err != nil {
+			return store.WrapKeyError("apply", put.Key, err)
+		}
+
+		compressedValues[i] = s.compressor.Compress(put.Value)
+		if err := s.checkValueLen(compressedValues[i]); err != nil {
+			return store.WrapKeyError("apply", put.Key, err)
+		}
+	}
+
+	if err := s.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("apply: flush pending puts: %w", err)
+	}
+
+	return s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		for _, key := range deletes {
+			if err := txn.Delete(key); err != nil {
+				return store.WrapKeyError("apply delete", key, err)
+			}
+		}
+
+		for i, put := range puts {
+			if err := txn.SetEntry(badger.NewEntry(put.Key, compressedValues[i])); err != nil {
+				return store.WrapKeyError("apply put", put.Key, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplacePrefix deletes all the keys starting with `prefix` then writes `kvs` in a single badger
+// transaction, see `store.PrefixReplacer`. Like `Apply`, a replacement too big for a single
+// transaction is never split, `badger.ErrTxnTooBig` is returned instead and nothing is applied.
+//
+// Pending puts are flushed first, so that a put made before the replacement never lands after
+// it. The transaction reads the whole prefix, it is retried when a concurrent write under the
+// prefix conflicts with it, see `updateWithRetry`.
+func (s *Store) ReplacePrefix(ctx context.Context, prefix []byte, kvs []store.KV) error {
+	zlogger := logging.Logger(ctx, s.logger)
+	if ce := zlogger.Check(zap.DebugLevel, "replacing prefix"); ce != nil {
+		ce.Write(zap.Stringer("prefix", store.Key(prefix)), zap.Int("kv_count", len(kvs)), store.RequestIDField(ctx))
+	}
+
+	if s.readOnly {
+		return store.ErrReadOnly
+	}
+
+	if err := store.CheckReplacePrefix(prefix, kvs); err != nil {
+		return err
+	}
+
+	compressedValues := make([][]byte, len(kvs))
+	for i, kv := range kvs {
+		if err := s.checkKeyLen(kv.Key); err != nil {
+			return store.WrapKeyError("replace prefix", kv.Key, err)
+		}
+
+		compressedValues[i] = s.compressor.Compress(kv.Value)
+		if err := s.checkValueLen(compressedValues[i]); err != nil {
+			return store.WrapKeyError("replace prefix", kv.Key, err)
+		}
+	}
+
+	if err := s.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("replace prefix: flush pending puts: %w", err)
+	}
+
+	return s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		// Iterators only see the writes made to `txn` before their creation, the deletes are
+		// safe while iterating
+		options := badger.DefaultIteratorOptions
+		options.PrefetchValues = false
+		options.Prefix = prefix
+
+		it := txn.NewIterator(options)
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := txn.Delete(it.Item().KeyCopy(nil)); err != nil {
+				it.Close()
+				return err
+			}
+		}
+		it.Close()
+
+		for i, kv := range kvs {
+			if err := txn.SetEntry(badger.NewEntry(kv.Key, compressedValues[i])); err != nil {
+				return store.WrapKeyError("replace prefix put", kv.Key, err)
+			}
+		}
+
+		return nil
+	})
+}
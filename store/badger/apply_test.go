@@ -0,0 +1,137 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply_AllOrNothing(t *testing.T) {
+	tests := []struct {
+		name   string
+		badKey []byte
+	}{
+		{"key too long", []byte(strings.Repeat("k", defaultMaxKeyLen+1))},
+		{"empty key rejected by badger", []byte{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, cleanup := newTestStore(t, "")
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, s.Put(ctx, []byte("blk:orphan"), []byte("orphan")))
+			require.NoError(t, s.FlushPuts(ctx))
+
+			err := s.Apply(ctx,
+				[]store.KV{
+					{Key: []byte("blk:replacement"), Value: []byte("replacement")},
+					{Key: test.badKey, Value: []byte("bad")},
+				},
+				[][]byte{[]byte("blk:orphan")},
+			)
+			require.Error(t, err)
+
+			assert.Equal(t, []store.KV{
+				{Key: []byte("blk:orphan"), Value: []byte("orphan")},
+			}, drain(t, s.Prefix(ctx, []byte("blk:"), store.Unlimited)))
+		})
+	}
+}
+
+func TestReplacePrefix_NoIntermediateState(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	index := func(generation int) (kvs []store.KV) {
+		for i := 0; i < 50; i++ {
+			kvs = append(kvs, store.KV{Key: []byte(fmt.Sprintf("idx:%d:%02d", generation, i)), Value: []byte("entry")})
+		}
+		return kvs
+	}
+	require.NoError(t, s.ReplacePrefix(ctx, []byte("idx:"), index(0)))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for generation := 1; generation <= 20; generation++ {
+			assert.NoError(t, s.ReplacePrefix(ctx, []byte("idx:"), index(generation)))
+		}
+	}()
+
+	// Readers must always see a whole generation of the index, never an empty nor a mixed one
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		kvs := drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited))
+		require.Len(t, kvs, 50)
+
+		generation := strings.Split(string(kvs[0].Key), ":")[1]
+		assert.True(t, strings.HasPrefix(string(kvs[49].Key), "idx:"+generation+":"), "mixed generations %s and %s", kvs[0].Key, kvs[49].Key)
+	}
+
+	assert.Equal(t, index(20), drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited)))
+}
+
+func TestReplacePrefix_ConcurrentWriters(t *testing.T) {
+	s, cleanup := newTestStore(t, "conflict_attempts=100")
+	defer cleanup()
+
+	ctx := context.Background()
+	index := func(writer, generation int) (kvs []store.KV) {
+		for i := 0; i < 10; i++ {
+			kvs = append(kvs, store.KV{Key: []byte(fmt.Sprintf("idx:%d:%d:%02d", writer, generation, i)), Value: []byte("entry")})
+		}
+		return kvs
+	}
+
+	// Both writers read the whole prefix, each conflicting with the other, retries resolve it
+	var wg sync.WaitGroup
+	for writer := 0; writer < 2; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for generation := 0; generation < 20; generation++ {
+				assert.NoError(t, s.ReplacePrefix(ctx, []byte("idx:"), index(writer, generation)))
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	kvs := drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited))
+	require.Len(t, kvs, 10)
+	assert.True(t, strings.HasPrefix(string(kvs[0].Key), "idx:0:19:") || strings.HasPrefix(string(kvs[0].Key), "idx:1:19:"), "got %s", kvs[0].Key)
+}
+
+func TestReplacePrefix_FlushesPendingPuts(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("idx:stale"), []byte("stale")))
+	require.NoError(t, s.Put(ctx, []byte("other"), []byte("kept")))
+
+	require.NoError(t, s.ReplacePrefix(ctx, []byte("idx:"), []store.KV{{Key: []byte("idx:a"), Value: []byte("fresh")}}))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	// The pending put under the prefix is replaced, not written over the replacement later on
+	assert.Equal(t, []store.KV{
+		{Key: []byte("idx:a"), Value: []byte("fresh")},
+	}, drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited)))
+
+	value, err := s.Get(ctx, []byte("other"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("kept"), value)
+}
@@ -3,11 +3,19 @@ package badger
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dfuse-io/kvdb/store"
 	"github.com/dfuse-io/logging"
@@ -16,11 +24,55 @@ import (
 	"go.uber.org/zap"
 )
 
+// defaultMaxKeyLen is the longest key badger accepts
+const defaultMaxKeyLen = 65000
+
+// defaultConflictAttempts is high enough for heavily contended keys to go through
+const defaultConflictAttempts = 100
+
 type Store struct {
+	// pendingPutCount counts the puts made since the last flush, it is accessed atomically so
+	// it must come first to be 64-bit aligned on 32-bit platforms
+	pendingPutCount int64
+
 	dsn        string
 	db         *badger.DB
 	writeBatch *badger.WriteBatch
 	compressor store.Compressor
+	logger     *zap.Logger
+
+	// iteratorObserver receives the statistics of `Scan`, `Prefix`, `BatchPrefix` and `MultiPrefix` iterations when set
+	iteratorObserver store.IteratorObserver
+
+	// iteratorPrefetchSize overrides badger's default prefetch size when not 0
+	iteratorPrefetchSize int
+	// maxKeyLen is the longest key accepted by writes, longer ones fail with `store.ErrKeyTooLong`
+	maxKeyLen int
+	// maxValueLen is the largest value, once compressed, accepted by writes, larger ones fail
+	// with `store.ErrValueTooLarge`, unbounded when 0
+	maxValueLen int
+	// syncOnFlush syncs the value log once at the end of each `FlushPuts` instead of on each commit
+	syncOnFlush bool
+	// strictClose makes `Close` fail on unflushed puts instead of flushing them
+	strictClose bool
+	// readOnly rejects writes with `store.ErrReadOnly`, badger being opened read-only
+	readOnly bool
+	// iteratorSlots bounds the number of iterating goroutines holding a read transaction, nil when unbounded
+	iteratorSlots chan struct{}
+	// conflictAttempts bounds the attempts of transactions retried on conflicts, see `updateWithRetry`
+	conflictAttempts int
+	// iteratorMaxDuration bounds the lifetime of iterations, see `iteratorContext`, unbounded when 0
+	iteratorMaxDuration time.Duration
+
+	warmupCancel context.CancelFunc
+	warmupDone   chan struct{}
+	warmupErr    error
+
+	// writeMu guards `writeBatch` and `flushErr`, `Put` only reads them, badger's write batch
+	// being safe for concurrent use, while `FlushPuts` replaces them
+	writeMu sync.RWMutex
+	// flushErr is the error of a failed flush, whose puts are lost, see `FlushPuts`
+	flushErr error
 }
 
 func (s *Store) String() string {
@@ -46,11 +98,137 @@ func NewStore(dsnString string) (store.KVStore, error) {
 		return nil, fmt.Errorf("creating path %q: %w", createPath, err)
 	}
 
-	db, err := badger.Open(badger.DefaultOptions(dsn.Path).WithLogger(nil).WithCompression(options.Snappy))
+	s := &Store{
+		dsn:    dsnString,
+		logger: zlog,
+	}
+
+	// Badger's own logs go to the store's logger unless `silent=true` is used
+	var logger badger.Logger
+	if dsn.Query().Get("silent") != "true" {
+		logger = &badgerLogger{store: s}
+	}
+
+	badgerOptions := badger.DefaultOptions(dsn.Path).WithLogger(logger).WithCompression(options.Snappy)
+	// See `FlushPuts` for the durability guarantees of `sync_on_flush`
+	syncOnFlush := dsn.Query().Get("sync_on_flush") == "true"
+	if syncOnFlush {
+		badgerOptions = badgerOptions.WithSyncWrites(false)
+	}
+
+	// The database must exist and have been closed cleanly, badger cannot replay its value
+	// log when read-only
+	readOnly := dsn.Query().Get("read_only") == "true"
+	if readOnly {
+		badgerOptions = badgerOptions.WithReadOnly(true)
+	}
+
+	// `recovery_mode=truncate` drops the partially written tail of the value log left by an
+	// unclean shutdown, instead of failing to open, losing the entries written in it
+	recoveryMode := dsn.Query().Get("recovery_mode")
+	switch recoveryMode {
+	case "":
+	case "truncate":
+		if readOnly {
+			return nil, fmt.Errorf("badger new: recovery_mode cannot be used with read_only, badger cannot truncate a read-only database")
+		}
+		badgerOptions = badgerOptions.WithTruncate(true)
+	default:
+		return nil, fmt.Errorf("badger new: invalid recovery_mode %q, expecting 'truncate'", recoveryMode)
+	}
+
+	if blockCacheSize := dsn.Query().Get("block_cache_size"); blockCacheSize != "" {
+		size, err := strconv.ParseInt(blockCacheSize, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("badger new: invalid block_cache_size %q: %w", blockCacheSize, err)
+		}
+		badgerOptions = badgerOptions.WithMaxCacheSize(size)
+	}
+
+	var iteratorPrefetchSize int
+	if prefetchSize := dsn.Query().Get("iterator_prefetch_size"); prefetchSize != "" {
+		iteratorPrefetchSize, err = strconv.Atoi(prefetchSize)
+		if err != nil || iteratorPrefetchSize <= 0 {
+			return nil, fmt.Errorf("badger new: invalid iterator_prefetch_size %q, expecting a positive integer", prefetchSize)
+		}
+	}
+
+	maxKeyLen := defaultMaxKeyLen
+	if value := dsn.Query().Get("max_key_len"); value != "" {
+		maxKeyLen, err = strconv.Atoi(value)
+		if err != nil || maxKeyLen <= 0 || maxKeyLen > defaultMaxKeyLen {
+			return nil, fmt.Errorf("badger new: invalid max_key_len %q, expecting a positive integer up to %d", value, defaultMaxKeyLen)
+		}
+	}
+
+	var maxValueLen int
+	if value := dsn.Query().Get("max_value_len"); value != "" {
+		maxValueLen, err = strconv.Atoi(value)
+		if err != nil || maxValueLen <= 0 {
+			return nil, fmt.Errorf("badger new: invalid max_value_len %q, expecting a positive integer", value)
+		}
+	}
+
+	conflictAttempts := defaultConflictAttempts
+	if value := dsn.Query().Get("conflict_attempts"); value != "" {
+		conflictAttempts, err = strconv.Atoi(value)
+		if err != nil || conflictAttempts <= 0 {
+			return nil, fmt.Errorf("badger new: invalid conflict_attempts %q, expecting a positive integer", value)
+		}
+	}
+
+	var iteratorSlots chan struct{}
+	if value := dsn.Query().Get("max_concurrent_iterators"); value != "" {
+		maxConcurrentIterators, err := strconv.Atoi(value)
+		if err != nil || maxConcurrentIterators <= 0 {
+			return nil, fmt.Errorf("badger new: invalid max_concurrent_iterators %q, expecting a positive integer", value)
+		}
+		iteratorSlots = make(chan struct{}, maxConcurrentIterators)
+	}
+
+	var iteratorMaxDuration time.Duration
+	if value := dsn.Query().Get("iterator_max_duration"); value != "" {
+		iteratorMaxDuration, err = time.ParseDuration(value)
+		if err != nil || iteratorMaxDuration <= 0 {
+			return nil, fmt.Errorf("badger new: invalid iterator_max_duration %q, expecting a positive duration", value)
+		}
+	}
+
+	var warmupPrefixes [][]byte
+	for _, warmupPrefix := range dsn.Query()["warmup_prefix"] {
+		prefix, err := hex.DecodeString(warmupPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("badger new: invalid warmup_prefix %q, expecting hexadecimal: %w", warmupPrefix, err)
+		}
+		warmupPrefixes = append(warmupPrefixes, prefix)
+	}
+
+	var openTimeout time.Duration
+	if value := dsn.Query().Get("open_timeout"); value != "" {
+		openTimeout, err = time.ParseDuration(value)
+		if err != nil || openTimeout <= 0 {
+			return nil, fmt.Errorf("badger new: invalid open_timeout %q, expecting a positive duration", value)
+		}
+	}
+
+	var valueLogSizesBefore map[string]int64
+	if recoveryMode != "" {
+		valueLogSizesBefore, err = valueLogSizes(badgerOptions.ValueDir)
+		if err != nil {
+			return nil, fmt.Errorf("badger new: list value log files: %w", err)
+		}
+		s.logger.Warn("opening badger in recovery mode, a corrupted value log tail is truncated", zap.String("recovery_mode", recoveryMode))
+	}
+
+	db, err := openWithTimeout(badgerOptions, openTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("badger new: open badger db: %w", err)
 	}
 
+	if recoveryMode != "" {
+		s.logTruncatedValueLogs(badgerOptions.ValueDir, valueLogSizesBefore)
+	}
+
 	// Deprecated: this is only used for backward compatible support as we deprecated this support in Badger
 	// It only allows for seamless decompression -- otherwise Snappy kicks in automatically. This is why we
 	// use `math.MaxInt64` as the threshold to use for compression, this way, compression never kicks in
@@ -60,57 +238,198 @@ func NewStore(dsnString string) (store.KVStore, error) {
 		return nil, err
 	}
 
-	s := &Store{
-		dsn:        dsnString,
-		db:         db,
-		compressor: compressor,
+	s.db = db
+	s.compressor = compressor
+	s.iteratorPrefetchSize = iteratorPrefetchSize
+	s.maxKeyLen = maxKeyLen
+	s.maxValueLen = maxValueLen
+	s.syncOnFlush = syncOnFlush
+	s.strictClose = dsn.Query().Get("strict_close") == "true"
+	s.readOnly = readOnly
+	s.conflictAttempts = conflictAttempts
+	s.iteratorSlots = iteratorSlots
+	s.iteratorMaxDuration = iteratorMaxDuration
+
+	if dsn.Query().Get("warmup") == "true" {
+		s.startWarmup(warmupPrefixes)
 	}
+
 	return s, nil
 }
 
+// openBadger is a variable so tests can simulate a slow open
+var openBadger = badger.Open
+
+// openWithTimeout opens badger, giving up after `timeout` when not 0, which recovering a large
+// or damaged database can exceed by far. Badger cannot be interrupted while opening, so the
+// open goes on in the background and the database is closed as soon as it completes, releasing
+// its directory lock.
+func openWithTimeout(opts badger.Options, timeout time.Duration) (*badger.DB, error) {
+	if timeout == 0 {
+		return openBadger(opts)
+	}
+
+	type openResult struct {
+		db  *badger.DB
+		err error
+	}
+
+	done := make(chan openResult, 1)
+	go func() {
+		db, err := openBadger(opts)
+		done <- openResult{db: db, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.db, result.err
+	case <-time.After(timeout):
+		go func() {
+			if result := <-done; result.err == nil {
+				result.db.Close()
+			}
+		}()
+		return nil, fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+}
+
+// Close flushes the puts made since the last `FlushPuts` then closes badger, so that they are
+// not lost. With `strict_close=true`, those puts are discarded instead and `Close` fails, for
+// callers that always flush at their own boundaries and want a missed flush to be reported.
+// Badger is closed in all cases.
 func (s *Store) Close() error {
-	return s.db.Close()
+	s.stopWarmup()
+
+	var err error
+	if pendingPutCount := atomic.LoadInt64(&s.pendingPutCount); pendingPutCount > 0 {
+		if s.strictClose {
+			s.writeBatch.Cancel()
+			err = fmt.Errorf("close: %d puts were not flushed and are discarded", pendingPutCount)
+		} else if flushErr := s.FlushPuts(context.Background()); flushErr != nil {
+			err = fmt.Errorf("close: flush pending puts: %w", flushErr)
+		}
+	}
+
+	if closeErr := s.db.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
-	zlogger := logging.Logger(ctx, zlog)
-	zlogger.Debug("putting", zap.Stringer("key", store.Key(key)))
-	if s.writeBatch == nil {
-		s.writeBatch = s.db.NewWriteBatch()
+	zlogger := logging.Logger(ctx, s.logger)
+	// Checked first so that no field is built on this hot path when debug logging is disabled
+	if ce := zlogger.Check(zap.DebugLevel, "putting"); ce != nil {
+		ce.Write(zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+	}
+	if err := s.checkWritable(key); err != nil {
+		return store.WrapKeyError("put", key, err)
 	}
 
 	value = s.compressor.Compress(value)
+	if err := s.checkValueLen(value); err != nil {
+		return store.WrapKeyError("put", key, err)
+	}
 
-	err = s.writeBatch.SetEntry(badger.NewEntry(key, value))
-	if err == badger.ErrTxnTooBig {
-		zlogger.Debug("txn too big pre-emptively pushing")
-		if err := s.writeBatch.Flush(); err != nil {
-			return err
-		}
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
 
-		s.writeBatch = s.db.NewWriteBatch()
-		err := s.writeBatch.SetEntry(badger.NewEntry(key, value))
-		if err != nil {
-			return fmt.Errorf("set entry (after flush): %w", err)
+	if s.flushErr != nil {
+		return store.WrapKeyError("put", key, s.flushErr)
+	}
+
+	if s.writeBatch == nil {
+		// Upgrading the lock lets another `Put` create the write batch first, hence the check
+		s.writeMu.RUnlock()
+		s.writeMu.Lock()
+		if s.writeBatch == nil {
+			s.writeBatch = s.db.NewWriteBatch()
 		}
+		s.writeMu.Unlock()
+		s.writeMu.RLock()
 	}
 
-	if err != nil {
-		return fmt.Errorf("set entry: %w", err)
+	// The write batch commits its entries in as many transactions as needed to fit badger's
+	// size limits, it only fails when a single entry cannot fit in a transaction
+	if err := s.writeBatch.SetEntry(badger.NewEntry(key, value)); err != nil {
+		return store.WrapKeyError("put", key, fmt.Errorf("set entry: %w", err))
+	}
+
+	atomic.AddInt64(&s.pendingPutCount, 1)
+	return nil
+}
+
+// checkWritable fails fast on writes to a `read_only=true` store and on keys longer than
+// `max_key_len`, before they reach badger
+func (s *Store) checkWritable(key []byte) error {
+	if s.readOnly {
+		return store.ErrReadOnly
 	}
+	return s.checkKeyLen(key)
+}
+
+func (s *Store) checkKeyLen(key []byte) error {
+	if len(key) > s.maxKeyLen {
+		return fmt.Errorf("%d bytes exceeds maximum of %d: %w", len(key), s.maxKeyLen, store.ErrKeyTooLong)
+	}
+	return nil
+}
 
+// checkValueLen fails fast on values larger than `max_value_len`, checked once compressed since
+// this is what is written to the value log
+func (s *Store) checkValueLen(compressedValue []byte) error {
+	if s.maxValueLen > 0 && len(compressedValue) > s.maxValueLen {
+		return fmt.Errorf("%d bytes exceeds maximum of %d: %w", len(compressedValue), s.maxValueLen, store.ErrValueTooLarge)
+	}
 	return nil
 }
 
+// FlushPuts commits all the entries written through `Put` since the previous flush. Once it
+// returns without error, the next `Put` always goes into a fresh, empty write batch, so
+// callers can flush at their own boundaries (a block for example) and know that nothing
+// written before the flush is part of the next batch.
+//
+// By default, badger syncs its value log to disk on every transaction commit, including the
+// intermediate commits a large write batch makes while it is filled, so a flushed entry
+// survives a power failure. With `sync_on_flush=true`, commits are not synced individually
+// anymore, the value log is synced once when `FlushPuts` completes, before it returns. This
+// trades the cost of one sync per commit for a single, larger one per flush, which helps
+// throughput when flushes are big and hurts it when they are small and frequent. Writes made
+// outside of `Put` (`Insert`, `Increment`, `Delete`, ...) are then only durable once a later
+// `FlushPuts` returns or the store is closed.
+//
+// The write batch commits its entries in as many transactions as needed to fit badger's size
+// limits while it is filled, so a flush never fails because the batch is too big. When a flush
+// fails nonetheless, the puts not yet committed are lost and cannot be flushed again, the
+// failure then sticks: further `Put` and `FlushPuts` calls fail until the store is reopened,
+// instead of a later flush reporting a success over lost puts.
 func (s *Store) FlushPuts(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.flushErr != nil {
+		return s.flushErr
+	}
+
 	if s.writeBatch == nil {
 		return nil
 	}
+
+	// A write batch cannot be used anymore once flushed, whether it succeeded or not
 	err := s.writeBatch.Flush()
+	s.writeBatch = s.db.NewWriteBatch()
+	atomic.StoreInt64(&s.pendingPutCount, 0)
 	if err != nil {
-		return err
+		logging.Logger(ctx, s.logger).Error("flush failed, puts of the write batch not yet committed are lost", zap.Error(err))
+		s.flushErr = fmt.Errorf("a previous flush failed, puts made before it might be lost, the store must be reopened: %w", err)
+		return fmt.Errorf("flush write batch: %w", err)
+	}
+
+	if s.syncOnFlush {
+		if err := s.db.Sync(); err != nil {
+			return fmt.Errorf("sync after flush: %w", err)
+		}
 	}
-	s.writeBatch = s.db.NewWriteBatch()
 	return nil
 }
 
@@ -121,6 +440,173 @@ func wrapNotFoundError(err error) error {
 	return err
 }
 
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	logging.Logger(ctx, s.logger).Debug("inserting", zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+	if err := s.checkWritable(key); err != nil {
+		return store.WrapKeyError("insert", key, err)
+	}
+
+	value = s.compressor.Compress(value)
+	if err := s.checkValueLen(value); err != nil {
+		return store.WrapKeyError("insert", key, err)
+	}
+
+	// A conflict means a concurrent transaction touched the key, retrying re-reads it so the
+	// loser of a concurrent insert sees `store.ErrKeyExists` instead.
+	return s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		if err == nil {
+			return store.ErrKeyExists
+		}
+
+		if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		return txn.SetEntry(badger.NewEntry(key, value))
+	})
+}
+
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	logging.Logger(ctx, s.logger).Debug("incrementing", zap.Stringer("key", store.Key(key)), zap.Int64("delta", delta), store.RequestIDField(ctx))
+	if err := s.checkWritable(key); err != nil {
+		return 0, store.WrapKeyError("increment", key, err)
+	}
+
+	// Concurrent increments of the same key conflict, retrying re-reads the latest value
+	err = s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		current := int64(0)
+		item, err := txn.Get(key)
+		switch {
+		case err == nil:
+			value, err := s.readValue(item)
+			if err != nil {
+				return err
+			}
+
+			if len(value) != 8 {
+				return fmt.Errorf("value of key %s is not a 8 bytes counter, got %d bytes", store.Key(key), len(value))
+			}
+			current = int64(binary.BigEndian.Uint64(value))
+
+		case err != badger.ErrKeyNotFound:
+			return err
+		}
+
+		newValue = current + delta
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(newValue))
+
+		return txn.SetEntry(badger.NewEntry(key, s.compressor.Compress(value)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newValue, nil
+}
+
+func (s *Store) CompareAndSwap(ctx context.Context, key, old, new []byte) (swapped bool, err error) {
+	logging.Logger(ctx, s.logger).Debug("comparing and swapping", zap.Stringer("key", store.Key(key)), zap.Bool("delete", new == nil), store.RequestIDField(ctx))
+	return s.compareAndSwap(ctx, key, old, new, 0)
+}
+
+// CompareAndSwapWithTTL is `CompareAndSwap` with `new` expiring after `ttl`. Badger expires
+// entries on a unix seconds basis, `ttl` is rounded up so the key never expires early.
+func (s *Store) CompareAndSwapWithTTL(ctx context.Context, key, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	logging.Logger(ctx, s.logger).Debug("comparing and swapping with ttl", zap.Stringer("key", store.Key(key)), zap.Bool("delete", new == nil), zap.Duration("ttl", ttl), store.RequestIDField(ctx))
+	if ttl <= 0 {
+		return false, store.WrapKeyError("compare and swap", key, fmt.Errorf("invalid ttl %s, must be positive", ttl))
+	}
+	return s.compareAndSwap(ctx, key, old, new, ttl)
+}
+
+// compareAndSwap backs `CompareAndSwap` and `CompareAndSwapWithTTL`, a `ttl` of 0 writes a
+// value that never expires
+func (s *Store) compareAndSwap(ctx context.Context, key, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	if err := s.checkWritable(key); err != nil {
+		return false, store.WrapKeyError("compare and swap", key, err)
+	}
+
+	var compressed []byte
+	if new != nil {
+		compressed = s.compressor.Compress(new)
+		if err := s.checkValueLen(compressed); err != nil {
+			return false, store.WrapKeyError("compare and swap", key, err)
+		}
+	}
+
+	// A conflict means a concurrent transaction touched the key, retrying re-reads it so the
+	// loser of a concurrent swap compares against the winner's value
+	err = s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		swapped = false
+
+		item, err := txn.Get(key)
+		switch {
+		case err == badger.ErrKeyNotFound:
+			if old != nil {
+				return nil
+			}
+
+		case err != nil:
+			return err
+
+		default:
+			if old == nil {
+				return nil
+			}
+
+			current, err := s.readValue(item)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(current, old) {
+				return nil
+			}
+		}
+
+		swapped = true
+		if new == nil {
+			return txn.Delete(key)
+		}
+
+		entry := badger.NewEntry(key, compressed)
+		if ttl > 0 {
+			entry.ExpiresAt = uint64(time.Now().Add(ttl).Unix()) + 1
+		}
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return false, store.WrapKeyError("compare and swap", key, err)
+	}
+	return swapped, nil
+}
+
+// updateWithRetry runs `fn` in an update transaction, running it again in a new transaction
+// when the commit fails with `badger.ErrConflict` because a concurrent transaction wrote a key
+// `fn` read. Attempts are spaced by an exponential, jittered backoff so that contending callers
+// spread out. After `conflict_attempts` attempts, or once `ctx` is done, the last error is
+// returned.
+func (s *Store) updateWithRetry(ctx context.Context, fn func(txn *badger.Txn) error) (err error) {
+	backoff := 100 * time.Microsecond
+	for attempt := 1; ; attempt++ {
+		err = s.db.Update(fn)
+		if err != badger.ErrConflict || attempt >= s.conflictAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff)))):
+		}
+
+		if backoff < 50*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
 func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
 	err = s.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get(key)
@@ -128,26 +614,186 @@ func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
 			return wrapNotFoundError(err)
 		}
 
-		// TODO: optimize: if we're going to decompress, we can use the `item.Value` instead
-		// of making a copy
-		value, err = item.ValueCopy(nil)
+		value, err = s.readValue(item)
+		return err
+	})
+	return value, store.WrapKeyError("get", key, err)
+}
+
+// GetInto is like `Get` but writes the value into `dst`, growing it only when its capacity is
+// too small, and returns the resulting slice. With the identity compressor, the stored value is
+// read in place and copied once into `dst`, so a large enough buffer makes the read
+// allocation free.
+//
+// The returned value shares its backing array with `dst`: it is only valid until `dst` is
+// reused or modified, typically by the next `GetInto` call with the same buffer. Copy it to
+// hold on to it. On error, the content of `dst` is unspecified.
+func (s *Store) GetInto(ctx context.Context, key []byte, dst []byte) (value []byte, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
 		if err != nil {
-			return err
+			return wrapNotFoundError(err)
+		}
+
+		// `stored` is only valid within the callback, it must not escape it
+		return item.Value(func(stored []byte) error {
+			decompressed, err := s.compressor.Decompress(stored)
+			if err != nil {
+				return err
+			}
+
+			value = append(dst[:0], decompressed...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, store.WrapKeyError("get", key, err)
+	}
+
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// GetRaw returns the value of `key` both as stored on disk and decompressed, to tell a
+// corruption of the compression layer from one of the encoding layered on top. When the
+// stored bytes cannot be decompressed, they are still returned along with the error.
+func (s *Store) GetRaw(ctx context.Context, key []byte) (compressed []byte, decompressed []byte, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return wrapNotFoundError(err)
+		}
+
+		compressed, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, store.WrapKeyError("get raw", key, err)
+	}
+
+	decompressed, err = s.compressor.Decompress(compressed)
+	if err != nil {
+		return compressed, nil, store.WrapKeyError("get raw", key, fmt.Errorf("decompress: %w", err))
+	}
+
+	return compressed, decompressed, nil
+}
+
+// ValueSize is exact, the value is accessed in place (memory mapped for large values living in
+// the value log) without copying nor decompressing it. Badger's `item.ValueSize()` would avoid
+// touching the value at all but is only an approximation for value log entries.
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return wrapNotFoundError(err)
 		}
 
-		value, err = s.compressor.Decompress(value)
+		return item.Value(func(value []byte) error {
+			size = len(value)
+			return nil
+		})
+	})
+	return
+}
+
+// Exists only looks the key up, the value is never read.
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
+		exists = true
 		return nil
 	})
 	return
 }
 
+// BatchExists looks all the keys up in a single read transaction, values are never read.
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	exists = make([]bool, len(keys))
+	err = s.db.View(func(txn *badger.Txn) error {
+		for i, key := range keys {
+			_, err := txn.Get(key)
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return store.WrapKeyError("exists", key, err)
+			}
+
+			exists[i] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
+
+// readValue copies and decompresses the value of `item`. An empty stored value is always
+// returned as a zero-length, non-nil slice, a `nil` value is reserved to key-only reads.
+func (s *Store) readValue(item *badger.Item) ([]byte, error) {
+	// TODO: optimize: if we're going to decompress, we can use the `item.Value` instead
+	// of making a copy
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = s.compressor.Decompress(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		value = []byte{}
+	}
+
+	return value, nil
+}
+
+// readRawValue copies the value of `item` as stored, without decompressing it, see `store.Raw`.
+func (s *Store) readRawValue(item *badger.Item) ([]byte, error) {
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		value = []byte{}
+	}
+
+	return value, nil
+}
+
+// valueReader returns `readRawValue` when `options` ask for raw values, `readValue` otherwise.
+func (s *Store) valueReader(options []store.ReadOption) func(item *badger.Item) ([]byte, error) {
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
+	if readOptions.Raw {
+		return s.readRawValue
+	}
+	return s.readValue
+}
+
 func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
-	zlogger := logging.Logger(ctx, zlog)
+	zlogger := logging.Logger(ctx, s.logger)
 	zlogger.Debug("batch deletion", zap.Int("key_count", len(keys)))
+	if s.readOnly {
+		return store.ErrReadOnly
+	}
 
 	deletionBatch := s.db.NewWriteBatch()
 	for _, key := range keys {
@@ -174,28 +820,69 @@ func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
 	return deletionBatch.Flush()
 }
 
-func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	logging.Logger(ctx, s.logger).Debug("deleting", zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+	if s.readOnly {
+		return store.WrapKeyError("delete", key, store.ErrReadOnly)
+	}
+
+	err = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+	return store.WrapKeyError("delete", key, err)
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	ctx, cancel := s.iteratorContext(ctx)
 	kr := store.NewIterator(ctx)
 
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
+	// Fast path for single key lookups, we resolve it synchronously through `Get` and return
+	// an already completed iterator, which avoids spawning a goroutine for a one-off lookup.
+	if len(keys) == 1 && !readOptions.KeyOnly && !readOptions.Raw {
+		defer cancel()
+
+		value, err := s.Get(ctx, keys[0])
+		if err != nil {
+			kr.PushError(err)
+			return kr
+		}
+
+		// Copied like the multi-key path does, so that the caller may reuse `keys` right away
+		key := make([]byte, len(keys[0]))
+		copy(key, keys[0])
+
+		if kr.PushItem(store.KV{Key: key, Value: value}) {
+			kr.PushFinished()
+		}
+		return kr
+	}
+
+	readItemValue := s.valueReader(options)
 	go func() {
-		err := s.db.View(func(txn *badger.Txn) error {
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
 			for _, key := range keys {
 				item, err := txn.Get(key)
 				if err != nil {
 					return wrapNotFoundError(err)
 				}
 
-				value, err := item.ValueCopy(nil)
-				if err != nil {
-					return err
-				}
-
-				value, err = s.compressor.Decompress(value)
-				if err != nil {
-					return err
+				// Looking a key up never reads its value, which is only read when asked for
+				var value []byte
+				if !readOptions.KeyOnly {
+					value, err = readItemValue(item)
+					if err != nil {
+						return err
+					}
 				}
 
-				if !kr.PushItem(store.KV{item.KeyCopy(nil), value}) {
+				if !kr.PushItem(store.KV{Key: item.KeyCopy(nil), Value: value}) {
 					break
 				}
 
@@ -214,12 +901,19 @@ func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
 }
 
 func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
-	zlogger := logging.Logger(ctx, zlog)
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
 	sit := store.NewIterator(ctx)
-	zlogger.Debug("scanning", zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)))
+	recorder := s.newIterationRecorder("Scan")
+	if ce := zlogger.Check(zap.DebugLevel, "scanning"); ce != nil {
+		ce.Write(zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)), store.RequestIDField(ctx))
+	}
 	go func() {
-		err := s.db.View(func(txn *badger.Txn) error {
-			badgerOptions := badgerIteratorOptions(store.Limit(limit), options)
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limit), options)
+			readItemValue := s.valueReader(options)
 			bit := txn.NewIterator(badgerOptions)
 			defer bit.Close()
 
@@ -232,18 +926,13 @@ func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int,
 				// we should not fetch nor decompress actual value
 				var value []byte
 				if badgerOptions.PrefetchValues {
-					value, err = bit.Item().ValueCopy(nil)
-					if err != nil {
-						return err
-					}
-
-					value, err = s.compressor.Decompress(value)
+					value, err = readItemValue(bit.Item())
 					if err != nil {
 						return err
 					}
 				}
 
-				if !sit.PushItem(store.KV{Key: bit.Item().KeyCopy(nil), Value: value}) {
+				if !recorder.push(sit, store.KV{Key: bit.Item().KeyCopy(nil), Value: value}) {
 					break
 				}
 
@@ -253,6 +942,7 @@ func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int,
 			}
 			return nil
 		})
+		recorder.done(err)
 		if err != nil {
 			sit.PushError(err)
 			return
@@ -265,12 +955,19 @@ func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int,
 }
 
 func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
-	zlogger := logging.Logger(ctx, zlog)
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
 	kr := store.NewIterator(ctx)
-	zlogger.Debug("prefix scanning", zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)))
+	recorder := s.newIterationRecorder("Prefix")
+	if ce := zlogger.Check(zap.DebugLevel, "prefix scanning"); ce != nil {
+		ce.Write(zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)), store.RequestIDField(ctx))
+	}
 	go func() {
-		err := s.db.View(func(txn *badger.Txn) error {
-			badgerOptions := badgerIteratorOptions(store.Limit(limit), options)
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limit), options)
+			readItemValue := s.valueReader(options)
 			badgerOptions.Prefix = prefix
 
 			it := txn.NewIterator(badgerOptions)
@@ -285,18 +982,13 @@ func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ..
 				// we should not fetch nor decompress actual value
 				var value []byte
 				if badgerOptions.PrefetchValues {
-					value, err = it.Item().ValueCopy(nil)
-					if err != nil {
-						return err
-					}
-
-					value, err = s.compressor.Decompress(value)
+					value, err = readItemValue(it.Item())
 					if err != nil {
 						return err
 					}
 				}
 
-				if !kr.PushItem(store.KV{Key: it.Item().KeyCopy(nil), Value: value}) {
+				if !recorder.push(kr, store.KV{Key: it.Item().KeyCopy(nil), Value: value}) {
 					break
 				}
 
@@ -306,6 +998,7 @@ func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ..
 			}
 			return nil
 		})
+		recorder.done(err)
 		if err != nil {
 			kr.PushError(err)
 			return
@@ -318,13 +1011,20 @@ func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ..
 }
 
 func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
-	zlogger := logging.Logger(ctx, zlog)
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
 	kr := store.NewIterator(ctx)
-	zlogger.Debug("batch prefix scanning", zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit", store.Limit(limit)))
+	recorder := s.newIterationRecorder("BatchPrefix")
+	if ce := zlogger.Check(zap.DebugLevel, "batch prefix scanning"); ce != nil {
+		ce.Write(zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit", store.Limit(limit)), store.RequestIDField(ctx))
+	}
 
 	go func() {
-		err := s.db.View(func(txn *badger.Txn) error {
-			badgerOptions := badgerIteratorOptions(store.Limit(limit), options)
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limit), options)
+			readItemValue := s.valueReader(options)
 			it := txn.NewIterator(badgerOptions)
 			defer it.Close()
 
@@ -339,29 +1039,90 @@ func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, o
 					// we should not fetch nor decompress actual value
 					var value []byte
 					if badgerOptions.PrefetchValues {
-						value, err = it.Item().ValueCopy(nil)
+						value, err = readItemValue(it.Item())
 						if err != nil {
 							return err
 						}
+					}
 
-						value, err = s.compressor.Decompress(value)
+					if !recorder.push(kr, store.KV{Key: it.Item().KeyCopy(nil), Value: value}) {
+						break terminateLoop
+					}
+
+					if store.Limit(limit).Reached(count) {
+						break terminateLoop
+					}
+				}
+			}
+
+			return nil
+		})
+		recorder.done(err)
+
+		if err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// MultiPrefix runs the scans of all `prefixes` in a single read transaction, see
+// `store.MultiPrefixer`, so they all see the same snapshot of the store.
+func (s *Store) MultiPrefix(ctx context.Context, prefixes [][]byte, limitEach int, options ...store.ReadOption) *store.Iterator {
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
+	kr := store.NewIterator(ctx)
+	recorder := s.newIterationRecorder("MultiPrefix")
+	if ce := zlogger.Check(zap.DebugLevel, "multi prefix scanning"); ce != nil {
+		ce.Write(zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit_each", store.Limit(limitEach)), store.RequestIDField(ctx))
+	}
+
+	if err := store.CheckMultiPrefixes(prefixes); err != nil {
+		cancel()
+		kr.PushError(err)
+		return kr
+	}
+
+	go func() {
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limitEach), options)
+			readItemValue := s.valueReader(options)
+			it := txn.NewIterator(badgerOptions)
+			defer it.Close()
+
+			var err error
+			for _, prefix := range prefixes {
+				count := uint64(0)
+				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+					count++
+
+					var value []byte
+					if badgerOptions.PrefetchValues {
+						value, err = readItemValue(it.Item())
 						if err != nil {
 							return err
 						}
 					}
 
-					if !kr.PushItem(store.KV{Key: it.Item().KeyCopy(nil), Value: value}) {
-						break terminateLoop
+					if !recorder.push(kr, store.KV{Key: it.Item().KeyCopy(nil), Value: value}) {
+						return nil
 					}
 
-					if store.Limit(limit).Reached(count) {
-						break terminateLoop
+					if store.Limit(limitEach).Reached(count) {
+						break
 					}
 				}
 			}
 
 			return nil
 		})
+		recorder.done(err)
 
 		if err != nil {
 			kr.PushError(err)
@@ -374,8 +1135,91 @@ func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, o
 	return kr
 }
 
-func badgerIteratorOptions(limit store.Limit, options []store.ReadOption) badger.IteratorOptions {
-	if limit.Unbounded() && len(options) == 0 {
+// iteratorContext bounds `ctx` by `iterator_max_duration` when set, so that an iteration its
+// consumer neither drains nor cancels stops with `context.DeadlineExceeded` pushed to its
+// iterator, releasing its read transaction (and slot), instead of being held forever. The
+// returned cancel func must be called once the iteration completes.
+func (s *Store) iteratorContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.iteratorMaxDuration == 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, s.iteratorMaxDuration)
+}
+
+// iterationRecorder gathers the statistics of an iteration for the `store.IteratorObserver`
+// set on the store, it does nothing but push items when there is none.
+type iterationRecorder struct {
+	observer store.IteratorObserver
+	stats    store.IteratorStats
+	start    time.Time
+}
+
+func (s *Store) newIterationRecorder(operation string) *iterationRecorder {
+	if s.iteratorObserver == nil {
+		return &iterationRecorder{}
+	}
+
+	return &iterationRecorder{
+		observer: s.iteratorObserver,
+		stats:    store.IteratorStats{Operation: operation},
+		start:    time.Now(),
+	}
+}
+
+// push pushes `kv` to `it`, counting it when the push succeeds, like `store.Iterator.PushItem`
+// it returns false when the context of the iterator is done.
+func (r *iterationRecorder) push(it *store.Iterator, kv store.KV) bool {
+	if !it.PushItem(kv) {
+		r.stats.Canceled = true
+		return false
+	}
+
+	r.stats.Items++
+	r.stats.Bytes += uint64(kv.Size())
+	return true
+}
+
+func (r *iterationRecorder) done(err error) {
+	if r.observer == nil {
+		return
+	}
+
+	// The context can also be done before any item is pushed, while waiting for a slot
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		r.stats.Canceled = true
+	}
+
+	r.stats.Duration = time.Since(r.start)
+	r.stats.Err = err
+	r.observer(r.stats)
+}
+
+// iteratorView runs `fn` in a read transaction like `db.View`, for the goroutines feeding a
+// `store.Iterator`. With `max_concurrent_iterators`, it first waits for one of the slots to
+// free up, so that no more than that many read transactions are held by iterations at once,
+// each of them pinning the tables and value log files it reads from garbage collection.
+//
+// The slot is only released once the iteration completes, so an iterator must be drained or
+// its context canceled. A consumer holding more iterators at once than there are slots can
+// deadlock, waiting on an iterator that waits for a slot held by another one.
+func (s *Store) iteratorView(ctx context.Context, fn func(txn *badger.Txn) error) error {
+	if s.iteratorSlots != nil {
+		select {
+		case s.iteratorSlots <- struct{}{}:
+			defer func() { <-s.iteratorSlots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return s.db.View(fn)
+}
+
+// badgerIteratorOptions prefetches `PrefetchSize` values when given, `iterator_prefetch_size`
+// otherwise, never more than `limit`.
+func (s *Store) badgerIteratorOptions(limit store.Limit, options []store.ReadOption) badger.IteratorOptions {
+	if limit.Unbounded() && len(options) == 0 && s.iteratorPrefetchSize == 0 {
 		return badger.DefaultIteratorOptions
 	}
 
@@ -385,6 +1229,12 @@ func badgerIteratorOptions(limit store.Limit, options []store.ReadOption) badger
 	}
 
 	opts := badger.DefaultIteratorOptions
+	if readOptions.PrefetchSize > 0 {
+		opts.PrefetchSize = readOptions.PrefetchSize
+	} else if s.iteratorPrefetchSize > 0 {
+		opts.PrefetchSize = s.iteratorPrefetchSize
+	}
+
 	if readOptions.KeyOnly {
 		opts.PrefetchValues = false
 	} else if limit.Bounded() && int(limit) < opts.PrefetchSize {
@@ -1,16 +1,26 @@
 package badger
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/dfuse-io/kvdb/store"
 	"github.com/dfuse-io/kvdb/store/storetest"
 	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func init() {
@@ -21,7 +31,11 @@ func TestAll(t *testing.T) {
 	storetest.TestAll(t, "Badger", NewTestBadgerFactory(t, "badger-test.db"))
 }
 
-func NewTestBadgerFactory(t *testing.T, testDBFilename string) storetest.DriverFactory {
+func BenchmarkAll(b *testing.B) {
+	storetest.Benchmark(b, NewTestBadgerFactory(b, "badger-bench.db"))
+}
+
+func NewTestBadgerFactory(t testing.TB, testDBFilename string) storetest.DriverFactory {
 	return func(opts ...store.Option) (store.KVStore, *storetest.DriverCapabilities, storetest.DriverCleanupFunc) {
 		dir, err := ioutil.TempDir("", "kvdb-badger")
 		require.NoError(t, err)
@@ -35,3 +49,1089 @@ func NewTestBadgerFactory(t *testing.T, testDBFilename string) storetest.DriverF
 		}
 	}
 }
+
+func newTestStore(t *testing.T, dsnQuery string) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+
+	dsn := fmt.Sprintf("badger://%s", path.Join(dir, "test.db"))
+	if dsnQuery != "" {
+		dsn += "?" + dsnQuery
+	}
+
+	kvStore, err := NewStore(dsn)
+	require.NoError(t, err)
+
+	return kvStore.(*Store), func() {
+		kvStore.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRequestIDLogging(t *testing.T) {
+	kvStore, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := logging.WithLogger(store.WithRequestID(context.Background(), "0000000aabbccdd"), zap.New(core))
+
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+	drain(t, kvStore.Scan(ctx, []byte("a"), []byte("b"), store.Unlimited))
+	drain(t, kvStore.Prefix(ctx, []byte("a"), store.Unlimited))
+
+	for _, message := range []string{"putting", "scanning", "prefix scanning"} {
+		entries := logs.FilterMessage(message).All()
+		require.Len(t, entries, 1, "expected one %q log line", message)
+		assert.Equal(t, "0000000aabbccdd", entries[0].ContextMap()["request_id"])
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core).With(zap.String("instance", "accounts"))
+
+	kvStore, err := store.New(fmt.Sprintf("badger://%s", path.Join(dir, "test.db")), store.WithLogger(logger))
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+	drain(t, kvStore.Prefix(ctx, []byte("a"), store.Unlimited))
+
+	for _, message := range []string{"putting", "prefix scanning"} {
+		entries := logs.FilterMessage(message).All()
+		require.Len(t, entries, 1, "expected one %q log line", message)
+		assert.Equal(t, "accounts", entries[0].ContextMap()["instance"])
+	}
+}
+
+func drain(t *testing.T, it *store.Iterator) (out []store.KV) {
+	for it.Next() {
+		out = append(out, it.Item())
+	}
+	require.NoError(t, it.Err())
+	return
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchmarkGet(b, func(ctx context.Context, s *Store, key, buf []byte) ([]byte, error) {
+		return s.Get(ctx, key)
+	})
+}
+
+func BenchmarkGetInto(b *testing.B) {
+	benchmarkGet(b, func(ctx context.Context, s *Store, key, buf []byte) ([]byte, error) {
+		return s.GetInto(ctx, key, buf)
+	})
+}
+
+func benchmarkGet(b *testing.B, get func(ctx context.Context, s *Store, key, buf []byte) ([]byte, error)) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := NewStore(fmt.Sprintf("badger://%s", path.Join(dir, "bench.db")))
+	require.NoError(b, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	key := []byte("key")
+	require.NoError(b, kvStore.Put(ctx, key, bytes.Repeat([]byte("v"), 512)))
+	require.NoError(b, kvStore.FlushPuts(ctx))
+
+	s := kvStore.(*Store)
+	buf := make([]byte, 0, 1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := get(ctx, s, key, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBatchGet_SingleKeyCopied(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("key"), []byte("value")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	key := []byte("key")
+	it := s.BatchGet(ctx, [][]byte{key})
+	copy(key, "xxx")
+
+	assert.Equal(t, []store.KV{{Key: []byte("key"), Value: []byte("value")}}, drain(t, it))
+}
+
+func BenchmarkBatchGet_SingleKey(b *testing.B) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := NewStore(fmt.Sprintf("badger://%s", path.Join(dir, "bench.db")))
+	require.NoError(b, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	key := []byte("key")
+	require.NoError(b, kvStore.Put(ctx, key, []byte("value")))
+	require.NoError(b, kvStore.FlushPuts(ctx))
+
+	keys := [][]byte{key}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		it := kvStore.BatchGet(ctx, keys)
+		for it.Next() {
+		}
+		if it.Err() != nil {
+			b.Fatal(it.Err())
+		}
+	}
+}
+
+func TestInsert_Concurrent(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	var inserted, existing int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := s.Insert(context.Background(), []byte("key"), []byte(fmt.Sprintf("value%d", i)))
+			switch err {
+			case nil:
+				atomic.AddInt32(&inserted, 1)
+			case store.ErrKeyExists:
+				atomic.AddInt32(&existing, 1)
+			default:
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), inserted)
+	assert.Equal(t, int32(9), existing)
+}
+
+func TestIncrement_Concurrent(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				_, err := s.Increment(context.Background(), []byte("counter"), 1)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := s.Increment(context.Background(), []byte("counter"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), value)
+}
+
+func TestUpdateWithRetry_Exhausted(t *testing.T) {
+	s, cleanup := newTestStore(t, "conflict_attempts=3")
+	defer cleanup()
+
+	attempts := 0
+	err := s.updateWithRetry(context.Background(), func(txn *badger.Txn) error {
+		attempts++
+		return badger.ErrConflict
+	})
+	assert.Equal(t, badger.ErrConflict, err)
+	assert.Equal(t, 3, attempts)
+
+	// Other errors are never retried
+	attempts = 0
+	err = s.updateWithRetry(context.Background(), func(txn *badger.Txn) error {
+		attempts++
+		return store.ErrKeyExists
+	})
+	assert.Equal(t, store.ErrKeyExists, err)
+	assert.Equal(t, 1, attempts)
+
+	_, err = NewStore("badger:///tmp/kvdb-badger-invalid?conflict_attempts=0")
+	assert.Error(t, err)
+}
+
+func BenchmarkPut_DebugDisabled(b *testing.B) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := store.New(fmt.Sprintf("badger://%s", path.Join(dir, "bench.db")), store.WithLogger(zap.NewNop()))
+	require.NoError(b, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%08d", i))
+	}
+	value := []byte("value")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if err := kvStore.Put(ctx, keys[n%len(keys)], value); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	require.NoError(b, kvStore.FlushPuts(ctx))
+}
+
+func TestFlushPuts_BatchBiggerThanTransaction(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	// Enough entries so that a single transaction cannot hold all of them
+	ctx := context.Background()
+	entryCount := 150000
+	for i := 0; i < entryCount; i++ {
+		require.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("key%08d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+
+	require.NoError(t, s.FlushPuts(ctx))
+	assert.Equal(t, int64(0), s.pendingPutCount)
+
+	for _, i := range []int{0, 1, entryCount / 2, entryCount - 1} {
+		value, err := s.Get(ctx, []byte(fmt.Sprintf("key%08d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("value%d", i), string(value))
+	}
+
+	assert.Len(t, drain(t, s.Prefix(ctx, []byte("key"), store.Unlimited, store.KeyOnly())), entryCount)
+}
+
+func TestFlushPuts_ConcurrentPuts(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				assert.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("key%d-%04d", writer, j)), []byte("value")))
+				if j%100 == 0 {
+					assert.NoError(t, s.FlushPuts(ctx))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, s.FlushPuts(ctx))
+	assert.Len(t, drain(t, s.Prefix(ctx, []byte("key"), store.Unlimited, store.KeyOnly())), 4*500)
+}
+
+func TestCapabilities(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	assert.Equal(t, store.Capabilities{
+		EmptyValue:        true,
+		Insert:            true,
+		Increment:         true,
+		CompareAndSwap:    true,
+		CompareAndSwapTTL: true,
+		ReverseScan:       true,
+		Stream:            true,
+		RenamePrefix:      true,
+		ReplacePrefix:     true,
+		Seek:              true,
+		Apply:             true,
+		MultiPrefix:       true,
+	}, s.Capabilities())
+}
+
+func TestBlockCacheAndWarmup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dsn := fmt.Sprintf("badger://%s", path.Join(dir, "test.db"))
+
+	kvStore, err := NewStore(dsn)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, kvStore.Put(context.Background(), []byte(fmt.Sprintf("a%03d", i)), []byte("value")))
+		require.NoError(t, kvStore.Put(context.Background(), []byte(fmt.Sprintf("b%03d", i)), []byte("value")))
+	}
+	require.NoError(t, kvStore.FlushPuts(context.Background()))
+	require.NoError(t, kvStore.Close())
+
+	kvStore, err = NewStore(dsn + "?block_cache_size=1048576&warmup=true&warmup_prefix=61&warmup_prefix=62")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	s := kvStore.(*Store)
+	assert.NotNil(t, s.db.DataCacheMetrics(), "block cache should be configured")
+
+	select {
+	case <-s.warmupDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warm-up did not complete in time")
+	}
+	assert.NoError(t, s.warmupErr)
+
+	_, err = NewStore(dsn + "2?warmup=true&warmup_prefix=zz")
+	assert.Error(t, err)
+
+	_, err = NewStore(dsn + "3?block_cache_size=abc")
+	assert.Error(t, err)
+}
+
+func TestWarmup_StoppedOnClose(t *testing.T) {
+	s, cleanup := newTestStore(t, "warmup=true")
+	defer cleanup()
+
+	require.NoError(t, s.Close())
+
+	select {
+	case <-s.warmupDone:
+	default:
+		t.Fatal("warm-up should be completed once the store is closed")
+	}
+}
+
+func TestFlushPuts_BatchBoundaries(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Flushing without any put is a no-op
+	require.NoError(t, s.FlushPuts(ctx))
+
+	// Block #1
+	require.NoError(t, s.Put(ctx, []byte("block1/a"), []byte("1")))
+	require.NoError(t, s.Put(ctx, []byte("block1/b"), []byte("2")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	firstBatch := s.writeBatch
+	assert.Equal(t, int64(0), s.pendingPutCount)
+
+	// Block #2, not flushed yet, only its own entries are pending
+	require.NoError(t, s.Put(ctx, []byte("block2/a"), []byte("3")))
+	assert.Equal(t, int64(1), s.pendingPutCount)
+
+	_, err := s.Get(ctx, []byte("block2/a"))
+	assert.Equal(t, store.ErrNotFound, err, "entries of the current batch must not be visible before the flush")
+
+	value, err := s.Get(ctx, []byte("block1/b"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+
+	require.NoError(t, s.FlushPuts(ctx))
+	assert.False(t, firstBatch == s.writeBatch, "a flush must start a new write batch")
+	assert.Equal(t, int64(0), s.pendingPutCount)
+
+	assert.Equal(t, []string{"block1/a", "block1/b", "block2/a"}, keysOf(drain(t, s.Prefix(ctx, []byte("block"), store.Unlimited))))
+
+	// Flushing again right away commits nothing more
+	require.NoError(t, s.FlushPuts(ctx))
+	assert.Len(t, drain(t, s.Prefix(ctx, []byte("block"), store.Unlimited)), 3)
+}
+
+func TestGetStream(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	large := make([]byte, 16*1024*1024)
+	for i := range large {
+		large[i] = byte(i % 251)
+	}
+
+	require.NoError(t, s.Put(ctx, []byte("large"), large))
+	require.NoError(t, s.Put(ctx, []byte("empty"), []byte{}))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	buffered, err := s.Get(ctx, []byte("large"))
+	require.NoError(t, err)
+
+	reader, err := store.GetStream(ctx, s, []byte("large"))
+	require.NoError(t, err)
+	streamed, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.True(t, bytes.Equal(buffered, streamed), "streamed value must match the buffered one")
+
+	reader, err = s.GetStream(ctx, []byte("empty"))
+	require.NoError(t, err)
+	streamed, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Len(t, streamed, 0)
+
+	_, err = s.GetStream(ctx, []byte("missing"))
+	assert.Equal(t, store.ErrNotFound, err)
+
+	// Closing before the end must release the stream
+	reader, err = s.GetStream(ctx, []byte("large"))
+	require.NoError(t, err)
+	_, err = reader.Read(make([]byte, 1024))
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+}
+
+func TestValueSize_ValueLog(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	// Values above badger's value threshold live in the value log, their size must be exact too
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("a"), 64*1024)
+	require.NoError(t, s.Put(ctx, []byte("large"), value))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	size, err := s.ValueSize(ctx, []byte("large"))
+	require.NoError(t, err)
+	assert.Equal(t, len(value), size)
+}
+
+func TestGetInto(t *testing.T) {
+	for _, compression := range []string{"none", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			s, cleanup := newTestStore(t, "compression="+compression)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, s.Put(ctx, []byte("a"), []byte("first")))
+			require.NoError(t, s.Put(ctx, []byte("b"), []byte("second value")))
+			require.NoError(t, s.Put(ctx, []byte("empty"), []byte{}))
+			require.NoError(t, s.FlushPuts(ctx))
+
+			buf := make([]byte, 0, 64)
+			value, err := s.GetInto(ctx, []byte("a"), buf)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("first"), value)
+			assert.Equal(t, &buf[:1][0], &value[0], "buffer should be reused")
+
+			// Growing past the buffer capacity still works
+			value, err = s.GetInto(ctx, []byte("b"), make([]byte, 0, 2))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("second value"), value)
+
+			value, err = s.GetInto(ctx, []byte("empty"), nil)
+			require.NoError(t, err)
+			assert.Equal(t, []byte{}, value)
+
+			_, err = s.GetInto(ctx, []byte("missing"), buf)
+			assert.Equal(t, store.ErrNotFound, err)
+		})
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	s, cleanup := newTestStore(t, "compression=zstd")
+	defer cleanup()
+
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("value"), 100)
+	require.NoError(t, s.Put(ctx, []byte("identity"), value))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	compressed, decompressed, err := s.GetRaw(ctx, []byte("identity"))
+	require.NoError(t, err)
+	assert.Equal(t, value, compressed)
+	assert.Equal(t, value, decompressed)
+
+	// Written compressed like older versions did, reads decompress it transparently
+	zstdValue := store.NewZstdCompressor(0).Compress(value)
+	require.NoError(t, s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("zstd"), zstdValue)
+	}))
+
+	compressed, decompressed, err = s.GetRaw(ctx, []byte("zstd"))
+	require.NoError(t, err)
+	assert.Equal(t, zstdValue, compressed)
+	assert.Equal(t, value, decompressed)
+
+	// A corrupted compressed value is still returned raw
+	corrupted := append(append([]byte{}, zstdValue[:8]...), 0xff, 0xff)
+	require.NoError(t, s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("corrupted"), corrupted)
+	}))
+
+	compressed, _, err = s.GetRaw(ctx, []byte("corrupted"))
+	assert.Error(t, err)
+	assert.Equal(t, corrupted, compressed)
+
+	_, _, err = s.GetRaw(ctx, []byte("missing"))
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestRawReads(t *testing.T) {
+	s, cleanup := newTestStore(t, "compression=zstd")
+	defer cleanup()
+
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("value"), 100)
+	zstdValue := store.NewZstdCompressor(0).Compress(value)
+	require.NoError(t, s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("raw/zstd"), zstdValue)
+	}))
+
+	readAll := func(it *store.Iterator) (values [][]byte) {
+		for it.Next() {
+			values = append(values, it.Item().Value)
+		}
+		require.NoError(t, it.Err())
+		return values
+	}
+
+	stored, _, err := s.GetRaw(ctx, []byte("raw/zstd"))
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]byte{stored}, readAll(s.Scan(ctx, []byte("raw/"), []byte("raw0"), store.Unlimited, store.Raw())))
+	assert.Equal(t, [][]byte{stored}, readAll(s.Prefix(ctx, []byte("raw/"), store.Unlimited, store.Raw())))
+	assert.Equal(t, [][]byte{stored}, readAll(s.BatchPrefix(ctx, [][]byte{[]byte("raw/")}, store.Unlimited, store.Raw())))
+	assert.Equal(t, [][]byte{stored}, readAll(s.MultiPrefix(ctx, [][]byte{[]byte("raw/")}, store.Unlimited, store.Raw())))
+	assert.Equal(t, [][]byte{stored}, readAll(s.BatchGet(ctx, [][]byte{[]byte("raw/zstd")}, store.Raw())))
+	assert.Equal(t, [][]byte{value}, readAll(s.Scan(ctx, []byte("raw/"), []byte("raw0"), store.Unlimited)))
+
+	// The client decompresses raw values with the same algorithm
+	decompressed, err := store.NewZstdCompressor(0).Decompress(readAll(s.Prefix(ctx, []byte("raw/"), store.Unlimited, store.Raw()))[0])
+	require.NoError(t, err)
+	assert.Equal(t, value, decompressed)
+}
+
+func TestIteratorPrefetchSize(t *testing.T) {
+	s, cleanup := newTestStore(t, "iterator_prefetch_size=500")
+	defer cleanup()
+
+	assert.Equal(t, 500, s.badgerIteratorOptions(store.Unlimited, nil).PrefetchSize)
+	assert.Equal(t, 20, s.badgerIteratorOptions(store.Unlimited, []store.ReadOption{store.PrefetchSize(20)}).PrefetchSize)
+	assert.Equal(t, 10, s.badgerIteratorOptions(10, []store.ReadOption{store.PrefetchSize(20)}).PrefetchSize)
+
+	_, err := NewStore("badger:///tmp/kvdb-badger-invalid?iterator_prefetch_size=-1")
+	assert.Error(t, err)
+}
+
+func BenchmarkPrefix_PrefetchSize(b *testing.B) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := store.New(fmt.Sprintf("badger://%s", path.Join(dir, "bench.db")), store.WithLogger(zap.NewNop()))
+	require.NoError(b, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	value := make([]byte, 256)
+	for i := 0; i < 100000; i++ {
+		require.NoError(b, kvStore.Put(ctx, []byte(fmt.Sprintf("key%08d", i)), value))
+	}
+	require.NoError(b, kvStore.FlushPuts(ctx))
+
+	for _, prefetchSize := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("prefetch_%d", prefetchSize), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				it := kvStore.Prefix(ctx, []byte("key"), store.Unlimited, store.PrefetchSize(prefetchSize))
+				for it.Next() {
+				}
+				require.NoError(b, it.Err())
+			}
+		})
+	}
+}
+
+func TestKeyError(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	tooLongKey := make([]byte, 70000)
+	tooLongKey[0] = 0xab
+
+	var keyErr *store.KeyError
+	err := s.Put(ctx, tooLongKey, []byte("value"))
+	require.True(t, errors.As(err, &keyErr), "got %v", err)
+	assert.Equal(t, "put", keyErr.Op)
+	assert.Equal(t, store.Key(tooLongKey), keyErr.Key)
+
+	_, err = s.Get(ctx, []byte{})
+	require.True(t, errors.As(err, &keyErr), "got %v", err)
+	assert.Equal(t, "get", keyErr.Op)
+
+	err = s.Delete(ctx, []byte{})
+	require.True(t, errors.As(err, &keyErr), "got %v", err)
+	assert.Equal(t, "delete", keyErr.Op)
+	assert.True(t, errors.Is(err, badger.ErrEmptyKey))
+
+	// Not found stays a bare sentinel
+	_, err = s.Get(ctx, []byte("missing"))
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestMaxKeyLen(t *testing.T) {
+	s, cleanup := newTestStore(t, "max_key_len=8")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("short"), []byte("1")))
+
+	err := s.Put(ctx, []byte("much too long"), []byte("2"))
+	assert.True(t, errors.Is(err, store.ErrKeyTooLong), "got %v", err)
+
+	err = s.Insert(ctx, []byte("much too long"), []byte("2"))
+	assert.True(t, errors.Is(err, store.ErrKeyTooLong), "got %v", err)
+
+	_, err = s.Increment(ctx, []byte("much too long"), 1)
+	assert.True(t, errors.Is(err, store.ErrKeyTooLong), "got %v", err)
+
+	require.NoError(t, s.FlushPuts(ctx))
+	value, err := s.Get(ctx, []byte("short"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	for _, invalid := range []string{"0", "abc", "65001"} {
+		_, err := NewStore("badger:///tmp/kvdb-badger-invalid?max_key_len=" + invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMaxValueLen(t *testing.T) {
+	s, cleanup := newTestStore(t, "max_value_len=8")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("12345678")))
+
+	err := s.Put(ctx, []byte("b"), []byte("123456789"))
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %v", err)
+
+	err = s.Insert(ctx, []byte("b"), []byte("123456789"))
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %v", err)
+
+	err = s.Apply(ctx, []store.KV{{Key: []byte("b"), Value: []byte("123456789")}}, nil)
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %v", err)
+
+	require.NoError(t, s.FlushPuts(ctx))
+	value, err := s.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12345678"), value)
+
+	_, err = s.Get(ctx, []byte("b"))
+	assert.Equal(t, store.ErrNotFound, err)
+
+	for _, invalid := range []string{"0", "-1", "abc"} {
+		_, err := NewStore("badger:///tmp/kvdb-badger-invalid?max_value_len=" + invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSyncOnFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := NewStore(fmt.Sprintf("badger://%s?sync_on_flush=true", path.Join(dir, "test.db")))
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	// Copying the files of the still opened database simulates a crash right after the flush,
+	// the entry is only in the value log, which is replayed on open. This is best-effort, the
+	// copy goes through the page cache, so it cannot tell whether the sync reached the disk.
+	crashed := path.Join(dir, "crashed.db")
+	require.NoError(t, os.Mkdir(crashed, 0755))
+	files, err := ioutil.ReadDir(path.Join(dir, "test.db"))
+	require.NoError(t, err)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(path.Join(dir, "test.db", file.Name()))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path.Join(crashed, file.Name()), content, 0644))
+	}
+
+	reopened, err := NewStore(fmt.Sprintf("badger://%s", crashed))
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	value, err := reopened.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+}
+
+func TestMaxConcurrentIterators(t *testing.T) {
+	s, cleanup := newTestStore(t, "max_concurrent_iterators=2")
+	defer cleanup()
+
+	// More entries than an iterator buffers, so that an undrained iterator holds its slot
+	ctx := context.Background()
+	for i := 0; i < 500; i++ {
+		require.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("key%04d", i)), []byte("v")))
+	}
+	require.NoError(t, s.FlushPuts(ctx))
+
+	first := s.Prefix(ctx, []byte("key"), store.Unlimited)
+	second := s.Prefix(ctx, []byte("key"), store.Unlimited)
+	require.True(t, first.Next())
+	require.True(t, second.Next())
+
+	third := make(chan int)
+	go func() {
+		third <- len(drain(t, s.Prefix(ctx, []byte("key"), store.Unlimited)))
+	}()
+
+	select {
+	case <-third:
+		t.Fatal("third iterator should wait for a slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	drain(t, first)
+	assert.Equal(t, 500, <-third)
+	drain(t, second)
+
+	// Many more concurrent scans than slots all complete
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Len(t, drain(t, s.Scan(ctx, []byte("key0100"), []byte("key0200"), store.Unlimited)), 100)
+		}()
+	}
+	wg.Wait()
+
+	// Waiting for a slot stops with the context
+	blocking := s.Prefix(ctx, []byte("key"), store.Unlimited)
+	blocking2 := s.Prefix(ctx, []byte("key"), store.Unlimited)
+	require.True(t, blocking.Next())
+	require.True(t, blocking2.Next())
+
+	canceled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	it := s.Prefix(canceled, []byte("key"), store.Unlimited)
+	for it.Next() {
+	}
+	assert.Error(t, it.Err())
+
+	drain(t, blocking)
+	drain(t, blocking2)
+
+	_, err := NewStore("badger:///tmp/kvdb-badger-invalid?max_concurrent_iterators=0")
+	assert.Error(t, err)
+}
+
+func TestIteratorMaxDuration(t *testing.T) {
+	s, cleanup := newTestStore(t, "iterator_max_duration=100ms&max_concurrent_iterators=1")
+	defer cleanup()
+
+	// More entries than an iterator buffers, so that a stalled consumer blocks the iteration
+	ctx := context.Background()
+	for i := 0; i < 500; i++ {
+		require.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("key%04d", i)), []byte("v")))
+	}
+	require.NoError(t, s.FlushPuts(ctx))
+
+	stalled := s.Prefix(ctx, []byte("key"), store.Unlimited)
+	require.True(t, stalled.Next())
+
+	// The stalled iteration times out and releases the only slot, without which the next
+	// iteration would itself time out waiting for it
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, drain(t, s.Scan(ctx, []byte("key0100"), []byte("key0200"), store.Unlimited)), 100)
+
+	count := 1
+	for stalled.Next() {
+		count++
+	}
+	assert.True(t, errors.Is(stalled.Err(), context.DeadlineExceeded))
+	assert.Less(t, count, 500)
+
+	_, err := NewStore("badger:///tmp/kvdb-badger-invalid?iterator_max_duration=forever")
+	assert.Error(t, err)
+}
+
+func TestCloseFlushesPendingPuts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	dsn := fmt.Sprintf("badger://%s", path.Join(dir, "test.db"))
+
+	kvStore, err := NewStore(dsn)
+	require.NoError(t, err)
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.Close())
+
+	// A strict store reports the unflushed put and discards it
+	kvStore, err = NewStore(dsn + "?strict_close=true")
+	require.NoError(t, err)
+	require.NoError(t, kvStore.Put(ctx, []byte("b"), []byte("2")))
+	assert.Error(t, kvStore.Close())
+
+	kvStore, err = NewStore(dsn + "?strict_close=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	value, err := kvStore.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	_, err = kvStore.Get(ctx, []byte("b"))
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestIteratorObserver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lock := sync.Mutex{}
+	var observed []store.IteratorStats
+	observer := func(stats store.IteratorStats) {
+		lock.Lock()
+		defer lock.Unlock()
+		observed = append(observed, stats)
+	}
+
+	kvStore, err := store.New(fmt.Sprintf("badger://%s", path.Join(dir, "test.db")), store.WithIteratorObserver(observer))
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 500; i++ {
+		require.NoError(t, kvStore.Put(ctx, []byte(fmt.Sprintf("key%04d", i)), []byte("value")))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	assert.Len(t, drain(t, kvStore.Scan(ctx, []byte("key0100"), []byte("key0200"), store.Unlimited)), 100)
+	assert.Len(t, drain(t, kvStore.Prefix(ctx, []byte("key"), 10)), 10)
+
+	// Stopping early, the items pushed to the iterator buffer are still counted
+	canceled, cancel := context.WithCancel(ctx)
+	it := kvStore.Prefix(canceled, []byte("key"), store.Unlimited)
+	require.True(t, it.Next())
+	cancel()
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(observed) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, "Scan", observed[0].Operation)
+	assert.Equal(t, uint64(100), observed[0].Items)
+	assert.Equal(t, uint64(100*(7+5)), observed[0].Bytes)
+	assert.False(t, observed[0].Canceled)
+	assert.NoError(t, observed[0].Err)
+
+	assert.Equal(t, "Prefix", observed[1].Operation)
+	assert.Equal(t, uint64(10), observed[1].Items)
+	assert.False(t, observed[1].Canceled)
+
+	assert.Equal(t, "Prefix", observed[2].Operation)
+	assert.True(t, observed[2].Canceled)
+	assert.True(t, observed[2].Items < 500)
+}
+
+func TestOpenTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opened := make(chan struct{})
+	defer func(original func(opts badger.Options) (*badger.DB, error)) { openBadger = original }(openBadger)
+	openBadger = func(opts badger.Options) (*badger.DB, error) {
+		defer close(opened)
+		time.Sleep(500 * time.Millisecond)
+		return badger.Open(opts)
+	}
+
+	dsn := fmt.Sprintf("badger://%s", path.Join(dir, "test.db"))
+	start := time.Now()
+	_, err = NewStore(dsn + "?open_timeout=50ms")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond, "open should not wait for badger")
+
+	// The late open completes in the background and is closed, releasing the database
+	<-opened
+	openBadger = badger.Open
+	require.Eventually(t, func() bool {
+		kvStore, err := NewStore(dsn + "?open_timeout=10s")
+		if err != nil {
+			return false
+		}
+		return assert.NoError(t, kvStore.Close())
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = NewStore(dsn + "?open_timeout=never")
+	assert.Error(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	dsn := fmt.Sprintf("badger://%s", path.Join(dir, "test.db"))
+
+	kvStore, err := NewStore(dsn)
+	require.NoError(t, err)
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.Close())
+
+	kvStore, err = NewStore(dsn + "?read_only=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+	s := kvStore.(*Store)
+
+	assert.True(t, s.Capabilities().ReadOnly)
+
+	value, err := s.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.True(t, errors.Is(s.Put(ctx, []byte("b"), []byte("2")), store.ErrReadOnly))
+	assert.True(t, errors.Is(s.Insert(ctx, []byte("b"), []byte("2")), store.ErrReadOnly))
+	_, err = s.Increment(ctx, []byte("counter"), 1)
+	assert.True(t, errors.Is(err, store.ErrReadOnly))
+	assert.True(t, errors.Is(s.Delete(ctx, []byte("a")), store.ErrReadOnly))
+	assert.Equal(t, store.ErrReadOnly, s.BatchDelete(ctx, [][]byte{[]byte("a")}))
+	assert.Equal(t, store.ErrReadOnly, s.Apply(ctx, []store.KV{{Key: []byte("b"), Value: []byte("2")}}, nil))
+	assert.Equal(t, store.ErrReadOnly, s.RenamePrefix(ctx, []byte("a"), []byte("b")))
+}
+
+func TestRecoveryModeTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	dbPath := path.Join(dir, "test.db")
+	dsn := fmt.Sprintf("badger://%s", dbPath)
+
+	kvStore, err := NewStore(dsn)
+	require.NoError(t, err)
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+	require.NoError(t, kvStore.Close())
+
+	sizes, err := valueLogSizes(dbPath)
+	require.NoError(t, err)
+	require.Len(t, sizes, 1)
+
+	// Simulates a value log tail allocated but never written, as left by an unclean shutdown
+	for name := range sizes {
+		f, err := os.OpenFile(path.Join(dbPath, name), os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = f.Write(make([]byte, 64))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	_, err = NewStore(dsn)
+	assert.True(t, errors.Is(err, badger.ErrTruncateNeeded), "got %v", err)
+
+	kvStore, err = NewStore(dsn + "?recovery_mode=truncate")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	value, err := kvStore.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	recovered, err := valueLogSizes(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, sizes, recovered)
+
+	for _, invalid := range []string{"recovery_mode=other", "recovery_mode=truncate&read_only=true"} {
+		_, err := NewStore(dsn + "?" + invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// localClockLeaseStore hides `CompareAndSwapWithTTL`, leases then expire on the local clock
+type localClockLeaseStore struct {
+	*Store
+}
+
+func (s localClockLeaseStore) Capabilities() store.Capabilities {
+	capabilities := s.Store.Capabilities()
+	capabilities.CompareAndSwapTTL = false
+	return capabilities
+}
+
+func TestLease_ExpiresOnStoreClock(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	ok, err := store.AcquireLease(ctx, s, []byte("lease"), "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The entry expires with the lease, the stored expiry is not what keeps it held
+	txn := s.db.NewTransaction(false)
+	defer txn.Discard()
+	item, err := txn.Get([]byte("lease"))
+	require.NoError(t, err)
+	assert.True(t, item.ExpiresAt() >= uint64(time.Now().Add(time.Minute).Unix()))
+}
+
+func TestLease_ExpiresOnLocalClock(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	kv := localClockLeaseStore{s}
+	ctx := context.Background()
+	ok, err := store.AcquireLease(ctx, kv, []byte("lease"), "first", 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	txn := s.db.NewTransaction(false)
+	defer txn.Discard()
+	item, err := txn.Get([]byte("lease"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), item.ExpiresAt())
+
+	ok, err = store.AcquireLease(ctx, kv, []byte("lease"), "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	time.Sleep(100 * time.Millisecond)
+
+	ok, err = store.AcquireLease(ctx, kv, []byte("lease"), "second", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,127 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"go.uber.org/zap"
+)
+
+// bulkLoadBatchSize is the number of entries handed to badger's stream writer at once
+const bulkLoadBatchSize = 1000
+
+// BulkLoad writes all the entries received on `kvs`, until it is closed, through badger's
+// stream writer. It builds the tables directly, bypassing the write batch, the value log
+// compactions and the transactions, which makes it much faster than `Put` to backfill a
+// fresh store.
+//
+// The entries must be received in strictly ascending key order, and must not be modified
+// once sent. `BulkLoad` is only usable on an empty store, dedicated to the load: it fails
+// right away if the store has any data or puts not yet flushed, and it is not safe to use
+// `Put`, `BatchDelete` or any other write while it runs. Badger blocks other writes during
+// the load.
+//
+// When it fails (ctx done, unordered keys or a badger error), the entries already received
+// might have been partially written, the load must then be restarted from a new store.
+func (s *Store) BulkLoad(ctx context.Context, kvs <-chan store.KV) error {
+	zlogger := logging.Logger(ctx, s.logger)
+
+	if s.readOnly {
+		return store.ErrReadOnly
+	}
+
+	if pendingPutCount := atomic.LoadInt64(&s.pendingPutCount); pendingPutCount > 0 {
+		return fmt.Errorf("bulk load: %d puts are pending, they must be flushed first", pendingPutCount)
+	}
+
+	empty, err := s.isEmpty()
+	if err != nil {
+		return fmt.Errorf("bulk load: %w", err)
+	}
+	if !empty {
+		return fmt.Errorf("bulk load: store is not empty, bulk loading is only possible on an empty store")
+	}
+
+	writer := s.db.NewStreamWriter()
+	if err := writer.Prepare(); err != nil {
+		return fmt.Errorf("bulk load: prepare stream writer: %w", err)
+	}
+
+	start := time.Now()
+	count, err := s.streamEntries(ctx, writer, kvs)
+
+	// Flush must always be called once prepared, it is what resumes the writes badger blocked
+	flushErr := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("bulk load: %w", err)
+	}
+	if flushErr != nil {
+		return fmt.Errorf("bulk load: flush stream writer: %w", flushErr)
+	}
+
+	zlogger.Info("bulk load completed", zap.Int("entry_count", count), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+func (s *Store) streamEntries(ctx context.Context, writer *badger.StreamWriter, kvs <-chan store.KV) (count int, err error) {
+	var lastKey []byte
+	list := &pb.KVList{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return count, ctx.Err()
+
+		case kv, ok := <-kvs:
+			if !ok {
+				if err := writer.Write(list); err != nil {
+					return count, fmt.Errorf("write entries: %w", err)
+				}
+				return count, nil
+			}
+
+			if count > 0 && bytes.Compare(kv.Key, lastKey) <= 0 {
+				return count, fmt.Errorf("key %s received after key %s, keys must be strictly ascending", store.Key(kv.Key), store.Key(lastKey))
+			}
+			if err := s.checkKeyLen(kv.Key); err != nil {
+				return count, store.WrapKeyError("bulk load", kv.Key, err)
+			}
+			value := s.compressor.Compress(kv.Value)
+			if err := s.checkValueLen(value); err != nil {
+				return count, store.WrapKeyError("bulk load", kv.Key, err)
+			}
+			lastKey = kv.Key
+			count++
+
+			list.Kv = append(list.Kv, &pb.KV{Key: kv.Key, Value: value, Version: 1})
+			if len(list.Kv) >= bulkLoadBatchSize {
+				if err := writer.Write(list); err != nil {
+					return count, fmt.Errorf("write entries: %w", err)
+				}
+				list = &pb.KVList{}
+			}
+		}
+	}
+}
+
+func (s *Store) isEmpty() (empty bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.PrefetchValues = false
+		it := txn.NewIterator(options)
+		defer it.Close()
+
+		it.Rewind()
+		empty = !it.Valid()
+		return nil
+	})
+
+	return empty, err
+}
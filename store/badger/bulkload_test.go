@@ -0,0 +1,130 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBulkLoad(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	entryCount := 10000
+	require.NoError(t, s.BulkLoad(ctx, sortedEntries(entryCount, 40)))
+
+	it := s.Prefix(ctx, nil, store.Unlimited)
+	count := 0
+	for it.Next() {
+		assert.Equal(t, bulkLoadKey(count), it.Item().Key)
+		assert.Equal(t, bulkLoadValue(count, 40), it.Item().Value)
+		count++
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, entryCount, count)
+
+	value, err := s.Get(ctx, bulkLoadKey(42))
+	require.NoError(t, err)
+	assert.Equal(t, bulkLoadValue(42, 40), value)
+
+	// Regular writes go on once the load is completed
+	require.NoError(t, s.Put(ctx, bulkLoadKey(42), []byte("overwritten")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	value, err = s.Get(ctx, bulkLoadKey(42))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("overwritten"), value)
+
+	// The store is not empty anymore
+	assert.Error(t, s.BulkLoad(ctx, sortedEntries(1, 40)))
+}
+
+func TestBulkLoad_Unordered(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	kvs := make(chan store.KV, 2)
+	kvs <- store.KV{Key: []byte("b"), Value: []byte("1")}
+	kvs <- store.KV{Key: []byte("a"), Value: []byte("2")}
+	close(kvs)
+
+	assert.Error(t, s.BulkLoad(context.Background(), kvs))
+}
+
+func TestBulkLoad_PendingPuts(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("1")))
+	assert.Error(t, s.BulkLoad(ctx, sortedEntries(1, 10)))
+}
+
+func sortedEntries(count int, valueSize int) <-chan store.KV {
+	kvs := make(chan store.KV, 1000)
+	go func() {
+		defer close(kvs)
+		for i := 0; i < count; i++ {
+			kvs <- store.KV{Key: bulkLoadKey(i), Value: bulkLoadValue(i, valueSize)}
+		}
+	}()
+	return kvs
+}
+
+func bulkLoadKey(i int) []byte {
+	return []byte(fmt.Sprintf("key%010d", i))
+}
+
+func bulkLoadValue(i int, size int) []byte {
+	value := make([]byte, size)
+	for j := range value {
+		value[j] = byte(i + j)
+	}
+	return value
+}
+
+const benchmarkLoadEntryCount = 1000000
+
+func BenchmarkLoad_BulkLoad(b *testing.B) {
+	benchmarkLoad(b, func(ctx context.Context, s *Store) error {
+		return s.BulkLoad(ctx, sortedEntries(benchmarkLoadEntryCount, 40))
+	})
+}
+
+func BenchmarkLoad_WriteBatch(b *testing.B) {
+	benchmarkLoad(b, func(ctx context.Context, s *Store) error {
+		for kv := range sortedEntries(benchmarkLoadEntryCount, 40) {
+			if err := s.Put(ctx, kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return s.FlushPuts(ctx)
+	})
+}
+
+func benchmarkLoad(b *testing.B, load func(ctx context.Context, s *Store) error) {
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		dir, err := ioutil.TempDir("", "kvdb-badger")
+		require.NoError(b, err)
+
+		kvStore, err := store.New(fmt.Sprintf("badger://%s", path.Join(dir, "bench.db")), store.WithLogger(zap.NewNop()))
+		require.NoError(b, err)
+		b.StartTimer()
+
+		require.NoError(b, load(context.Background(), kvStore.(*Store)))
+
+		b.StopTimer()
+		kvStore.Close()
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,45 @@
+package badger
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// badgerLogger forwards badger's internal logs to the store's logger, levels mapping one to
+// one. It reads the logger of the store on each call, so a logger set through
+// `store.WithLogger` after the database is opened receives them too.
+type badgerLogger struct {
+	store *Store
+}
+
+func (l *badgerLogger) Errorf(format string, args ...interface{}) {
+	l.log(zap.ErrorLevel, format, args)
+}
+
+func (l *badgerLogger) Warningf(format string, args ...interface{}) {
+	l.log(zap.WarnLevel, format, args)
+}
+
+func (l *badgerLogger) Infof(format string, args ...interface{}) {
+	l.log(zap.InfoLevel, format, args)
+}
+
+func (l *badgerLogger) Debugf(format string, args ...interface{}) {
+	l.log(zap.DebugLevel, format, args)
+}
+
+func (l *badgerLogger) log(level zapcore.Level, format string, args []interface{}) {
+	logger := l.store.logger
+	// Checked first so that the message is only formatted when the level is enabled
+	if !logger.Core().Enabled(level) {
+		return
+	}
+
+	// Badger messages often end with a new line, zap adds its own
+	if ce := logger.Check(level, strings.TrimSpace(fmt.Sprintf(format, args...))); ce != nil {
+		ce.Write(zap.String("component", "badger"))
+	}
+}
@@ -0,0 +1,53 @@
+package badger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBadgerLogger(t *testing.T) {
+	s, logs, cleanup := newObservedTestStore(t, "")
+	defer cleanup()
+
+	(&badgerLogger{store: s}).Warningf("value log %d is corrupted\n", 3)
+	entries := logs.FilterMessage("value log 3 is corrupted").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "badger", entries[0].ContextMap()["component"])
+
+	// Logs emitted by badger itself reach the store logger
+	require.NoError(t, s.db.DropAll())
+	assert.NotEmpty(t, logs.FilterField(zap.String("component", "badger")).FilterMessageSnippet("DropAll").All())
+}
+
+func TestBadgerLogger_Silent(t *testing.T) {
+	s, logs, cleanup := newObservedTestStore(t, "silent=true")
+	defer cleanup()
+
+	require.NoError(t, s.db.DropAll())
+	assert.Empty(t, logs.FilterField(zap.String("component", "badger")).All())
+}
+
+func newObservedTestStore(t *testing.T, dsnQuery string) (*Store, *observer.ObservedLogs, func()) {
+	dir, err := ioutil.TempDir("", "kvdb-badger")
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.DebugLevel)
+	kvStore, err := store.New(fmt.Sprintf("badger://%s?%s", path.Join(dir, "test.db"), dsnQuery), store.WithLogger(zap.New(core)))
+	require.NoError(t, err)
+
+	return kvStore.(*Store), logs, func() {
+		kvStore.Close()
+		os.RemoveAll(dir)
+	}
+}
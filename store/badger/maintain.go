@@ -0,0 +1,55 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// maintenanceGCDiscardRatio is the minimum ratio of stale data a value log file must hold to
+// be rewritten, badger's recommended value
+const maintenanceGCDiscardRatio = 0.5
+
+// Maintain compacts the database, typically after a backfill that overwrote or deleted many
+// keys, to bring read performance back to its steady state. It never runs on its own, callers
+// decide when the cost is worth it.
+//
+// All the levels of the LSM tree are first merged into one, dropping overwritten and deleted
+// entries, then value log files are garbage collected one at a time until none holds enough
+// stale data anymore. Writes can go on meanwhile, but it competes with them for I/O.
+//
+// The duration is bounded by `ctx`: value log garbage collection stops between two files once
+// it is done, returning nil since the work done so far is kept. Flattening cannot be
+// interrupted once started, it is skipped when `ctx` is already done.
+func (s *Store) Maintain(ctx context.Context) error {
+	zlogger := logging.Logger(ctx, s.logger)
+	start := time.Now()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	zlogger.Info("flattening lsm tree")
+	if err := s.db.Flatten(1); err != nil {
+		return fmt.Errorf("flatten: %w", err)
+	}
+
+	rewrittenCount := 0
+	for ctx.Err() == nil {
+		err := s.db.RunValueLogGC(maintenanceGCDiscardRatio)
+		if err == badger.ErrNoRewrite {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("value log gc: %w", err)
+		}
+		rewrittenCount++
+	}
+
+	zlogger.Info("maintenance completed", zap.Int("rewritten_value_log_count", rewrittenCount), zap.Duration("elapsed", time.Since(start)), zap.Bool("interrupted", ctx.Err() != nil))
+	return nil
+}
@@ -0,0 +1,43 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintain(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	// Values large enough to live in the value log, overwritten then partly deleted
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("v"), 4096)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("key%04d", i)), value))
+		}
+		require.NoError(t, s.FlushPuts(ctx))
+	}
+	for i := 0; i < 500; i++ {
+		require.NoError(t, s.Delete(ctx, []byte(fmt.Sprintf("key%04d", i))))
+	}
+
+	require.NoError(t, s.Maintain(ctx))
+
+	got, err := s.Get(ctx, []byte("key0999"))
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	exists, err := s.Exists(ctx, []byte("key0000"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, s.Maintain(canceled))
+}
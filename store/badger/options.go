@@ -1,5 +1,36 @@
 package badger
 
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
+func (s *Store) SetIteratorObserver(observer store.IteratorObserver) {
+	s.iteratorObserver = observer
+}
+
 func (s *Store) EnableEmpty() {
-	zlog.Info("discarding possible empty value on store implementation, not required for this store")
+	s.logger.Info("discarding possible empty value on store implementation, not required for this store")
+}
+
+func (s *Store) Capabilities() store.Capabilities {
+	return store.Capabilities{
+		EmptyValue:        true,
+		Insert:            true,
+		Increment:         true,
+		CompareAndSwap:    true,
+		CompareAndSwapTTL: true,
+		ReverseScan:       true,
+		Stream:            true,
+		RenamePrefix:      true,
+		ReplacePrefix:     true,
+		Seek:              true,
+		Apply:             true,
+		ReadOnly:          s.readOnly,
+		MultiPrefix:       true,
+	}
 }
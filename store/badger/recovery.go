@@ -0,0 +1,60 @@
+package badger
+
+import (
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// valueLogSizes returns the size of each value log file of the database living in `dir`, by
+// file name. A missing directory has no value log files.
+func valueLogSizes(dir string) (map[string]int64, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.vlog"))
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		sizes[filepath.Base(path)] = info.Size()
+	}
+
+	return sizes, nil
+}
+
+// logTruncatedValueLogs reports the value log files that badger truncated or deleted while
+// opening with `recovery_mode=truncate`, comparing their sizes before opening, `before`, with
+// their current ones.
+func (s *Store) logTruncatedValueLogs(dir string, before map[string]int64) {
+	after, err := valueLogSizes(dir)
+	if err != nil {
+		s.logger.Warn("unable to tell what recovery truncated from the value log", zap.Error(err))
+		return
+	}
+
+	truncated := 0
+	for name, sizeBefore := range before {
+		sizeAfter, found := after[name]
+		if found && sizeAfter >= sizeBefore {
+			continue
+		}
+
+		truncated++
+		s.logger.Warn("value log file truncated by recovery, the entries written in its dropped tail are lost",
+			zap.String("file", filepath.Join(dir, name)),
+			zap.Bool("deleted", !found),
+			zap.Int64("size_before", sizeBefore),
+			zap.Int64("size_after", sizeAfter),
+			zap.Int64("truncated_bytes", sizeBefore-sizeAfter),
+		)
+	}
+
+	if truncated == 0 {
+		s.logger.Info("recovery mode did not need to truncate the value log")
+	}
+}
@@ -0,0 +1,110 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// prefixWriter is implemented by both `badger.Txn` and `badger.WriteBatch`
+type prefixWriter interface {
+	SetEntry(e *badger.Entry) error
+	Delete(key []byte) error
+}
+
+// RenamePrefix deletes all the keys starting with `to` then moves all the keys starting with
+// `from` under `to`, see `store.PrefixRenamer`. Values are moved as stored, they are never
+// decompressed.
+//
+// The rename is atomic as long as all the deletes and moves fit in a single badger
+// transaction. When they do not, the rename is performed through a write batch, which commits
+// multiple transactions: readers can then observe a partially renamed prefix, and a failure
+// midway can leave it so. Calling `RenamePrefix` again completes the rename in that case.
+//
+// Pending puts are flushed first, so that a put made before the rename is renamed along, or
+// deleted when under `to`, instead of landing after it. The single transaction is retried when
+// a concurrent write under either prefix conflicts with it, see `updateWithRetry`.
+func (s *Store) RenamePrefix(ctx context.Context, from, to []byte) error {
+	zlogger := logging.Logger(ctx, s.logger)
+	zlogger.Debug("renaming prefix", zap.Stringer("from", store.Key(from)), zap.Stringer("to", store.Key(to)), store.RequestIDField(ctx))
+
+	if s.readOnly {
+		return store.ErrReadOnly
+	}
+
+	if err := store.CheckRenamePrefixes(from, to); err != nil {
+		return err
+	}
+
+	if err := s.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("rename prefix: flush pending puts: %w", err)
+	}
+
+	err := s.updateWithRetry(ctx, func(txn *badger.Txn) error {
+		return renamePrefix(txn, txn, from, to)
+	})
+	if err != badger.ErrTxnTooBig {
+		return err
+	}
+
+	zlogger.Info("prefix rename too big for a single transaction, renaming through a write batch, it will not be atomic", zap.Stringer("from", store.Key(from)), zap.Stringer("to", store.Key(to)))
+
+	writeBatch := s.db.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	err = s.db.View(func(txn *badger.Txn) error {
+		return renamePrefix(txn, writeBatch, from, to)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeBatch.Flush()
+}
+
+// renamePrefix reads the keys to delete and move from `txn` and writes the changes to `w`.
+// Iterators only see the writes made to `txn` before their creation, so `txn` can also be
+// used as `w`.
+func renamePrefix(txn *badger.Txn, w prefixWriter, from, to []byte) error {
+	keyOnlyOptions := badger.DefaultIteratorOptions
+	keyOnlyOptions.PrefetchValues = false
+	keyOnlyOptions.Prefix = to
+
+	staleIt := txn.NewIterator(keyOnlyOptions)
+	for staleIt.Rewind(); staleIt.Valid(); staleIt.Next() {
+		if err := w.Delete(staleIt.Item().KeyCopy(nil)); err != nil {
+			staleIt.Close()
+			return err
+		}
+	}
+	staleIt.Close()
+
+	options := badger.DefaultIteratorOptions
+	options.Prefix = from
+
+	it := txn.NewIterator(options)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		key := it.Item().KeyCopy(nil)
+		value, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		renamed := append(append(make([]byte, 0, len(to)+len(key)-len(from)), to...), key[len(from):]...)
+		if err := w.SetEntry(badger.NewEntry(renamed, value)); err != nil {
+			return err
+		}
+
+		if err := w.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
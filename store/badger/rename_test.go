@@ -0,0 +1,66 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenamePrefix_TooBigForTransaction(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	// Enough entries so that a single transaction cannot hold the whole rename
+	ctx := context.Background()
+	entryCount := 150000
+	for i := 0; i < entryCount; i++ {
+		require.NoError(t, s.Put(ctx, []byte(fmt.Sprintf("tmp:%08d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+	require.NoError(t, s.Put(ctx, []byte("idx:stale"), []byte("stale")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	require.NoError(t, s.RenamePrefix(ctx, []byte("tmp:"), []byte("idx:")))
+
+	for _, i := range []int{0, entryCount / 2, entryCount - 1} {
+		value, err := s.Get(ctx, []byte(fmt.Sprintf("idx:%08d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("value%d", i), string(value))
+	}
+
+	_, err := s.Get(ctx, []byte("idx:stale"))
+	assert.Equal(t, store.ErrNotFound, err)
+
+	assert.Len(t, drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited, store.KeyOnly())), entryCount)
+	assert.Len(t, drain(t, s.Prefix(ctx, []byte("tmp:"), store.Unlimited, store.KeyOnly())), 0)
+}
+
+func TestRenamePrefix_ConcurrentWriters(t *testing.T) {
+	s, cleanup := newTestStore(t, "conflict_attempts=100")
+	defer cleanup()
+
+	// Each writer renames its own staging prefix into the shared one, every rename reading the
+	// whole destination conflicts with the others, retries resolve it
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for writer := 0; writer < 2; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			staging := []byte(fmt.Sprintf("tmp%d:", writer))
+			for generation := 0; generation < 20; generation++ {
+				assert.NoError(t, s.Put(ctx, append(staging, fmt.Sprintf("%02d", generation)...), []byte("entry")))
+				assert.NoError(t, s.RenamePrefix(ctx, staging, []byte("idx:")))
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	kvs := drain(t, s.Prefix(ctx, []byte("idx:"), store.Unlimited))
+	require.Len(t, kvs, 1)
+	assert.Equal(t, "idx:19", string(kvs[0].Key))
+}
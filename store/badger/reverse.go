@@ -0,0 +1,233 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// ReverseScan returns the keys in the range [start, exclusiveEnd[ in descending order, the
+// greatest key lower than `exclusiveEnd` coming first.
+func (s *Store) ReverseScan(ctx context.Context, start, exclusiveEnd []byte, limit int) *store.Iterator {
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
+	kr := store.NewIterator(ctx)
+	if ce := zlogger.Check(zap.DebugLevel, "reverse scanning"); ce != nil {
+		ce.Write(zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)), store.RequestIDField(ctx))
+	}
+
+	go func() {
+		defer cancel()
+
+		// Like `Scan`, an empty exclusive end bounds nothing
+		if len(exclusiveEnd) == 0 {
+			kr.PushFinished()
+			return
+		}
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limit), nil)
+			badgerOptions.Reverse = true
+			bit := txn.NewIterator(badgerOptions)
+			defer bit.Close()
+
+			count := uint64(0)
+			for seekBefore(bit, exclusiveEnd); bit.Valid() && bytes.Compare(bit.Item().Key(), start) >= 0; bit.Next() {
+				count++
+
+				value, err := s.readValue(bit.Item())
+				if err != nil {
+					return err
+				}
+
+				if !kr.PushItem(store.KV{Key: bit.Item().KeyCopy(nil), Value: value}) {
+					break
+				}
+
+				if store.Limit(limit).Reached(count) {
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// ReversePrefix returns the keys starting with `prefix` in descending order.
+func (s *Store) ReversePrefix(ctx context.Context, prefix []byte, limit int) *store.Iterator {
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
+	kr := store.NewIterator(ctx)
+	if ce := zlogger.Check(zap.DebugLevel, "reverse prefix scanning"); ce != nil {
+		ce.Write(zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)), store.RequestIDField(ctx))
+	}
+
+	go func() {
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			badgerOptions := s.badgerIteratorOptions(store.Limit(limit), nil)
+			// `Prefix` is not set in the options on purpose, badger would then consider the
+			// prefix successor we seek to as invalid, preventing to step over it
+			badgerOptions.Reverse = true
+			bit := txn.NewIterator(badgerOptions)
+			defer bit.Close()
+
+			count := uint64(0)
+			for seekBefore(bit, prefixSuccessor(prefix)); bit.ValidForPrefix(prefix); bit.Next() {
+				count++
+
+				value, err := s.readValue(bit.Item())
+				if err != nil {
+					return err
+				}
+
+				if !kr.PushItem(store.KV{Key: bit.Item().KeyCopy(nil), Value: value}) {
+					break
+				}
+
+				if store.Limit(limit).Reached(count) {
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// ScanAround returns, in ascending key order, up to `before` keys strictly lower than `pivot`,
+// the `pivot` key itself when it exists and up to `after` keys strictly greater than `pivot`.
+// The pivot is never counted in `before` nor `after`, so at most `before + after + 1` items
+// are returned.
+//
+// Both sides are read from the same snapshot of the database, a reverse scan from the pivot
+// for the lower keys and a forward scan from it for the pivot and the greater keys.
+func (s *Store) ScanAround(ctx context.Context, pivot []byte, before, after int) *store.Iterator {
+	zlogger := logging.Logger(ctx, s.logger)
+	ctx, cancel := s.iteratorContext(ctx)
+	kr := store.NewIterator(ctx)
+	if ce := zlogger.Check(zap.DebugLevel, "scanning around"); ce != nil {
+		ce.Write(zap.Stringer("pivot", store.Key(pivot)), zap.Int("before", before), zap.Int("after", after), store.RequestIDField(ctx))
+	}
+
+	go func() {
+		defer cancel()
+
+		err := s.iteratorView(ctx, func(txn *badger.Txn) error {
+			lowers, err := s.collectBefore(txn, pivot, before)
+			if err != nil {
+				return err
+			}
+
+			// Lower keys were collected in descending order
+			for i := len(lowers) - 1; i >= 0; i-- {
+				if !kr.PushItem(lowers[i]) {
+					return nil
+				}
+			}
+
+			bit := txn.NewIterator(s.badgerIteratorOptions(store.Limit(after+1), nil))
+			defer bit.Close()
+
+			count := 0
+			for bit.Seek(pivot); bit.Valid(); bit.Next() {
+				isPivot := bytes.Equal(bit.Item().Key(), pivot)
+				if !isPivot {
+					if count >= after {
+						break
+					}
+					count++
+				}
+
+				value, err := s.readValue(bit.Item())
+				if err != nil {
+					return err
+				}
+
+				if !kr.PushItem(store.KV{Key: bit.Item().KeyCopy(nil), Value: value}) {
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+func (s *Store) collectBefore(txn *badger.Txn, pivot []byte, count int) (out []store.KV, err error) {
+	// Nothing can be lower than an empty pivot
+	if count <= 0 || len(pivot) == 0 {
+		return nil, nil
+	}
+
+	badgerOptions := s.badgerIteratorOptions(store.Limit(count), nil)
+	badgerOptions.Reverse = true
+	bit := txn.NewIterator(badgerOptions)
+	defer bit.Close()
+
+	for seekBefore(bit, pivot); bit.Valid() && len(out) < count; bit.Next() {
+		value, err := s.readValue(bit.Item())
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, store.KV{Key: bit.Item().KeyCopy(nil), Value: value})
+	}
+
+	return out, nil
+}
+
+// seekBefore positions the reverse iterator `it` on the greatest key strictly lower than `key`,
+// or on the greatest key of the database when `key` is nil.
+func seekBefore(it *badger.Iterator, key []byte) {
+	if key == nil {
+		it.Rewind()
+		return
+	}
+
+	it.Seek(key)
+	if it.Valid() && bytes.Equal(it.Item().Key(), key) {
+		it.Next()
+	}
+}
+
+// prefixSuccessor returns the smallest key greater than all keys starting with `prefix`, or
+// nil when there is none (empty prefix or only 0xFF bytes).
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			successor := make([]byte, i+1)
+			copy(successor, prefix)
+			successor[i]++
+			return successor
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package badger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ store.ReversibleKVStore = (*Store)(nil)
+
+func newTestAroundStore(t *testing.T) (*Store, func()) {
+	s, cleanup := newTestStore(t, "")
+
+	for _, key := range []string{"a", "b1", "b2", "b3", "b4", "c", "d"} {
+		require.NoError(t, s.Put(context.Background(), []byte(key), []byte("v"+key)))
+	}
+	require.NoError(t, s.FlushPuts(context.Background()))
+
+	return s, cleanup
+}
+
+func TestReverseScan(t *testing.T) {
+	s, cleanup := newTestAroundStore(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		start        string
+		exclusiveEnd string
+		limit        int
+		expectedKeys []string
+	}{
+		{"full range", "", "z", store.Unlimited, []string{"d", "c", "b4", "b3", "b2", "b1", "a"}},
+		{"exclusive end", "b", "c", store.Unlimited, []string{"b4", "b3", "b2", "b1"}},
+		{"inclusive start", "b2", "b4", store.Unlimited, []string{"b3", "b2"}},
+		{"limited", "", "c", 2, []string{"b4", "b3"}},
+		{"empty end", "a", "", store.Unlimited, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedKeys, keysOf(drain(t, s.ReverseScan(context.Background(), []byte(test.start), []byte(test.exclusiveEnd), test.limit))))
+		})
+	}
+}
+
+func TestReversePrefix(t *testing.T) {
+	s, cleanup := newTestAroundStore(t)
+	defer cleanup()
+
+	assert.Equal(t, []string{"b4", "b3", "b2", "b1"}, keysOf(drain(t, s.ReversePrefix(context.Background(), []byte("b"), store.Unlimited))))
+	assert.Equal(t, []string{"b4", "b3"}, keysOf(drain(t, s.ReversePrefix(context.Background(), []byte("b"), 2))))
+	assert.Equal(t, []string{"d", "c", "b4", "b3", "b2", "b1", "a"}, keysOf(drain(t, s.ReversePrefix(context.Background(), nil, store.Unlimited))))
+	assert.Equal(t, []string(nil), keysOf(drain(t, s.ReversePrefix(context.Background(), []byte("e"), store.Unlimited))))
+}
+
+func TestScanAround(t *testing.T) {
+	s, cleanup := newTestAroundStore(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		pivot        string
+		before       int
+		after        int
+		expectedKeys []string
+	}{
+		{"existing pivot", "b2", 1, 1, []string{"b1", "b2", "b3"}},
+		{"existing pivot, wider", "b2", 2, 3, []string{"a", "b1", "b2", "b3", "b4", "c"}},
+		{"missing pivot", "b25", 2, 2, []string{"b1", "b2", "b3", "b4"}},
+		{"no before", "b2", 0, 2, []string{"b2", "b3", "b4"}},
+		{"no after", "b2", 2, 0, []string{"a", "b1", "b2"}},
+		{"pivot only", "c", 0, 0, []string{"c"}},
+		{"pivot at start", "a", 3, 1, []string{"a", "b1"}},
+		{"pivot at end", "d", 1, 3, []string{"c", "d"}},
+		{"pivot before all", "0", 2, 2, []string{"a", "b1"}},
+		{"pivot after all", "z", 2, 2, []string{"c", "d"}},
+		{"empty pivot", "", 2, 2, []string{"a", "b1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kvs := drain(t, s.ScanAround(context.Background(), []byte(test.pivot), test.before, test.after))
+			assert.Equal(t, test.expectedKeys, keysOf(kvs))
+
+			for _, kv := range kvs {
+				assert.Equal(t, "v"+string(kv.Key), string(kv.Value))
+			}
+		})
+	}
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	assert.Equal(t, []byte("c"), prefixSuccessor([]byte("b")))
+	assert.Equal(t, []byte{0x01, 0x03}, prefixSuccessor([]byte{0x01, 0x02, 0xFF}))
+	assert.Nil(t, prefixSuccessor([]byte{0xFF, 0xFF}))
+	assert.Nil(t, prefixSuccessor(nil))
+}
+
+func keysOf(kvs []store.KV) (out []string) {
+	for _, kv := range kvs {
+		out = append(out, string(kv.Key))
+	}
+	return
+}
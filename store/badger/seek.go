@@ -0,0 +1,59 @@
+package badger
+
+import (
+	"context"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// SeekFloor returns the greatest key lower than or equal to `key` along with its value, a
+// single reverse seek in badger.
+func (s *Store) SeekFloor(ctx context.Context, key []byte) (kv store.KV, err error) {
+	if ce := logging.Logger(ctx, s.logger).Check(zap.DebugLevel, "seeking floor"); ce != nil {
+		ce.Write(zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+	}
+
+	// Badger does not accept empty keys, so nothing can be lower than or equal to it
+	if len(key) == 0 {
+		return kv, store.ErrNotFound
+	}
+
+	return s.seek(key, true)
+}
+
+// SeekCeil returns the lowest key greater than or equal to `key` along with its value, a
+// single seek in badger.
+func (s *Store) SeekCeil(ctx context.Context, key []byte) (kv store.KV, err error) {
+	if ce := logging.Logger(ctx, s.logger).Check(zap.DebugLevel, "seeking ceil"); ce != nil {
+		ce.Write(zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+	}
+
+	return s.seek(key, false)
+}
+
+func (s *Store) seek(key []byte, reverse bool) (kv store.KV, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.PrefetchValues = false
+		options.Reverse = reverse
+		it := txn.NewIterator(options)
+		defer it.Close()
+
+		it.Seek(key)
+		if !it.Valid() {
+			return store.ErrNotFound
+		}
+
+		value, err := s.readValue(it.Item())
+		if err != nil {
+			return err
+		}
+
+		kv = store.KV{Key: it.Item().KeyCopy(nil), Value: value}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,48 @@
+package badger
+
+import (
+	"context"
+	"io"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"go.uber.org/zap"
+)
+
+// GetStream streams the decompressed value of `key`. The value is never copied nor fully
+// decompressed in memory, it's written to the returned reader as it's consumed. The read
+// transaction is kept open until the reader is closed or fully read, so the reader must
+// always be closed.
+func (s *Store) GetStream(ctx context.Context, key []byte) (io.ReadCloser, error) {
+	logging.Logger(ctx, s.logger).Debug("streaming", zap.Stringer("key", store.Key(key)), store.RequestIDField(ctx))
+
+	txn := s.db.NewTransaction(false)
+	item, err := txn.Get(key)
+	if err != nil {
+		txn.Discard()
+		return nil, wrapNotFoundError(err)
+	}
+
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer txn.Discard()
+
+		err := item.Value(func(value []byte) error {
+			return s.compressor.DecompressTo(writer, value)
+		})
+		writer.CloseWithError(err)
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			reader.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+
+	return reader, nil
+}
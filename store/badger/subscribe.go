@@ -0,0 +1,45 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// Subscribe calls `handler` for each key starting with `prefix` as soon as it is committed,
+// with its decompressed value. Changes are delivered in commit order, but the keys committed
+// together (a `FlushPuts` for example) come in no specific order. Only changes committed
+// after the subscription started are delivered, there is no replay of existing keys.
+//
+// Deleted keys are delivered too, with a nil value, badger's change feed does not allow to
+// tell them apart from empty values.
+//
+// It blocks until `ctx` is done, returning its error, or until `handler` returns an error,
+// which is then returned as is. Changes are delivered from a single goroutine, a slow handler
+// delays the following changes and, once badger's buffers are full, slows down the writes.
+func (s *Store) Subscribe(ctx context.Context, prefix []byte, handler func(store.KV) error) error {
+	zlogger := logging.Logger(ctx, s.logger)
+	zlogger.Debug("subscribing", zap.Stringer("prefix", store.Key(prefix)), store.RequestIDField(ctx))
+
+	return s.db.Subscribe(ctx, func(list *badger.KVList) error {
+		for _, kv := range list.Kv {
+			var value []byte
+			if len(kv.Value) > 0 {
+				var err error
+				value, err = s.compressor.Decompress(kv.Value)
+				if err != nil {
+					return fmt.Errorf("decompress value of key %s: %w", store.Key(kv.Key), err)
+				}
+			}
+
+			if err := handler(store.KV{Key: kv.Key, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, prefix)
+}
@@ -0,0 +1,93 @@
+package badger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	s, cleanup := newTestStore(t, "")
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan store.KV, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Subscribe(ctx, []byte("sub/"), func(kv store.KV) error {
+			received <- kv
+			return nil
+		})
+	}()
+
+	// The subscription is registered asynchronously, a marker key is written until it is seen
+	waitSubscribed(t, s, received)
+
+	require.NoError(t, s.Put(ctx, []byte("other/a"), []byte("ignored")))
+	require.NoError(t, s.Put(ctx, []byte("sub/a"), []byte("1")))
+	require.NoError(t, s.Put(ctx, []byte("su"), []byte("ignored")))
+	require.NoError(t, s.Put(ctx, []byte("sub/b"), []byte("2")))
+	require.NoError(t, s.FlushPuts(ctx))
+	require.NoError(t, s.BatchDelete(ctx, [][]byte{[]byte("sub/a"), []byte("other/a")}))
+
+	var got []store.KV
+	for len(got) < 3 {
+		select {
+		case kv := <-received:
+			got = append(got, kv)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for changes, got %d", len(got))
+		}
+	}
+
+	// Keys flushed together are committed together, in no specific order
+	assert.ElementsMatch(t, []store.KV{
+		{Key: []byte("sub/a"), Value: []byte("1")},
+		{Key: []byte("sub/b"), Value: []byte("2")},
+	}, got[:2])
+	assert.Equal(t, store.KV{Key: []byte("sub/a"), Value: nil}, got[2])
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not stop on context cancel")
+	}
+
+	assert.Len(t, received, 0, "only keys under the prefix should have been delivered")
+}
+
+func waitSubscribed(t *testing.T, s *Store, received chan store.KV) {
+	ctx := context.Background()
+	deadline := time.After(5 * time.Second)
+	for {
+		require.NoError(t, s.Put(ctx, []byte("sub/ready"), []byte("ready")))
+		require.NoError(t, s.FlushPuts(ctx))
+
+		select {
+		case <-received:
+			// Drains the extra markers that might still be delivered
+			require.NoError(t, s.BatchDelete(ctx, [][]byte{[]byte("sub/ready")}))
+			for {
+				select {
+				case kv := <-received:
+					if string(kv.Key) != "sub/ready" {
+						t.Fatalf("unexpected key %q", kv.Key)
+					}
+				case <-time.After(100 * time.Millisecond):
+					return
+				}
+			}
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("subscription never started")
+		}
+	}
+}
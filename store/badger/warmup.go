@@ -0,0 +1,81 @@
+package badger
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"go.uber.org/zap"
+)
+
+// startWarmup reads all the keys and values under `prefixes` (the whole database when there
+// is none) in the background, to fill badger's caches and the OS page cache so the first
+// queries after a start are not slowed down. It never blocks, the warm-up is stopped when the
+// store is closed.
+func (s *Store) startWarmup(prefixes [][]byte) {
+	if len(prefixes) == 0 {
+		prefixes = [][]byte{nil}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.warmupCancel = cancel
+	s.warmupDone = make(chan struct{})
+
+	go func() {
+		defer close(s.warmupDone)
+
+		start := time.Now()
+		count := 0
+
+		// Badger is used directly instead of `Prefix` so that the read transaction is fully
+		// completed once this goroutine ends, the store can then be closed safely
+		err := s.db.View(func(txn *badger.Txn) error {
+			for _, prefix := range prefixes {
+				if err := warmupPrefix(ctx, txn, prefix, &count); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			if ctx.Err() == nil {
+				s.warmupErr = err
+				s.logger.Warn("warm-up failed", zap.Error(err))
+			}
+			return
+		}
+
+		s.logger.Info("warm-up completed", zap.Int("prefix_count", len(prefixes)), zap.Int("key_count", count), zap.Duration("elapsed", time.Since(start)))
+	}()
+}
+
+func warmupPrefix(ctx context.Context, txn *badger.Txn, prefix []byte, count *int) error {
+	badgerOptions := badger.DefaultIteratorOptions
+	badgerOptions.Prefix = prefix
+
+	bit := txn.NewIterator(badgerOptions)
+	defer bit.Close()
+
+	for bit.Rewind(); bit.Valid(); bit.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Reading the value is what loads the blocks, the value itself is not needed
+		if err := bit.Item().Value(func([]byte) error { return nil }); err != nil {
+			return err
+		}
+		*count++
+	}
+	return nil
+}
+
+func (s *Store) stopWarmup() {
+	if s.warmupCancel == nil {
+		return
+	}
+
+	s.warmupCancel()
+	<-s.warmupDone
+}
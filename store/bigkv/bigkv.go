@@ -216,10 +216,65 @@ func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
 		return nil, store.ErrNotFound
 	}
 
-	return row[s.columnName][0].Value, nil
+	return rowValue(row[s.columnName][0].Value), nil
 }
 
-func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
+// ValueSize needs to fetch the row since Bigtable has no way to only get the size of a cell.
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	btOptions := bigtableReadOptions(store.Limit(store.Unlimited), nil)
+	row, err := s.table.ReadRow(ctx, string(s.withPrefix(key)), btOptions...)
+	if err != nil {
+		return 0, err
+	}
+	if len(row) == 0 {
+		return 0, store.ErrNotFound
+	}
+
+	return len(row[s.columnName][0].Value), nil
+}
+
+// Exists reads the row with its value stripped by Bigtable, so the value is never transferred.
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	btOptions := bigtableReadOptions(store.Limit(store.Unlimited), []store.ReadOption{store.KeyOnly()})
+	row, err := s.table.ReadRow(ctx, string(s.withPrefix(key)), btOptions...)
+	if err != nil {
+		return false, err
+	}
+
+	return len(row) != 0, nil
+}
+
+// BatchExists reads all the rows at once with their values stripped by Bigtable. Rows come
+// back in key order, not in the order of `keys`, so they are matched back by key.
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	if len(keys) == 0 {
+		return []bool{}, nil
+	}
+
+	btKeys := make([]string, len(keys))
+	for i, key := range keys {
+		btKeys[i] = string(s.withPrefix(key))
+	}
+
+	found := map[string]bool{}
+	btOptions := bigtableReadOptions(store.Limit(store.Unlimited), []store.ReadOption{store.KeyOnly()})
+	err = s.table.ReadRows(ctx, bigtable.RowList(btKeys), func(row bigtable.Row) bool {
+		found[row.Key()] = true
+		return true
+	}, btOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	exists = make([]bool, len(keys))
+	for i, btKey := range btKeys {
+		exists[i] = found[btKey]
+	}
+	return exists, nil
+}
+
+// BatchGet has the values stripped by Bigtable with `KeyOnly()`, they are never transferred.
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
 	if traceEnabled {
 		logging.Logger(ctx, zlog).Debug("batch get", zap.Int("key_count", len(keys)))
 	}
@@ -229,11 +284,16 @@ func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
 		btKeys[i] = string(key)
 	}
 
-	btOptions := bigtableReadOptions(store.Limit(store.Unlimited), nil)
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
+	btOptions := bigtableReadOptions(store.Limit(store.Unlimited), options)
 	kr := store.NewIterator(ctx)
 	go func() {
 		err := s.table.ReadRows(ctx, bigtable.RowList(btKeys), func(row bigtable.Row) bool {
-			return kr.PushItem(store.KV{Key: s.withoutPrefix([]byte(row.Key())), Value: row[s.columnName][0].Value})
+			return kr.PushItem(s.rowKV(row, readOptions.KeyOnly))
 		}, btOptions...)
 
 		if err != nil {
@@ -289,6 +349,16 @@ func (s *Store) BatchDelete(ctx context.Context, deletionKeys [][]byte) (err err
 	return nil
 }
 
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	if traceEnabled {
+		logging.Logger(ctx, zlog).Debug("delete", zap.Stringer("key", store.Key(key)))
+	}
+
+	mut := bigtable.NewMutation()
+	mut.DeleteRow()
+	return s.table.Apply(ctx, string(s.withPrefix(key)), mut)
+}
+
 func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
 	startKey := s.withPrefix(start)
 	endKey := s.withPrefix(exclusiveEnd)
@@ -304,12 +374,17 @@ func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int,
 		return sit
 	}
 
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
 	btOptions := bigtableReadOptions(store.Limit(limit), options)
 	rowRange := bigtable.NewRange(string(startKey), string(endKey))
 
 	go func() {
 		err := s.table.ReadRows(ctx, rowRange, func(row bigtable.Row) bool {
-			return sit.PushItem(store.KV{s.withoutPrefix([]byte(row.Key())), row[s.columnName][0].Value})
+			return sit.PushItem(s.rowKV(row, readOptions.KeyOnly))
 		}, btOptions...)
 
 		if err != nil {
@@ -328,12 +403,17 @@ func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ..
 	}
 
 	sit := store.NewIterator(ctx)
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
 	btOptions := bigtableReadOptions(store.Limit(limit), options)
 	prefix = s.withPrefix(prefix)
 
 	go func() {
 		err := s.table.ReadRows(ctx, bigtable.PrefixRange(string(prefix)), func(row bigtable.Row) bool {
-			return sit.PushItem(store.KV{s.withoutPrefix([]byte(row.Key())), row[s.columnName][0].Value})
+			return sit.PushItem(s.rowKV(row, readOptions.KeyOnly))
 		}, btOptions...)
 
 		if err != nil {
@@ -353,6 +433,11 @@ func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, o
 	}
 
 	sit := store.NewIterator(ctx)
+	readOptions := store.ReadOptions{}
+	for _, opt := range options {
+		opt.Apply(&readOptions)
+	}
+
 	btOptions := bigtableReadOptions(store.Limit(limit), options)
 	rowRanges := make([]bigtable.RowRange, len(prefixes))
 	for i, prefix := range prefixes {
@@ -361,7 +446,7 @@ func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, o
 
 	go func() {
 		err := s.table.ReadRows(ctx, bigtable.RowRangeList(rowRanges), func(row bigtable.Row) bool {
-			return sit.PushItem(store.KV{Key: s.withoutPrefix([]byte(row.Key())), Value: row[s.columnName][0].Value})
+			return sit.PushItem(s.rowKV(row, readOptions.KeyOnly))
 		}, btOptions...)
 
 		if err != nil {
@@ -375,6 +460,24 @@ func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, o
 	return sit
 }
 
+// rowKV returns the key and value of `row`, the value being left nil when `keyOnly` is set, as
+// it was stripped by Bigtable anyway.
+func (s *Store) rowKV(row bigtable.Row, keyOnly bool) store.KV {
+	kv := store.KV{Key: s.withoutPrefix([]byte(row.Key()))}
+	if !keyOnly {
+		kv.Value = rowValue(row[s.columnName][0].Value)
+	}
+	return kv
+}
+
+// rowValue ensures an empty stored value is returned as a zero-length, non-nil slice.
+func rowValue(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
+
 func (s *Store) withPrefix(key []byte) []byte {
 	if len(s.keyPrefix) == 0 {
 		return key
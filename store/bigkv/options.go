@@ -1,5 +1,13 @@
 package bigkv
 
+import "github.com/dfuse-io/kvdb/store"
+
 func (s *Store) EnableEmpty() {
 	zlog.Info("discarding possible empty value on store implementation, not required for this store")
 }
+
+func (s *Store) Capabilities() store.Capabilities {
+	return store.Capabilities{
+		EmptyValue: true,
+	}
+}
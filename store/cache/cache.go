@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+// Store wraps a backing store and keeps the values read through `Get` in a least recently
+// used cache bounded by memory, not by entry count: each entry accounts for the length of its
+// key and value, and the least recently used entries are evicted as soon as the total goes
+// over `max_bytes`. Values larger than `max_bytes` on their own are never cached. Values
+// returned from the cache are shared between callers, they must not be modified.
+//
+// Only `Get` is served from the cache, the other reads go straight to the backing store.
+// Writes made through the wrapper keep the cache consistent: a key put is not cached until
+// `FlushPuts` returns, and a key deleted is evicted. Writes made to the backing store from
+// elsewhere are not seen until their key is evicted.
+type Store struct {
+	dsn      string
+	backing  store.KVStore
+	maxBytes int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	// recency holds the `*entry` of `entries`, most recently used first
+	recency *list.List
+	size    int
+	// pendingPuts are the keys put since the last flush, never cached until they are flushed
+	pendingPuts map[string]struct{}
+	// generation changes on each invalidation, a value read from the backing store while it
+	// changed might be stale and is not cached
+	generation uint64
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("cache kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "cache",
+		Title:       "Cache",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports cache://?max_bytes=512MB&backing=<url escaped dsn>, `max_bytes` being a
+// number of bytes optionally followed by a `KB`, `MB` or `GB` (powers of 1024) unit.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("cache new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+	maxBytes, err := parseByteSize(query.Get("max_bytes"))
+	if err != nil {
+		return nil, fmt.Errorf("cache new: invalid max_bytes %q, expecting a positive size like 512MB", query.Get("max_bytes"))
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("cache new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("cache new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Int("max_bytes", maxBytes))
+
+	return &Store{
+		dsn:         dsnString,
+		backing:     backing,
+		maxBytes:    maxBytes,
+		entries:     make(map[string]*list.Element),
+		recency:     list.New(),
+		pendingPuts: make(map[string]struct{}),
+	}, nil
+}
+
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func parseByteSize(raw string) (int, error) {
+	multiplier := 1
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(strings.ToUpper(raw), unit.suffix) {
+			raw = raw[:len(raw)-len(unit.suffix)]
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("size must be positive")
+	}
+
+	return size * multiplier, nil
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	if err := s.backing.Put(ctx, key, value); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pendingPuts[string(key)] = struct{}{}
+	s.evict(string(key))
+	return nil
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	if err := s.backing.FlushPuts(ctx); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key := range s.pendingPuts {
+		s.evict(key)
+	}
+	s.pendingPuts = make(map[string]struct{})
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	s.lock.Lock()
+	if element, found := s.entries[string(key)]; found {
+		s.recency.MoveToFront(element)
+		value = element.Value.(*entry).value
+		s.lock.Unlock()
+		return value, nil
+	}
+	generation := s.generation
+	s.lock.Unlock()
+
+	value, err = s.backing.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, pending := s.pendingPuts[string(key)]; !pending && generation == s.generation {
+		s.add(string(key), value)
+	}
+	return value, nil
+}
+
+// add caches `value`, then evicts the least recently used entries until the cache fits in
+// `maxBytes` again. It must be called with the lock held.
+func (s *Store) add(key string, value []byte) {
+	size := len(key) + len(value)
+	if size > s.maxBytes {
+		return
+	}
+
+	if _, found := s.entries[key]; found {
+		return
+	}
+
+	s.entries[key] = s.recency.PushFront(&entry{key: key, value: value})
+	s.size += size
+
+	for s.size > s.maxBytes {
+		s.remove(s.recency.Back())
+	}
+}
+
+// evict removes `key` from the cache, if present, and invalidates the values being read from
+// the backing store. It must be called with the lock held.
+func (s *Store) evict(key string) {
+	s.generation++
+	if element, found := s.entries[key]; found {
+		s.remove(element)
+	}
+}
+
+func (s *Store) remove(element *list.Element) {
+	removed := s.recency.Remove(element).(*entry)
+	delete(s.entries, removed.key)
+	s.size -= len(removed.key) + len(removed.value)
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	return s.backing.ValueSize(ctx, key)
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	return s.backing.Exists(ctx, key)
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	return s.backing.BatchExists(ctx, keys)
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	return s.backing.BatchGet(ctx, keys, options...)
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	err = s.backing.BatchDelete(ctx, keys)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Evicted even on error, some of the keys might have been deleted
+	for _, key := range keys {
+		s.evict(string(key))
+	}
+	return err
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	err = s.backing.Delete(ctx, key)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.evict(string(key))
+	return err
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.backing.Scan(ctx, start, exclusiveEnd, limit, options...)
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.backing.Prefix(ctx, prefix, limit, options...)
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.backing.BatchPrefix(ctx, prefixes, limit, options...)
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "Cache", storetest.NewBadgerBackedFactory(t, "cache", "max_bytes=1MB"))
+}
+
+func newTestStore(t *testing.T, dsnQuery string) (*Store, storetest.DriverCleanupFunc) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "cache", dsnQuery)
+	return kvStore.(*Store), cleanup
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "cache", "max_bytes=1MB",
+		"",
+		"max_bytes=0",
+		"max_bytes=-1KB",
+		"max_bytes=lots",
+	)
+}
+
+func TestParseByteSize(t *testing.T) {
+	for raw, expected := range map[string]int{
+		"100":   100,
+		"100B":  100,
+		"2KB":   2048,
+		"512MB": 512 << 20,
+		"1gb":   1 << 30,
+	} {
+		size, err := parseByteSize(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, expected, size, raw)
+	}
+}
+
+func TestMaxBytes_Eviction(t *testing.T) {
+	kvStore, cleanup := newTestStore(t, "max_bytes=1KB")
+	defer cleanup()
+
+	ctx := context.Background()
+	sizes := map[string]int{"a": 100, "b": 500, "c": 300, "d": 50, "e": 600, "f": 2000}
+	for key, size := range sizes {
+		require.NoError(t, kvStore.Put(ctx, []byte(key), make([]byte, size)))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err := kvStore.Get(ctx, []byte(key))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 954, kvStore.size)
+	assert.Len(t, kvStore.entries, 4)
+
+	// Reading `a` makes `b` the least recently used, `b` and `c` must go to make room for `e`
+	_, err := kvStore.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	_, err = kvStore.Get(ctx, []byte("e"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 753, kvStore.size)
+	assert.ElementsMatch(t, []string{"a", "d", "e"}, cachedKeys(kvStore))
+
+	// Larger than the whole budget, never cached
+	value, err := kvStore.Get(ctx, []byte("f"))
+	require.NoError(t, err)
+	assert.Len(t, value, 2000)
+	assert.ElementsMatch(t, []string{"a", "d", "e"}, cachedKeys(kvStore))
+	assert.LessOrEqual(t, kvStore.size, 1024)
+}
+
+func TestWrites_KeepCacheConsistent(t *testing.T) {
+	kvStore, cleanup := newTestStore(t, "max_bytes=1KB")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.Put(ctx, []byte("b"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	assertGet(t, kvStore, "a", "1")
+	assertGet(t, kvStore, "b", "1")
+
+	// The previous value is read until the flush, without being cached
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("2")))
+	assertGet(t, kvStore, "a", "1")
+	assert.ElementsMatch(t, []string{"b"}, cachedKeys(kvStore))
+
+	require.NoError(t, kvStore.FlushPuts(ctx))
+	assertGet(t, kvStore, "a", "2")
+
+	require.NoError(t, kvStore.Delete(ctx, []byte("b")))
+	_, err := kvStore.Get(ctx, []byte("b"))
+	assert.Equal(t, store.ErrNotFound, err)
+	assert.ElementsMatch(t, []string{"a"}, cachedKeys(kvStore))
+}
+
+func assertGet(t *testing.T, kvStore *Store, key, expected string) {
+	t.Helper()
+
+	value, err := kvStore.Get(context.Background(), []byte(key))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(value))
+}
+
+func cachedKeys(kvStore *Store) (out []string) {
+	for key := range kvStore.entries {
+		out = append(out, key)
+	}
+	return
+}
@@ -0,0 +1,427 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Store wraps a backing store and stops sending it operations when too many of them fail,
+// to give it a chance to recover instead of hammering it.
+//
+// Outcomes of operations are counted over fixed windows of time. When, within a window, at
+// least `min_requests` operations completed and the ratio of failed ones reaches `threshold`,
+// the circuit opens: every operation then fails right away with `store.ErrCircuitOpen` for
+// the `cooldown` period. Once elapsed, the circuit is half-open, a single operation goes
+// through as a probe, closing the circuit when it succeeds and opening it again otherwise.
+//
+// Errors telling about the operation itself rather than the health of the backing store, like
+// `store.ErrNotFound` or `store.ErrForbidden`, are regular outcomes and are not counted as
+// failures, see `regularErrors`. Operations ended by their context being canceled or expired
+// tell nothing either way and are not counted at all, a half-open probe ended so lets the
+// next operation probe again. `FlushPuts` fails fast too while the circuit is
+// open, the puts it would have flushed stay pending in the backing store until a later flush.
+type Store struct {
+	dsn     string
+	backing store.KVStore
+
+	threshold   float64
+	minRequests int
+	window      time.Duration
+	cooldown    time.Duration
+
+	lock        sync.Mutex
+	state       state
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("circuit breaker kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "circuitbreaker",
+		Title:       "Circuit Breaker",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports circuitbreaker://?threshold=0.5&cooldown=30s&min_requests=10&window=10s&backing=<url escaped dsn>,
+// only `backing` is required, the other options default to the values above.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("circuitbreaker new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+
+	threshold := 0.5
+	if query.Get("threshold") != "" {
+		threshold, err = strconv.ParseFloat(query.Get("threshold"), 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return nil, fmt.Errorf("circuitbreaker new: invalid threshold %q, expecting a number in ]0, 1]", query.Get("threshold"))
+		}
+	}
+
+	minRequests := 10
+	if query.Get("min_requests") != "" {
+		minRequests, err = strconv.Atoi(query.Get("min_requests"))
+		if err != nil || minRequests <= 0 {
+			return nil, fmt.Errorf("circuitbreaker new: invalid min_requests %q, expecting a positive integer", query.Get("min_requests"))
+		}
+	}
+
+	window, err := parseDuration(query, "window", 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("circuitbreaker new: %w", err)
+	}
+
+	cooldown, err := parseDuration(query, "cooldown", 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("circuitbreaker new: %w", err)
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("circuitbreaker new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("circuitbreaker new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance",
+		zap.String("dsn", dsnString),
+		zap.Float64("threshold", threshold),
+		zap.Int("min_requests", minRequests),
+		zap.Duration("window", window),
+		zap.Duration("cooldown", cooldown),
+	)
+
+	return &Store{
+		dsn:         dsnString,
+		backing:     backing,
+		threshold:   threshold,
+		minRequests: minRequests,
+		window:      window,
+		cooldown:    cooldown,
+		windowStart: time.Now(),
+	}, nil
+}
+
+func parseDuration(query url.Values, name string, defaultValue time.Duration) (time.Duration, error) {
+	if query.Get(name) == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(query.Get(name))
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, expecting a positive duration", name, query.Get(name))
+	}
+	return duration, nil
+}
+
+// allow returns `store.ErrCircuitOpen` when the operation must not reach the backing store.
+// When nil is returned, the outcome of the operation must be reported through `record`.
+func (s *Store) allow() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch s.state {
+	case stateOpen:
+		if time.Since(s.openedAt) < s.cooldown {
+			return store.ErrCircuitOpen
+		}
+
+		zlog.Info("circuit half-open, probing backing store", zap.String("dsn", s.dsn))
+		s.state = stateHalfOpen
+		s.probing = true
+		return nil
+
+	case stateHalfOpen:
+		// A single probe at a time
+		if s.probing {
+			return store.ErrCircuitOpen
+		}
+		s.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+func (s *Store) record(err error) {
+	failed := isFailure(err)
+	abandoned := isAbandoned(err)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch s.state {
+	case stateOpen:
+		// An operation started before the circuit opened, nothing to learn from it
+		return
+
+	case stateHalfOpen:
+		if !s.probing {
+			return
+		}
+
+		s.probing = false
+		if abandoned {
+			return
+		}
+
+		if failed {
+			s.open(err)
+			return
+		}
+
+		zlog.Info("circuit closed, backing store recovered", zap.String("dsn", s.dsn))
+		s.state = stateClosed
+		s.resetWindow(time.Now())
+		return
+	}
+
+	if abandoned {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.window {
+		s.resetWindow(now)
+	}
+
+	s.requests++
+	if failed {
+		s.failures++
+	}
+
+	if s.requests >= s.minRequests && float64(s.failures)/float64(s.requests) >= s.threshold {
+		s.open(err)
+	}
+}
+
+func (s *Store) open(cause error) {
+	zlog.Warn("circuit open, failing fast until cooldown elapses",
+		zap.String("dsn", s.dsn),
+		zap.Int("requests", s.requests),
+		zap.Int("failures", s.failures),
+		zap.Duration("cooldown", s.cooldown),
+		zap.Error(cause),
+	)
+
+	s.state = stateOpen
+	s.openedAt = time.Now()
+}
+
+func (s *Store) resetWindow(now time.Time) {
+	s.windowStart = now
+	s.requests = 0
+	s.failures = 0
+}
+
+// regularErrors are the outcomes caused by the operation or its caller, not by the backing
+// store, those are not counted as failures
+var regularErrors = []error{
+	store.ErrNotFound,
+	store.ErrKeyExists,
+	store.ErrKeyTooLong,
+	store.ErrValueTooLarge,
+	store.ErrForbidden,
+	store.ErrReadOnly,
+	store.ErrRateLimited,
+}
+
+func isFailure(err error) bool {
+	if err == nil || isAbandoned(err) {
+		return false
+	}
+
+	for _, regular := range regularErrors {
+		if errors.Is(err, regular) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	if err := s.allow(); err != nil {
+		return err
+	}
+
+	err = s.backing.Put(ctx, key, value)
+	s.record(err)
+	return err
+}
+
+func (s *Store) FlushPuts(ctx context.Context) (err error) {
+	if err := s.allow(); err != nil {
+		return err
+	}
+
+	err = s.backing.FlushPuts(ctx)
+	s.record(err)
+	return err
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+
+	value, err = s.backing.Get(ctx, key)
+	s.record(err)
+	return value, err
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+
+	size, err = s.backing.ValueSize(ctx, key)
+	s.record(err)
+	return size, err
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	if err := s.allow(); err != nil {
+		return false, err
+	}
+
+	exists, err = s.backing.Exists(ctx, key)
+	s.record(err)
+	return exists, err
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+
+	exists, err = s.backing.BatchExists(ctx, keys)
+	s.record(err)
+	return exists, err
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	if err := s.allow(); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.recordIterator(ctx, s.backing.BatchGet(ctx, keys, options...))
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	if err := s.allow(); err != nil {
+		return err
+	}
+
+	err = s.backing.BatchDelete(ctx, keys)
+	s.record(err)
+	return err
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	if err := s.allow(); err != nil {
+		return err
+	}
+
+	err = s.backing.Delete(ctx, key)
+	s.record(err)
+	return err
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.allow(); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.recordIterator(ctx, s.backing.Scan(ctx, start, exclusiveEnd, limit, options...))
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.allow(); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.recordIterator(ctx, s.backing.Prefix(ctx, prefix, limit, options...))
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.allow(); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.recordIterator(ctx, s.backing.BatchPrefix(ctx, prefixes, limit, options...))
+}
+
+// recordIterator forwards the items of `it` and records its outcome as soon as it is known,
+// that is on its first item, which is a success, or when it completes without any. Recording
+// before forwarding the first item ends a half-open probe even when the consumer never reads
+// it, and an error occurring after some items were read is not counted.
+func (s *Store) recordIterator(ctx context.Context, it *store.Iterator) *store.Iterator {
+	kr := store.NewIterator(ctx)
+	go func() {
+		recorded := false
+		for it.Next() {
+			if !recorded {
+				s.record(nil)
+				recorded = true
+			}
+
+			if !kr.PushItem(it.Item()) {
+				return
+			}
+		}
+
+		err := it.Err()
+		if !recorded {
+			s.record(err)
+		}
+		if err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// isAbandoned tells whether the operation was ended by its context, canceled or expired,
+// before the backing store could tell anything about its health
+func isAbandoned(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func failedIterator(ctx context.Context, err error) *store.Iterator {
+	it := store.NewIterator(ctx)
+	it.PushError(err)
+	return it
+}
@@ -0,0 +1,224 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "CircuitBreaker", storetest.NewBadgerBackedFactory(t, "circuitbreaker", ""))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "circuitbreaker", "threshold=0.5",
+		"threshold=1.5",
+		"threshold=abc",
+		"cooldown=-1s",
+		"window=abc",
+		"min_requests=0",
+	)
+}
+
+var errBackendDown = errors.New("backend down")
+
+// flakyStore fails all reads while `failing` is set, with `err` or `errBackendDown` when nil,
+// counting the calls that reached it
+type flakyStore struct {
+	store.KVStore
+	failing int32
+	calls   int32
+	err     error
+}
+
+func (f *flakyStore) failure() error {
+	if f.err != nil {
+		return f.err
+	}
+	return errBackendDown
+}
+
+func (f *flakyStore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if atomic.LoadInt32(&f.failing) == 1 {
+		return nil, f.failure()
+	}
+	return f.KVStore.Get(ctx, key)
+}
+
+func (f *flakyStore) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	atomic.AddInt32(&f.calls, 1)
+	if atomic.LoadInt32(&f.failing) == 1 {
+		return failedIterator(ctx, f.failure())
+	}
+	return f.KVStore.Prefix(ctx, prefix, limit, options...)
+}
+
+func TestBreaker_OpensAndRecovers(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "circuitbreaker", "threshold=0.5&min_requests=4&cooldown=100ms")
+	defer cleanup()
+
+	s := kvStore.(*Store)
+	flaky := &flakyStore{KVStore: s.backing}
+	s.backing = flaky
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	// Not found is a regular outcome, it never opens the circuit
+	for i := 0; i < 10; i++ {
+		_, err := s.Get(ctx, []byte("missing"))
+		require.Equal(t, store.ErrNotFound, err)
+	}
+
+	// Backend fails, the circuit opens once enough failures are seen in the window
+	atomic.StoreInt32(&flaky.failing, 1)
+	s.resetWindow(time.Now())
+	for i := 0; i < 4; i++ {
+		_, err := s.Get(ctx, []byte("a"))
+		require.Equal(t, errBackendDown, err)
+	}
+
+	callsWhenOpened := atomic.LoadInt32(&flaky.calls)
+	_, err := s.Get(ctx, []byte("a"))
+	assert.Equal(t, store.ErrCircuitOpen, err)
+	assert.Equal(t, store.ErrCircuitOpen, readErr(s.Prefix(ctx, []byte("a"), store.Unlimited)))
+	assert.Equal(t, callsWhenOpened, atomic.LoadInt32(&flaky.calls), "backend should not be reached while the circuit is open")
+
+	// After the cooldown, the probe fails, so the circuit opens again
+	time.Sleep(150 * time.Millisecond)
+	_, err = s.Get(ctx, []byte("a"))
+	assert.Equal(t, errBackendDown, err)
+	_, err = s.Get(ctx, []byte("a"))
+	assert.Equal(t, store.ErrCircuitOpen, err)
+
+	// Backend heals, the next probe closes the circuit
+	atomic.StoreInt32(&flaky.failing, 0)
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, readErr(s.Prefix(ctx, []byte("a"), store.Unlimited)))
+
+	value, err := s.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+}
+
+func TestBreaker_RegularErrorsKeepCircuitClosed(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "circuitbreaker", "threshold=0.5&min_requests=4&cooldown=1h")
+	defer cleanup()
+
+	s := kvStore.(*Store)
+	flaky := &flakyStore{KVStore: s.backing}
+	s.backing = flaky
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	atomic.StoreInt32(&flaky.failing, 1)
+	for _, regular := range []error{store.ErrKeyExists, store.ErrRateLimited} {
+		flaky.err = fmt.Errorf("get: %w", regular)
+		for i := 0; i < 10; i++ {
+			_, err := s.Get(ctx, []byte("a"))
+			require.True(t, errors.Is(err, regular), "got %s", err)
+		}
+	}
+
+	atomic.StoreInt32(&flaky.failing, 0)
+	value, err := s.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+}
+
+func TestBreaker_ProbeEndsOnFirstItem(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "circuitbreaker", "threshold=0.5&min_requests=4&cooldown=100ms")
+	defer cleanup()
+
+	s := kvStore.(*Store)
+	flaky := &flakyStore{KVStore: s.backing}
+	s.backing = flaky
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, s.Put(ctx, []byte("ab"), []byte("2")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	atomic.StoreInt32(&flaky.failing, 1)
+	s.resetWindow(time.Now())
+	for i := 0; i < 4; i++ {
+		_, err := s.Get(ctx, []byte("a"))
+		require.Equal(t, errBackendDown, err)
+	}
+	assert.Equal(t, store.ErrCircuitOpen, s.FlushPuts(ctx))
+
+	// The probe is never read by its consumer, the circuit still closes on its first item
+	atomic.StoreInt32(&flaky.failing, 0)
+	time.Sleep(150 * time.Millisecond)
+	probeCtx, cancelProbe := context.WithCancel(ctx)
+	defer cancelProbe()
+	s.Prefix(probeCtx, []byte("a"), store.Unlimited)
+
+	assert.Eventually(t, func() bool {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.state == stateClosed
+	}, time.Second, 10*time.Millisecond)
+
+	value, err := s.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+	assert.NoError(t, s.FlushPuts(ctx))
+}
+
+func TestBreaker_CanceledProbeIsNeutral(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "circuitbreaker", "threshold=0.5&min_requests=4&cooldown=100ms")
+	defer cleanup()
+
+	s := kvStore.(*Store)
+	flaky := &flakyStore{KVStore: s.backing}
+	s.backing = flaky
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, s.FlushPuts(ctx))
+
+	atomic.StoreInt32(&flaky.failing, 1)
+	s.resetWindow(time.Now())
+	for i := 0; i < 4; i++ {
+		_, err := s.Get(ctx, []byte("a"))
+		require.Equal(t, errBackendDown, err)
+	}
+
+	// The probe is canceled by its caller, the circuit stays half-open without a probe running
+	time.Sleep(150 * time.Millisecond)
+	flaky.err = context.Canceled
+	_, err := s.Get(ctx, []byte("a"))
+	require.Equal(t, context.Canceled, err)
+
+	s.lock.Lock()
+	assert.Equal(t, stateHalfOpen, s.state)
+	assert.False(t, s.probing)
+	s.lock.Unlock()
+
+	// The next operation probes again, still failing, the circuit opens again
+	flaky.err = nil
+	_, err = s.Get(ctx, []byte("a"))
+	assert.Equal(t, errBackendDown, err)
+	_, err = s.Get(ctx, []byte("a"))
+	assert.Equal(t, store.ErrCircuitOpen, err)
+}
+
+func readErr(it *store.Iterator) error {
+	for it.Next() {
+	}
+	return it.Err()
+}
@@ -3,6 +3,8 @@ package store
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"sync/atomic"
 
 	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap/zapcore"
@@ -11,10 +13,41 @@ import (
 type Compressor interface {
 	Compress(in []byte) []byte
 	Decompress(in []byte) ([]byte, error)
+	// DecompressTo writes the decompressed form of `in` to `w` progressively, without ever
+	// holding the full decompressed value in memory.
+	DecompressTo(w io.Writer, in []byte) error
 
 	zapcore.ObjectMarshaler
 }
 
+// CompressionStatsReporter is implemented by compressors able to track the bytes going in and
+// out of `Compress`, the identity compressor does not since it never compresses.
+type CompressionStatsReporter interface {
+	// EnableStats turns on tracking of the cumulative bytes going in and out of `Compress`.
+	EnableStats()
+	// Stats returns the cumulative number of bytes received by and produced by `Compress`
+	// since stats were enabled. Both values are 0 when stats tracking is not enabled.
+	Stats() (inBytes, outBytes uint64)
+}
+
+// NamedCompressor is implemented by compressors telling the name of their compression
+// algorithm, use `CompressorName` to get the name of any compressor.
+type NamedCompressor interface {
+	// Name returns the name of the compression algorithm in use, `none` for the identity compressor.
+	Name() string
+}
+
+// CompressorName returns the name of the compression algorithm of `compressor`, `unknown` when
+// it does not implement `NamedCompressor`.
+func CompressorName(compressor Compressor) string {
+	if named, ok := compressor.(NamedCompressor); ok {
+		return named.Name()
+	}
+	return "unknown"
+}
+
+// NewCompressor returns the compressor for the given mode, the empty string and `none` both
+// returns the identity compressor (see `NoOpCompressor`) which leaves values untouched.
 func NewCompressor(mode string, thresholdInBytes int) (Compressor, error) {
 	switch mode {
 	case "zst", "zstd":
@@ -26,12 +59,42 @@ func NewCompressor(mode string, thresholdInBytes int) (Compressor, error) {
 	}
 }
 
+// compressionStats accumulates the bytes seen by a compressor, it must be the first field of
+// the struct embedding it so that the atomic counters are 64-bit aligned on 32-bit platforms.
+type compressionStats struct {
+	inBytes  uint64
+	outBytes uint64
+	enabled  int32
+}
+
+func (s *compressionStats) EnableStats() {
+	atomic.StoreInt32(&s.enabled, 1)
+}
+
+func (s *compressionStats) Stats() (inBytes, outBytes uint64) {
+	return atomic.LoadUint64(&s.inBytes), atomic.LoadUint64(&s.outBytes)
+}
+
+func (s *compressionStats) record(in, out []byte) {
+	if atomic.LoadInt32(&s.enabled) == 0 {
+		return
+	}
+
+	atomic.AddUint64(&s.inBytes, uint64(len(in)))
+	atomic.AddUint64(&s.outBytes, uint64(len(out)))
+}
+
+// NoOpCompressor is the identity compressor, values are returned as-is without any copy.
 type NoOpCompressor struct{}
 
 func NewNoOpCompressor() *NoOpCompressor {
 	return &NoOpCompressor{}
 }
 
+func (NoOpCompressor) Name() string {
+	return "none"
+}
+
 func (NoOpCompressor) Compress(in []byte) []byte {
 	return in
 }
@@ -39,12 +102,19 @@ func (NoOpCompressor) Decompress(in []byte) ([]byte, error) {
 	return in, nil
 }
 
-func (NoOpCompressor) MarshalLogObject(enc zapcore.ObjectEncoder) error {
-	enc.AddString("compression", "none")
+func (NoOpCompressor) DecompressTo(w io.Writer, in []byte) error {
+	_, err := w.Write(in)
+	return err
+}
+
+func (c NoOpCompressor) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("compression", c.Name())
 	return nil
 }
 
 type ZstdCompressor struct {
+	compressionStats
+
 	enc              *zstd.Encoder
 	dec              *zstd.Decoder
 	thresholdInBytes int
@@ -62,12 +132,18 @@ func NewZstdCompressor(thresholdInBytes int) *ZstdCompressor {
 	}
 }
 
+func (*ZstdCompressor) Name() string {
+	return "zstd"
+}
+
 func (c *ZstdCompressor) Compress(in []byte) (out []byte) {
+	out = in
 	if len(in) > c.thresholdInBytes {
-		return c.enc.EncodeAll(in, out)
+		out = c.enc.EncodeAll(in, nil)
 	}
 
-	return in
+	c.record(in, out)
+	return out
 }
 
 var zstdMagicBytes = []byte{0x28, 0xB5, 0x2F, 0xFD}
@@ -86,8 +162,26 @@ func (c *ZstdCompressor) Decompress(in []byte) ([]byte, error) {
 	return in, nil
 }
 
+func (c *ZstdCompressor) DecompressTo(w io.Writer, in []byte) error {
+	if !bytes.HasPrefix(in, zstdMagicBytes) {
+		_, err := w.Write(in)
+		return err
+	}
+
+	// The shared decoder cannot be used as a stream decoder concurrently, a dedicated one is
+	// needed for each stream
+	dec, err := zstd.NewReader(bytes.NewReader(in), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
+
+	_, err = io.Copy(w, dec)
+	return err
+}
+
 func (c *ZstdCompressor) MarshalLogObject(enc zapcore.ObjectEncoder) error {
-	enc.AddString("compression", "zstd")
+	enc.AddString("compression", c.Name())
 	enc.AddInt("compression_size_threshold", c.thresholdInBytes)
 	return nil
 }
@@ -0,0 +1,295 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+// Store wraps a backing store and compresses values before they reach it, the compression
+// being chosen from the key, so that families of keys holding values that compress well can
+// be compressed while those holding tiny or incompressible values are left untouched.
+//
+// Each `rule` maps a key prefix to a compression mode (`zstd` or `none`), the longest matching
+// prefix wins, and keys matching no rule use the `default` mode. Values no larger than
+// `threshold` bytes are never compressed.
+//
+// Each value is stored behind a header byte telling whether it is compressed, reads rely on it
+// alone, so rules can be changed at any time: values already written stay readable, and new
+// writes follow the new rules. `ValueSize` reports the size as stored, without the header. The
+// header also makes empty values possible whatever the backing store. Counters of `Increment`
+// cannot carry the header, it is not supported.
+type Store struct {
+	dsn     string
+	backing store.KVStore
+
+	// rules are sorted by descending prefix length, so the first match is the longest one
+	rules       []rule
+	defaultZstd bool
+	threshold   int
+	zstd        *store.ZstdCompressor
+}
+
+type rule struct {
+	prefix []byte
+	zstd   bool
+}
+
+// The header byte stored in front of each value
+const (
+	headerNone byte = 0x00
+	headerZstd byte = 0x01
+)
+
+func (s *Store) String() string {
+	return fmt.Sprintf("compression kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "compression",
+		Title:       "Compression",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports compression://?rule=<hex prefix>:<mode>&rule=<hex prefix>:<mode>&default=<mode>&threshold=<bytes>&backing=<url escaped dsn>,
+// where `mode` is `zstd` or `none`. Only `backing` is required, `default` is `none` and
+// `threshold` is 512 bytes when not given, below that, compression gains little and can even
+// make values bigger.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("compression new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+
+	threshold := 512
+	if query.Get("threshold") != "" {
+		threshold, err = strconv.Atoi(query.Get("threshold"))
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("compression new: invalid threshold %q, expecting a positive integer", query.Get("threshold"))
+		}
+	}
+
+	defaultZstd, err := parseMode(query.Get("default"))
+	if err != nil {
+		return nil, fmt.Errorf("compression new: invalid default: %w", err)
+	}
+
+	rules, err := parseRules(query["rule"])
+	if err != nil {
+		return nil, fmt.Errorf("compression new: %w", err)
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("compression new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("compression new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance",
+		zap.String("dsn", dsnString),
+		zap.Int("rule_count", len(rules)),
+		zap.Bool("default_zstd", defaultZstd),
+		zap.Int("threshold", threshold),
+	)
+
+	return &Store{
+		dsn:         dsnString,
+		backing:     backing,
+		rules:       rules,
+		defaultZstd: defaultZstd,
+		threshold:   threshold,
+		// The threshold is applied by `compress`, which must know whether a value got compressed
+		zstd: store.NewZstdCompressor(0),
+	}, nil
+}
+
+// parseMode returns whether `mode` compresses with zstd, it only accepts the modes a rule can
+// use, the aliases of `store.NewCompressor` are not needed here
+func parseMode(mode string) (zstd bool, err error) {
+	switch mode {
+	case "", "none":
+		return false, nil
+	case "zstd":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown mode %q, expecting zstd or none", mode)
+	}
+}
+
+func parseRules(values []string) ([]rule, error) {
+	seen := map[string]bool{}
+	rules := make([]rule, len(values))
+	for i, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule %q, expecting <hex prefix>:<mode>", value)
+		}
+
+		prefix, err := hex.DecodeString(parts[0])
+		if err != nil || len(prefix) == 0 {
+			return nil, fmt.Errorf("invalid rule %q, expecting a non-empty hexadecimal prefix", value)
+		}
+
+		if seen[string(prefix)] {
+			return nil, fmt.Errorf("invalid rule %q, prefix %x has multiple rules", value, prefix)
+		}
+		seen[string(prefix)] = true
+
+		zstd, err := parseMode(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", value, err)
+		}
+
+		rules[i] = rule{prefix: prefix, zstd: zstd}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].prefix) > len(rules[j].prefix) })
+	return rules, nil
+}
+
+func (s *Store) compress(key, value []byte) []byte {
+	zstd := s.defaultZstd
+	for _, rule := range s.rules {
+		if bytes.HasPrefix(key, rule.prefix) {
+			zstd = rule.zstd
+			break
+		}
+	}
+
+	if zstd && len(value) > s.threshold {
+		return append([]byte{headerZstd}, s.zstd.Compress(value)...)
+	}
+	return append([]byte{headerNone}, value...)
+}
+
+// decompress strips the header of `value`, decompressing it when needed. Empty values are
+// those of key-only items, they are returned as-is.
+func (s *Store) decompress(key, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	switch value[0] {
+	case headerNone:
+		return value[1:], nil
+	case headerZstd:
+		decompressed, err := s.zstd.Decompress(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("decompress value of key %s: %w", store.Key(key), err)
+		}
+		return decompressed, nil
+	default:
+		return nil, fmt.Errorf("decompress value of key %s: unknown header %#x", store.Key(key), value[0])
+	}
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	return s.backing.Put(ctx, key, s.compress(key, value))
+}
+
+// Insert compresses the value like `Put` does, the backing store checking the key existence.
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	return store.Insert(ctx, s.backing, key, s.compress(key, value))
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	return s.backing.FlushPuts(ctx)
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	value, err = s.backing.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decompress(key, value)
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	size, err = s.backing.ValueSize(ctx, key)
+	if err != nil || size == 0 {
+		return size, err
+	}
+	return size - 1, nil
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	return s.backing.Exists(ctx, key)
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	return s.backing.BatchExists(ctx, keys)
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	return s.decompressIterator(ctx, s.backing.BatchGet(ctx, keys, options...))
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	return s.backing.BatchDelete(ctx, keys)
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	return s.backing.Delete(ctx, key)
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.decompressIterator(ctx, s.backing.Scan(ctx, start, exclusiveEnd, limit, options...))
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.decompressIterator(ctx, s.backing.Prefix(ctx, prefix, limit, options...))
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	return s.decompressIterator(ctx, s.backing.BatchPrefix(ctx, prefixes, limit, options...))
+}
+
+// decompressIterator forwards the items of `it` with their values decompressed, key-only
+// items keep their `nil` value.
+func (s *Store) decompressIterator(ctx context.Context, it *store.Iterator) *store.Iterator {
+	kr := store.NewIterator(ctx)
+	go func() {
+		for it.Next() {
+			item := it.Item()
+			value, err := s.decompress(item.Key, item.Value)
+			if err != nil {
+				kr.PushError(err)
+				return
+			}
+
+			if !kr.PushItem(store.KV{Key: item.Key, Value: value}) {
+				return
+			}
+		}
+
+		if err := it.Err(); err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
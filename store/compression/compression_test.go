@@ -0,0 +1,117 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var zstdMagicBytes = []byte{0x28, 0xB5, 0x2F, 0xFD}
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "Compression", storetest.NewBadgerBackedFactory(t, "compression", "default=zstd&rule=6d:none"))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "compression", "default=zstd",
+		"default=snappy",
+		"threshold=-1",
+		"rule=zz:zstd",
+		"rule=62",
+		"rule=:zstd",
+		"rule=62:zstd&rule=62:none",
+	)
+}
+
+func TestRules(t *testing.T) {
+	// Blocks ("b") are compressed, except the markers nested under them ("bm"), the rest isn't
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "compression", "rule=62:zstd&rule=626d:none&threshold=16")
+	defer cleanup()
+
+	s := kvStore.(*Store)
+	ctx := context.Background()
+	block := bytes.Repeat([]byte("block row "), 20)
+	entries := []store.KV{
+		{Key: []byte("b1"), Value: block},
+		{Key: []byte("b2"), Value: []byte("tiny")},
+		{Key: []byte("bm1"), Value: block},
+		{Key: []byte("m1"), Value: block},
+	}
+	for _, kv := range entries {
+		require.NoError(t, s.Put(ctx, kv.Key, kv.Value))
+	}
+	require.NoError(t, s.FlushPuts(ctx))
+
+	// Only the large block row is stored compressed, the others are stored as-is behind the header
+	for _, kv := range entries {
+		raw, err := s.backing.Get(ctx, kv.Key)
+		require.NoError(t, err)
+		if string(kv.Key) == "b1" {
+			assert.Equal(t, headerZstd, raw[0])
+			assert.True(t, bytes.HasPrefix(raw[1:], zstdMagicBytes), "key %s", kv.Key)
+		} else {
+			assert.Equal(t, append([]byte{headerNone}, kv.Value...), raw, "key %s", kv.Key)
+		}
+	}
+
+	size, err := s.ValueSize(ctx, []byte("b1"))
+	require.NoError(t, err)
+	assert.True(t, size < len(block), "stored size %d should be smaller than %d", size, len(block))
+
+	// Reads are transparent
+	for _, kv := range entries {
+		value, err := s.Get(ctx, kv.Key)
+		require.NoError(t, err)
+		assert.Equal(t, kv.Value, value)
+	}
+
+	var got []store.KV
+	it := s.Prefix(ctx, []byte("b"), store.Unlimited)
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, entries[:3], got)
+}
+
+func TestRules_Changed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-compression")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	backing := url.QueryEscape("badger://" + path.Join(dir, "test.db"))
+	block := bytes.Repeat([]byte("block row "), 20)
+	// Raw values looking like zstd frames must not be mistaken for compressed ones
+	lookalike := append(append([]byte{}, zstdMagicBytes...), "not compressed"...)
+
+	ctx := context.Background()
+	writer, err := store.New("compression://?default=zstd&threshold=16&backing=" + backing)
+	require.NoError(t, err)
+	require.NoError(t, writer.Put(ctx, []byte("b1"), block))
+	require.NoError(t, writer.FlushPuts(ctx))
+	require.NoError(t, writer.Close())
+
+	// Values written under the previous rules stay readable
+	reader, err := store.New("compression://?default=none&backing=" + backing)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	require.NoError(t, reader.Put(ctx, []byte("b2"), lookalike))
+	require.NoError(t, reader.FlushPuts(ctx))
+
+	for key, expected := range map[string][]byte{"b1": block, "b2": lookalike} {
+		value, err := reader.Get(ctx, []byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, "key %s", key)
+	}
+}
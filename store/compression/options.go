@@ -0,0 +1,27 @@
+package compression
+
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) EnableEmpty() {
+	if enabler, ok := s.backing.(store.EmtpyValueEnabler); ok {
+		enabler.EnableEmpty()
+	}
+}
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	if setter, ok := s.backing.(store.LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+// Capabilities keeps `Insert` of the backing store, forwarded with compressed values, its other
+// optional interfaces would hand out or take values as stored and are not exposed. Empty values
+// are always supported, the backing store never receiving one because of the header.
+func (s *Store) Capabilities() store.Capabilities {
+	capabilities := s.backing.Capabilities().Intersect(store.Capabilities{Insert: true, ReadOnly: true})
+	capabilities.EmptyValue = true
+	return capabilities
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZstdCompressor_Stats(t *testing.T) {
+	compressor := NewZstdCompressor(16)
+	compressor.EnableStats()
+
+	payloads := [][]byte{
+		bytes.Repeat([]byte("a"), 4096),
+		bytes.Repeat([]byte("abcd"), 1024),
+		[]byte("small"),
+	}
+
+	expectedIn, expectedOut := uint64(0), uint64(0)
+	for _, payload := range payloads {
+		out := compressor.Compress(payload)
+		expectedIn += uint64(len(payload))
+		expectedOut += uint64(len(out))
+	}
+
+	inBytes, outBytes := compressor.Stats()
+	assert.Equal(t, expectedIn, inBytes)
+	assert.Equal(t, expectedOut, outBytes)
+	assert.Less(t, outBytes, inBytes)
+
+	stats := Stats{CompressionInBytes: inBytes, CompressionOutBytes: outBytes}
+	assert.InDelta(t, float64(expectedOut)/float64(expectedIn), stats.CompressionRatio(), 0.0001)
+}
+
+func TestCompressor_StatsDisabled(t *testing.T) {
+	compressor := NewZstdCompressor(0)
+	compressor.Compress(bytes.Repeat([]byte("a"), 4096))
+
+	inBytes, outBytes := compressor.Stats()
+	assert.Equal(t, uint64(0), inBytes)
+	assert.Equal(t, uint64(0), outBytes)
+	assert.Equal(t, 1.0, Stats{}.CompressionRatio())
+}
+
+func TestCompressor_StatsConcurrent(t *testing.T) {
+	compressor := NewZstdCompressor(1024)
+	compressor.EnableStats()
+
+	payload := []byte("0123456789")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				compressor.Compress(payload)
+			}
+		}()
+	}
+	wg.Wait()
+
+	inBytes, outBytes := compressor.Stats()
+	require.Equal(t, uint64(10*100*len(payload)), inBytes)
+	assert.Equal(t, inBytes, outBytes)
+}
+
+func TestNewCompressor_Identity(t *testing.T) {
+	for _, mode := range []string{"", "none"} {
+		t.Run(mode, func(t *testing.T) {
+			compressor, err := NewCompressor(mode, 0)
+			require.NoError(t, err)
+			assert.Equal(t, "none", CompressorName(compressor))
+
+			in := []byte("this value must pass through the identity compressor unchanged")
+			compressed := compressor.Compress(in)
+			assert.Equal(t, in, compressed)
+			assert.True(t, &in[0] == &compressed[0], "expected no copy of the input")
+
+			decompressed, err := compressor.Decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, in, decompressed)
+			assert.True(t, &in[0] == &decompressed[0], "expected no copy of the input")
+
+			allocs := testing.AllocsPerRun(100, func() {
+				out, _ := compressor.Decompress(compressor.Compress(in))
+				_ = out
+			})
+			assert.Equal(t, 0.0, allocs)
+		})
+	}
+}
+
+func TestNoOpCompressor_NoStats(t *testing.T) {
+	var compressor Compressor = NoOpCompressor{}
+	_, ok := compressor.(CompressionStatsReporter)
+	assert.False(t, ok, "the identity compressor never compresses, it must not report stats")
+}
+
+func TestNewCompressor_Zstd(t *testing.T) {
+	compressor, err := NewCompressor("zstd", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", CompressorName(compressor))
+}
+
+func TestCompressor_EmptyValue(t *testing.T) {
+	for _, compressor := range []Compressor{NewNoOpCompressor(), NewZstdCompressor(0)} {
+		t.Run(CompressorName(compressor), func(t *testing.T) {
+			for _, in := range [][]byte{nil, {}} {
+				out, err := compressor.Decompress(compressor.Compress(in))
+				require.NoError(t, err)
+				assert.Len(t, out, 0)
+			}
+		})
+	}
+}
+
+func TestCompressor_DecompressTo(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	zstdCompressor := NewZstdCompressor(16)
+	compressed := zstdCompressor.Compress(payload)
+	require.True(t, len(compressed) < len(payload))
+
+	tests := []struct {
+		name       string
+		compressor Compressor
+		in         []byte
+	}{
+		{"zstd, compressed", zstdCompressor, compressed},
+		{"zstd, uncompressed", zstdCompressor, payload},
+		{"none", NewNoOpCompressor(), payload},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := bytes.NewBuffer(nil)
+			require.NoError(t, test.compressor.DecompressTo(buffer, test.in))
+			assert.Equal(t, payload, buffer.Bytes())
+		})
+	}
+}
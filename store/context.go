@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIDKeyType int
+
+// requestIDKey is the well-known context key under which the request ID is stored, it's
+// unexported so the only way to set it is through `WithRequestID`.
+const requestIDKey requestIDKeyType = 0
+
+// WithRequestID returns a copy of `ctx` carrying the given request ID. Callers usually
+// use something tying the low-level store operations to their high-level unit of work,
+// like the block ID being processed. Store implementations add it to their log lines.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by `ctx`, if any.
+func RequestID(ctx context.Context) (id string, found bool) {
+	id, found = ctx.Value(requestIDKey).(string)
+	return
+}
+
+// RequestIDField returns a `request_id` zap field for the request ID carried by `ctx`, or
+// a no-op field when the context has none.
+func RequestIDField(ctx context.Context) zap.Field {
+	if id, found := RequestID(ctx); found {
+		return zap.String("request_id", id)
+	}
+
+	return zap.Skip()
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeyDistribution counts the keys starting with `prefix` (all keys when empty), bucketed by
+// their first `depth` bytes following `prefix`, to see how a keyspace is distributed and spot
+// its hotspots. Buckets are keyed by the hex encoding of those bytes, keys having fewer than
+// `depth` bytes after `prefix` being counted in the bucket of the bytes they have.
+//
+// It is meant as a diagnostic tool: the counts are exact, but every key under `prefix` is read
+// through a key-only scan, which takes a while on large keyspaces, and the store can change
+// while it runs.
+func KeyDistribution(ctx context.Context, kv KVStore, prefix []byte, depth int) (map[string]uint64, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("key distribution: depth must be at least 1, got %d", depth)
+	}
+
+	counts := make(map[string]uint64)
+	it := kv.Prefix(ctx, prefix, Unlimited, KeyOnly())
+	for it.Next() {
+		bucket := it.Item().Key[len(prefix):]
+		if len(bucket) > depth {
+			bucket = bucket[:depth]
+		}
+		counts[Key(bucket).String()]++
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("key distribution: read %s keys: %w", Key(prefix), err)
+	}
+
+	return counts, nil
+}
@@ -1,7 +1,43 @@
 package store
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound      = errors.New("not found")
+	ErrKeyExists     = errors.New("key exists")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrCircuitOpen   = errors.New("circuit open")
+	ErrKeyTooLong    = errors.New("key too long")
+	ErrReadOnly      = errors.New("store is read-only")
+	ErrValueTooLarge = errors.New("value too large")
+	ErrForbidden     = errors.New("forbidden")
 )
+
+// KeyError is returned by stores when an operation on a given key fails, it carries the
+// operation and the key along with the cause, which `errors.Is` and `errors.As` unwrap.
+type KeyError struct {
+	Op  string
+	Key Key
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s key %s: %s", e.Op, e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// WrapKeyError wraps `err` in a `KeyError`. A nil `err` gives nil and `ErrNotFound` is
+// returned as is, not finding a key is an expected outcome that callers compare directly.
+func WrapKeyError(op string, key []byte, err error) error {
+	if err == nil || err == ErrNotFound {
+		return err
+	}
+
+	return &KeyError{Op: op, Key: key, Err: err}
+}
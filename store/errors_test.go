@@ -0,0 +1,25 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapKeyError(t *testing.T) {
+	assert.NoError(t, WrapKeyError("get", []byte{0x01}, nil))
+	assert.Equal(t, ErrNotFound, WrapKeyError("get", []byte{0x01}, ErrNotFound))
+
+	cause := errors.New("disk on fire")
+	err := WrapKeyError("put", []byte{0xab, 0xcd}, fmt.Errorf("set entry: %w", cause))
+	assert.Equal(t, "put key abcd: set entry: disk on fire", err.Error())
+	assert.True(t, errors.Is(err, cause))
+
+	var keyErr *KeyError
+	require.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "put", keyErr.Op)
+	assert.Equal(t, Key{0xab, 0xcd}, keyErr.Key)
+}
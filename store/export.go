@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// importFlushBytes is the amount of keys and values put by `Import` between two flushes, so
+// that stores buffering puts until `FlushPuts` do not accumulate a whole stream in memory.
+const importFlushBytes = 16 * 1024 * 1024
+
+// maxExportFieldLen bounds the length of a key or value read by `Import`, protecting against
+// huge allocations when fed a corrupted or foreign stream.
+const maxExportFieldLen = 1 << 30
+
+// Export writes all the keys starting with `prefix` (all keys when empty) along with their
+// value to `w`, in key order. Each entry is framed as the uvarint length of the key, the key,
+// the uvarint length of the value and the value, entries following each other until the end
+// of the stream. The format does not depend on the store, `Import` can load it in any of them.
+func Export(ctx context.Context, kv KVStore, w io.Writer, prefix []byte) error {
+	// Stops the scan when returning early on a write error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	bw := bufio.NewWriter(w)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	writeField := func(field []byte) error {
+		n := binary.PutUvarint(lenBuf, uint64(len(field)))
+		if _, err := bw.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		_, err := bw.Write(field)
+		return err
+	}
+
+	it := kv.Prefix(ctx, prefix, Unlimited)
+	for it.Next() {
+		item := it.Item()
+		if err := writeField(item.Key); err != nil {
+			return fmt.Errorf("export: write key %s: %w", Key(item.Key), err)
+		}
+		if err := writeField(item.Value); err != nil {
+			return fmt.Errorf("export: write value of %s: %w", Key(item.Key), err)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("export: read %s keys: %w", Key(prefix), err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	return nil
+}
+
+// Import puts in `kv` all the entries read from `r`, in the format written by `Export`, until
+// the end of the stream. Puts are flushed regularly and once all entries are read, existing
+// keys are overwritten. A stream truncated in the middle of an entry fails with
+// `io.ErrUnexpectedEOF`, the entries read until then being flushed nonetheless.
+func Import(ctx context.Context, kv KVStore, r io.Reader) (err error) {
+	br := bufio.NewReader(r)
+	pendingBytes := 0
+	defer func() {
+		if flushErr := kv.FlushPuts(ctx); flushErr != nil && err == nil {
+			err = fmt.Errorf("import: flush: %w", flushErr)
+		}
+	}()
+
+	for {
+		key, err := readExportField(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("import: read key: %w", err)
+		}
+
+		value, err := readExportField(br)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("import: read value of %s: %w", Key(key), err)
+		}
+
+		if err := kv.Put(ctx, key, value); err != nil {
+			return fmt.Errorf("import: put: %w", err)
+		}
+
+		pendingBytes += len(key) + len(value)
+		if pendingBytes >= importFlushBytes {
+			if err := kv.FlushPuts(ctx); err != nil {
+				return fmt.Errorf("import: flush: %w", err)
+			}
+			pendingBytes = 0
+		}
+	}
+}
+
+// readExportField reads a length prefixed field, returning `io.EOF` only when the stream ends
+// right before the field.
+func readExportField(br *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if length > maxExportFieldLen {
+		return nil, fmt.Errorf("field of %d bytes exceeds maximum of %d", length, maxExportFieldLen)
+	}
+
+	field := make([]byte, length)
+	if _, err := io.ReadFull(br, field); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return field, nil
+}
@@ -0,0 +1,85 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	_ "github.com/dfuse-io/kvdb/store/shortkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source, err := store.New(fmt.Sprintf("badger://%s?compression=zstd", path.Join(dir, "source.db")))
+	require.NoError(t, err)
+	defer source.Close()
+
+	// The destination stores its keys differently, the stream does not depend on it
+	destination, err := store.New("shortkey://?prefix=626c6b3a&backing=" + url.QueryEscape(fmt.Sprintf("badger://%s", path.Join(dir, "destination.db"))))
+	require.NoError(t, err)
+	defer destination.Close()
+
+	ctx := context.Background()
+	expected := []store.KV{
+		{Key: []byte("blk:00000001"), Value: bytes.Repeat([]byte("a"), 2000)},
+		{Key: []byte("blk:00000002"), Value: []byte{0x00}},
+		{Key: []byte("blk:00000003"), Value: []byte("three")},
+	}
+	for _, kv := range expected {
+		require.NoError(t, source.Put(ctx, kv.Key, kv.Value))
+	}
+	require.NoError(t, source.Put(ctx, []byte("trx:01"), []byte("not exported")))
+	require.NoError(t, source.FlushPuts(ctx))
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, store.Export(ctx, source, buffer, []byte("blk:")))
+	require.NoError(t, store.Import(ctx, destination, buffer))
+
+	var imported []store.KV
+	it := destination.Prefix(ctx, nil, store.Unlimited)
+	for it.Next() {
+		imported = append(imported, it.Item())
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, expected, imported)
+}
+
+func TestImport_Truncated(t *testing.T) {
+	driver := &memoryDriver{values: map[string][]byte{}}
+
+	// Key "a" with value "bc" then key "d" without its value
+	err := store.Import(context.Background(), driver, bytes.NewReader([]byte{0x01, 'a', 0x02, 'b', 'c', 0x01, 'd'}))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "got %v", err)
+	assert.Equal(t, map[string][]byte{"a": []byte("bc")}, driver.values)
+	assert.Equal(t, 1, driver.flushCount)
+}
+
+type memoryDriver struct {
+	store.TestKVDBDriver
+	values     map[string][]byte
+	flushCount int
+}
+
+func (d *memoryDriver) Put(ctx context.Context, key, value []byte) error {
+	d.values[string(key)] = value
+	return nil
+}
+
+func (d *memoryDriver) FlushPuts(ctx context.Context) error {
+	d.flushCount++
+	return nil
+}
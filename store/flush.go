@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FlushPutsWithRetry calls `kv.FlushPuts` up to `attempts` times, waiting `backoff` before
+// the first retry and doubling it before each following one, until a flush succeeds. It gives
+// up early when `ctx` is done, returning the last flush error.
+//
+// Retrying relies on the pending puts being kept by the store when a flush fails, which is the
+// case of netkv. Badger cannot flush the puts of a failed flush again, its failures stick and
+// retrying them fails the same way.
+func FlushPutsWithRetry(ctx context.Context, kv KVStore, attempts int, backoff time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = kv.FlushPuts(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= attempts {
+			break
+		}
+
+		zlog.Debug("flush failed, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("flush puts, giving up after %d attempts: %w", attempt, err)
+		}
+		backoff *= 2
+	}
+
+	return fmt.Errorf("flush puts, giving up after %d attempts: %w", attempts, err)
+}
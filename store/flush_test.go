@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFlakyFlushDriver struct {
+	TestKVDBDriver
+	failures int
+	calls    int
+}
+
+func (d *testFlakyFlushDriver) FlushPuts(ctx context.Context) error {
+	d.calls++
+	if d.calls <= d.failures {
+		return errors.New("transient failure")
+	}
+	return nil
+}
+
+func TestFlushPutsWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	driver := &testFlakyFlushDriver{failures: 2}
+	assert.NoError(t, FlushPutsWithRetry(ctx, driver, 3, time.Millisecond))
+	assert.Equal(t, 3, driver.calls)
+
+	driver = &testFlakyFlushDriver{failures: 3}
+	err := FlushPutsWithRetry(ctx, driver, 3, time.Millisecond)
+	assert.EqualError(t, err, "flush puts, giving up after 3 attempts: transient failure")
+	assert.Equal(t, 3, driver.calls)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	driver = &testFlakyFlushDriver{failures: 3}
+	assert.Error(t, FlushPutsWithRetry(canceled, driver, 3, time.Hour))
+	assert.Equal(t, 1, driver.calls)
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"io"
+	"time"
 )
 
 type Purgeable interface {
@@ -12,13 +14,19 @@ type Purgeable interface {
 type KVStore interface {
 	// Put writes to a transaction, which might be flushed from time to time. Call FlushPuts() to ensure all Put entries are properly written to the database.
 	Put(ctx context.Context, key, value []byte) (err error)
-	// FlushPuts takes any pending writes (calls to Put()), and flushes them.
+	// FlushPuts takes any pending writes (calls to Put()), and flushes them. Once it returns without error, subsequent Put() calls start a new batch that never contains entries written before the flush.
 	FlushPuts(ctx context.Context) (err error)
 
 	// Get a given key.  Returns `kvdb.ErrNotFound` if not found.
 	Get(ctx context.Context, key []byte) (value []byte, err error)
-	// Get a batch of keys.  Returns `kvdb.ErrNotFound` the first time a key is not found: not finding a key is fatal and interrupts the resultset from being fetched completely.  BatchGet guarantees that Iterator return results in the exact same order as keys
-	BatchGet(ctx context.Context, keys [][]byte) *Iterator
+	// Get a batch of keys.  Returns `kvdb.ErrNotFound` the first time a key is not found: not finding a key is fatal and interrupts the resultset from being fetched completely.  BatchGet guarantees that Iterator return results in the exact same order as keys. With `KeyOnly()`, items have a `nil` value, values not being read when the backend allows it (see each backend).
+	BatchGet(ctx context.Context, keys [][]byte, options ...ReadOption) *Iterator
+	// ValueSize returns the size in bytes of the value of `key` as stored by the backend, so after compression and including any backend specific encoding, which is what it uses on disk. Returns `kvdb.ErrNotFound` if not found. The value is never decompressed, but depending on the backend it might still need to be fetched (see each backend).
+	ValueSize(ctx context.Context, key []byte) (size int, err error)
+	// Exists reports whether `key` exists. The value is not sent back to the caller, but depending on the backend it might still need to be fetched (see each backend).
+	Exists(ctx context.Context, key []byte) (exists bool, err error)
+	// BatchExists reports whether each of `keys` exists, `exists[i]` being the answer for `keys[i]`. Like `Exists`, values are not sent back to the caller.
+	BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error)
 
 	Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...ReadOption) *Iterator
 
@@ -26,6 +34,12 @@ type KVStore interface {
 	BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...ReadOption) *Iterator
 
 	BatchDelete(ctx context.Context, keys [][]byte) (err error)
+	// Delete a single key. Like `BatchDelete`, it is not part of the pending puts, and deleting a key that does not exist is not an error.
+	Delete(ctx context.Context, key []byte) (err error)
+
+	// Capabilities reports the optional features supported by this instance, so callers
+	// can pick code paths up front instead of probing for them.
+	Capabilities() Capabilities
 
 	// Close the underlying store engine and clear up any resources currently hold
 	// by this instance.
@@ -35,7 +49,114 @@ type KVStore interface {
 	Close() error
 }
 
-// ReversibleKVStore is not currently used.  Was to be an optimization to avoid writing block numbers twice (to search the timeline), for stores that support reverse scans (unlike Bigtable).
+// StatsReporter is implemented by stores able to report runtime statistics about themselves.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// Inserter is implemented by stores able to write a key only when it does not exist yet,
+// atomically. `Insert` returns `store.ErrKeyExists` when the key is already present, in
+// which case the stored value is left untouched.
+//
+// Contrary to `Put`, `Insert` is not buffered and is applied right away, it does not see
+// pending `Put` entries that were not flushed yet.
+type Inserter interface {
+	Insert(ctx context.Context, key, value []byte) (err error)
+}
+
+// Incrementer is implemented by stores able to atomically add to a counter. The counter value
+// is stored as a 8 bytes big-endian int64, a missing key starts at zero. `Increment` returns
+// the counter value after `delta` was added.
+//
+// Like `Insert`, `Increment` is not buffered and is applied right away.
+type Incrementer interface {
+	Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error)
+}
+
+// CompareAndSwapper is implemented by stores able to replace the value of a key only when it
+// still holds an expected value, atomically. An `old` value of `nil` expects the key not to
+// exist, while an empty one expects an existing empty value. A `new` value of `nil` deletes
+// the key. When the key does not hold `old`, `swapped` is false and the stored value is left
+// untouched, which is not an error.
+//
+// Like `Insert`, `CompareAndSwap` is not buffered and is applied right away.
+type CompareAndSwapper interface {
+	CompareAndSwap(ctx context.Context, key, old, new []byte) (swapped bool, err error)
+}
+
+// TTLCompareAndSwapper is implemented by stores able to compare and swap, see
+// `CompareAndSwapper`, writing a `new` value that expires after `ttl`, which must be positive.
+// Once expired, the key behaves as if it was deleted. The expiry is decided by the clock of
+// the store, never by the one of the caller, and might be rounded up to the store's precision.
+type TTLCompareAndSwapper interface {
+	CompareAndSwapWithTTL(ctx context.Context, key, old, new []byte, ttl time.Duration) (swapped bool, err error)
+}
+
+// Streamer is implemented by stores able to stream a value instead of materializing it fully
+// in memory. The value is read from a consistent snapshot, `store.ErrNotFound` is returned
+// directly when the key does not exist. The returned reader must always be closed.
+//
+// Use `store.GetStream` to stream from any store, it falls back to a buffered `Get` for
+// stores not implementing `Streamer`.
+type Streamer interface {
+	GetStream(ctx context.Context, key []byte) (io.ReadCloser, error)
+}
+
+// PrefixRenamer is implemented by stores able to rename a key prefix natively. All the keys
+// starting with `to` are deleted, then all the keys starting with `from` are moved under `to`,
+// keeping their suffix and value. `from` and `to` cannot be prefixes of one another. Puts not
+// yet flushed are not considered.
+//
+// Use `store.RenamePrefix` to rename a prefix on any store, it falls back to reads, puts and
+// deletes for stores not implementing `PrefixRenamer`. Whether the rename is atomic depends on
+// the store.
+type PrefixRenamer interface {
+	RenamePrefix(ctx context.Context, from, to []byte) error
+}
+
+// PrefixReplacer is implemented by stores able to replace the content of a key prefix
+// atomically: all the keys starting with `prefix` are deleted and `kvs` are written, readers
+// never observing the prefix partially replaced. All the keys of `kvs` must start with
+// `prefix`. Puts not yet flushed are not considered.
+//
+// Use `store.ReplacePrefix` to replace a prefix on any store, it falls back to listing the
+// keys of the prefix then `store.Apply` for stores not implementing `PrefixReplacer`.
+type PrefixReplacer interface {
+	ReplacePrefix(ctx context.Context, prefix []byte, kvs []KV) error
+}
+
+// Applier is implemented by stores able to apply a set of deletes and puts atomically: either
+// all of them are applied or none is, and readers never observe a part of them. Deletes are
+// applied before puts, so a key both deleted and put ends up with the put value. Puts not yet
+// flushed are not considered.
+//
+// Use `store.Apply` to apply mutations on any store, it falls back to non-atomic deletes and
+// puts for stores not implementing `Applier`, `Capabilities().Apply` telling which is used.
+type Applier interface {
+	Apply(ctx context.Context, puts []KV, deletes [][]byte) error
+}
+
+// Seeker is implemented by stores able to find the nearest existing key around a given one in
+// a single seek. `SeekFloor` returns the greatest key lower than or equal to `key` and
+// `SeekCeil` the lowest key greater than or equal to it, both along with their value. They
+// return `store.ErrNotFound` when there is no such key.
+type Seeker interface {
+	SeekFloor(ctx context.Context, key []byte) (KV, error)
+	SeekCeil(ctx context.Context, key []byte) (KV, error)
+}
+
+// MultiPrefixer is implemented by stores able to run many prefix scans in a single call. The
+// keys starting with each of `prefixes` are returned grouped by prefix, in the order of
+// `prefixes`, each group being limited to `limitEach` keys (`Unlimited` for no limit).
+// Prefixes cannot be prefixes of one another, so that each key belongs to a single group.
+//
+// Use `store.MultiPrefix` to run the scans on any store, it falls back to one `Prefix` call
+// per prefix for stores not implementing `MultiPrefixer`.
+type MultiPrefixer interface {
+	MultiPrefix(ctx context.Context, prefixes [][]byte, limitEach int, options ...ReadOption) *Iterator
+}
+
+// ReversibleKVStore is implemented by stores that support reverse scans (unlike Bigtable), items are returned in descending key order.  Was first meant as an optimization to avoid writing block numbers twice (to search the timeline).
 type ReversibleKVStore interface {
 	ReverseScan(ctx context.Context, start, exclusiveEnd []byte, limit int) *Iterator
 	ReversePrefix(ctx context.Context, prefix []byte, limit int) *Iterator
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A lease is stored as the 8 bytes big-endian unix nanoseconds time at which it expires,
+// followed by the name of its holder.
+const leaseExpiryLen = 8
+
+// AcquireLease takes the lease stored at `key` for `holder` during `ttl`, coordinating a single
+// writer among many instances sharing a store. It succeeds when nobody holds the lease, when
+// its previous holder let it expire, or when `holder` already holds it, in which case it is
+// extended. It returns false, without error, when another holder has it.
+//
+// Leases rely on `kv` implementing `CompareAndSwapper`, so concurrent instances never both
+// acquire the lease. When `kv` reports the `CompareAndSwapTTL` capability, the lease is written
+// with `ttl` and expires on the clock of the store. Otherwise, the expiry stored in the lease is
+// compared against the local clock: the clocks of the instances must then be kept in sync, with
+// a skew well below `ttl`. Either way, a holder must renew its lease well before `ttl` elapses,
+// and must stop writing once a renewal fails.
+func AcquireLease(ctx context.Context, kv KVStore, key []byte, holder string, ttl time.Duration) (acquired bool, err error) {
+	swapper, current, err := readLease(ctx, kv, key)
+	if err != nil {
+		return false, fmt.Errorf("acquire lease: %w", err)
+	}
+
+	if current != nil {
+		currentHolder, expiry := decodeLease(current)
+		if currentHolder != holder && (swapper.expiresLeases() || time.Now().Before(expiry)) {
+			return false, nil
+		}
+	}
+
+	acquired, err = swapper.swap(ctx, key, current, holder, ttl)
+	if err != nil {
+		return false, fmt.Errorf("acquire lease: %w", err)
+	}
+	return acquired, nil
+}
+
+// RenewLease extends the lease stored at `key` by `ttl` from now, see `AcquireLease`. It
+// returns false, without error, when `holder` does not hold the lease anymore, the lease having
+// been taken over after it expired.
+func RenewLease(ctx context.Context, kv KVStore, key []byte, holder string, ttl time.Duration) (renewed bool, err error) {
+	swapper, current, err := readLease(ctx, kv, key)
+	if err != nil {
+		return false, fmt.Errorf("renew lease: %w", err)
+	}
+
+	if current == nil {
+		return false, nil
+	}
+	if currentHolder, _ := decodeLease(current); currentHolder != holder {
+		return false, nil
+	}
+
+	renewed, err = swapper.swap(ctx, key, current, holder, ttl)
+	if err != nil {
+		return false, fmt.Errorf("renew lease: %w", err)
+	}
+	return renewed, nil
+}
+
+// ReleaseLease deletes the lease stored at `key` when `holder` holds it, so that another holder
+// can acquire it right away instead of waiting for it to expire, see `AcquireLease`. It returns
+// false, without error, when `holder` does not hold the lease.
+func ReleaseLease(ctx context.Context, kv KVStore, key []byte, holder string) (released bool, err error) {
+	swapper, current, err := readLease(ctx, kv, key)
+	if err != nil {
+		return false, fmt.Errorf("release lease: %w", err)
+	}
+
+	if current == nil {
+		return false, nil
+	}
+	if currentHolder, _ := decodeLease(current); currentHolder != holder {
+		return false, nil
+	}
+
+	released, err = swapper.CompareAndSwap(ctx, key, current, nil)
+	if err != nil {
+		return false, fmt.Errorf("release lease: %w", err)
+	}
+	return released, nil
+}
+
+// leaseSwapper writes leases through `CompareAndSwapWithTTL` when `ttl` is set, or through
+// `CompareAndSwap` otherwise
+type leaseSwapper struct {
+	CompareAndSwapper
+	ttl TTLCompareAndSwapper
+}
+
+// expiresLeases is true when the store expires leases itself, a stored lease is then held
+func (s leaseSwapper) expiresLeases() bool {
+	return s.ttl != nil
+}
+
+func (s leaseSwapper) swap(ctx context.Context, key, current []byte, holder string, ttl time.Duration) (bool, error) {
+	lease := encodeLease(holder, time.Now().Add(ttl))
+	if s.ttl != nil {
+		return s.ttl.CompareAndSwapWithTTL(ctx, key, current, lease, ttl)
+	}
+	return s.CompareAndSwap(ctx, key, current, lease)
+}
+
+// readLease returns the lease stored at `key`, `nil` when there is none.
+func readLease(ctx context.Context, kv KVStore, key []byte) (leaseSwapper, []byte, error) {
+	swapper, ok := kv.(CompareAndSwapper)
+	if !ok {
+		return leaseSwapper{}, nil, fmt.Errorf("store does not support compare and swap")
+	}
+
+	writer := leaseSwapper{CompareAndSwapper: swapper}
+	if ttlSwapper, ok := kv.(TTLCompareAndSwapper); ok && kv.Capabilities().CompareAndSwapTTL {
+		writer.ttl = ttlSwapper
+	}
+
+	current, err := kv.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return writer, nil, nil
+	}
+	if err != nil {
+		return leaseSwapper{}, nil, err
+	}
+
+	if len(current) < leaseExpiryLen {
+		return leaseSwapper{}, nil, fmt.Errorf("value of key %s is not a lease, got %d bytes", Key(key), len(current))
+	}
+	return writer, current, nil
+}
+
+func encodeLease(holder string, expiry time.Time) []byte {
+	value := make([]byte, leaseExpiryLen+len(holder))
+	binary.BigEndian.PutUint64(value, uint64(expiry.UnixNano()))
+	copy(value[leaseExpiryLen:], holder)
+	return value
+}
+
+func decodeLease(value []byte) (holder string, expiry time.Time) {
+	return string(value[leaseExpiryLen:]), time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"bytes"
+	"context"
+)
+
+// ScanFunc starts a key-ordered scan using the given context and returns its iterator.
+type ScanFunc func(ctx context.Context) *Iterator
+
+// MergeScans starts all the received scans and merges their results into a single iterator
+// yielding items in key order, stopping after `limit` items (`Unlimited` for no limit).
+// Each scan must produce its own items in key order, which is what `Scan` and `Prefix`
+// guarantee. When two scans produce the same key, both items are emitted, the one coming
+// from the earliest scan first.
+//
+// The scans are started with a child context of `ctx` that is cancelled once the merge
+// completes, so scans that were not fully consumed are released properly. Picking the
+// next item is linear in the number of scans, this is meant to merge a handful of them.
+func MergeScans(ctx context.Context, limit int, scans ...ScanFunc) *Iterator {
+	it := NewIterator(ctx)
+
+	go func() {
+		scanCtx, cancelScans := context.WithCancel(ctx)
+		defer cancelScans()
+
+		iterators := make([]*Iterator, len(scans))
+		heads := make([]*KV, len(scans))
+		for i, scan := range scans {
+			iterators[i] = scan(scanCtx)
+
+			head, err := nextHead(iterators[i])
+			if err != nil {
+				it.PushError(err)
+				return
+			}
+			heads[i] = head
+		}
+
+		count := uint64(0)
+		for {
+			next := -1
+			for i, head := range heads {
+				if head != nil && (next == -1 || bytes.Compare(head.Key, heads[next].Key) < 0) {
+					next = i
+				}
+			}
+
+			if next == -1 {
+				break
+			}
+
+			if !it.PushItem(*heads[next]) {
+				return
+			}
+
+			count++
+			if Limit(limit).Reached(count) {
+				break
+			}
+
+			head, err := nextHead(iterators[next])
+			if err != nil {
+				it.PushError(err)
+				return
+			}
+			heads[next] = head
+		}
+
+		it.PushFinished()
+	}()
+
+	return it
+}
+
+func nextHead(it *Iterator) (*KV, error) {
+	if !it.Next() {
+		return nil, it.Err()
+	}
+
+	item := it.Item()
+	return &item, nil
+}
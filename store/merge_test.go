@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeScans(t *testing.T) {
+	tests := []struct {
+		name         string
+		scans        [][]string
+		limit        int
+		expectedKeys []string
+	}{
+		{"no scans", nil, 0, nil},
+		{"empty scans", [][]string{{}, {}}, 0, nil},
+		{"single scan", [][]string{{"a", "b"}}, 0, []string{"a", "b"}},
+		{"interleaved", [][]string{{"a", "d", "e"}, {"b", "c", "f"}}, 0, []string{"a", "b", "c", "d", "e", "f"}},
+		{"duplicated keys", [][]string{{"a", "b"}, {"b"}}, 0, []string{"a", "b", "b"}},
+		{"limited", [][]string{{"a", "d", "e"}, {"b", "c", "f"}}, 4, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scans := make([]ScanFunc, len(test.scans))
+			for i, keys := range test.scans {
+				scans[i] = testScanFunc(keys, nil)
+			}
+
+			var keys []string
+			it := MergeScans(context.Background(), test.limit, scans...)
+			for it.Next() {
+				keys = append(keys, string(it.Item().Key))
+			}
+
+			require.NoError(t, it.Err())
+			assert.Equal(t, test.expectedKeys, keys)
+		})
+	}
+}
+
+func TestMergeScans_Error(t *testing.T) {
+	scanErr := errors.New("scan failed")
+
+	it := MergeScans(context.Background(), 0, testScanFunc([]string{"a"}, nil), testScanFunc([]string{"b"}, scanErr))
+	for it.Next() {
+	}
+
+	assert.Equal(t, scanErr, it.Err())
+}
+
+func testScanFunc(keys []string, err error) ScanFunc {
+	return func(ctx context.Context) *Iterator {
+		it := NewIterator(ctx)
+		go func() {
+			for _, key := range keys {
+				if !it.PushItem(KV{Key: []byte(key), Value: []byte("v")}) {
+					return
+				}
+			}
+
+			if err != nil {
+				it.PushError(err)
+				return
+			}
+			it.PushFinished()
+		}()
+		return it
+	}
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// MultiPrefix returns the keys starting with each of `prefixes`, grouped by prefix, see
+// `MultiPrefixer`. The scans are run by `kv` in a single call when it implements
+// `MultiPrefixer`, otherwise `Prefix` is called for each prefix in turn, each scan then
+// seeing the store as it is when it starts.
+func MultiPrefix(ctx context.Context, kv KVStore, prefixes [][]byte, limitEach int, options ...ReadOption) *Iterator {
+	if multiPrefixer, ok := kv.(MultiPrefixer); ok {
+		return multiPrefixer.MultiPrefix(ctx, prefixes, limitEach, options...)
+	}
+
+	it := NewIterator(ctx)
+	if err := CheckMultiPrefixes(prefixes); err != nil {
+		it.PushError(err)
+		return it
+	}
+
+	go func() {
+		for _, prefix := range prefixes {
+			prefixIt := kv.Prefix(ctx, prefix, limitEach, options...)
+			for prefixIt.Next() {
+				if !it.PushItem(prefixIt.Item()) {
+					return
+				}
+			}
+
+			if err := prefixIt.Err(); err != nil {
+				it.PushError(err)
+				return
+			}
+		}
+
+		it.PushFinished()
+	}()
+
+	return it
+}
+
+// CheckMultiPrefixes returns an error when one of `prefixes` is a prefix of another one,
+// including when a prefix is repeated, which `MultiPrefixer` does not accept.
+func CheckMultiPrefixes(prefixes [][]byte) error {
+	for i, prefix := range prefixes {
+		for _, other := range prefixes[i+1:] {
+			if bytes.HasPrefix(prefix, other) || bytes.HasPrefix(other, prefix) {
+				return fmt.Errorf("multi prefix: prefixes %s and %s overlap", Key(prefix), Key(other))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package netkv
+
+import (
+	"context"
+
+	"github.com/dfuse-io/kvdb/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokenCredentials sends the token of the DSN along with each request, see the
+// `WithTokenRules` option of the server.
+type tokenCredentials struct {
+	token                    string
+	requireTransportSecurity bool
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return c.requireTransportSecurity
+}
+
+// wrapForbiddenError turns the `PermissionDenied` status of a server restricting its clients
+// to prefixes back into `store.ErrForbidden`
+func wrapForbiddenError(err error) error {
+	if status.Code(err) == codes.PermissionDenied {
+		return store.ErrForbidden
+	}
+	return err
+}
+
+func forbiddenUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return wrapForbiddenError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+func forbiddenStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, wrapForbiddenError(err)
+	}
+	return &forbiddenClientStream{ClientStream: stream}, nil
+}
+
+// forbiddenClientStream maps the errors of streamed responses, which are only received once
+// the stream is read
+type forbiddenClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *forbiddenClientStream) RecvMsg(m interface{}) error {
+	return wrapForbiddenError(s.ClientStream.RecvMsg(m))
+}
@@ -6,13 +6,18 @@ import (
 	"io"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dfuse-io/logging"
 
 	"github.com/dfuse-io/kvdb/store"
 	pbnetkv "github.com/dfuse-io/kvdb/store/netkv/pb"
+	"github.com/golang/protobuf/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Store struct {
@@ -20,6 +25,14 @@ type Store struct {
 	conn     *grpc.ClientConn
 	client   pbnetkv.NetKVClient
 	putBatch []*pbnetkv.KeyValue
+	putLock  sync.Mutex
+
+	// strongConsistency makes reads flush the pending puts first, so they see them, which
+	// commits them on the server, see `NewStore`
+	strongConsistency bool
+	// capabilities of the store served by the server, see `Capabilities`
+	capabilities     *pbnetkv.CapabilitiesResponse
+	capabilitiesLock sync.Mutex
 }
 
 func (s *Store) String() string {
@@ -41,10 +54,18 @@ func NewStore(dsnString string) (store.KVStore, error) {
 	}
 
 	var grpcOpts []grpc.DialOption
-	if dsn.Query().Get("insecure") == "true" {
+	insecure := dsn.Query().Get("insecure") == "true"
+	if insecure {
 		grpcOpts = append(grpcOpts, grpc.WithInsecure())
 	}
 
+	// `token` authenticates against a server restricting its clients to the prefixes granted to
+	// their token, accesses to other keys then fail with `store.ErrForbidden`
+	if token := dsn.Query().Get("token"); token != "" {
+		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(&tokenCredentials{token: token, requireTransportSecurity: !insecure}))
+	}
+	grpcOpts = append(grpcOpts, grpc.WithUnaryInterceptor(forbiddenUnaryInterceptor), grpc.WithStreamInterceptor(forbiddenStreamInterceptor))
+
 	// TODO: init gRPC connection to the `dsn.Host`
 	conn, err := grpc.Dial(dsn.Host, grpcOpts...)
 	if err != nil {
@@ -59,6 +80,21 @@ func NewStore(dsnString string) (store.KVStore, error) {
 		client: client,
 	}
 
+	// With `eventual` consistency (the default), reads do not see puts not yet flushed
+	// through `FlushPuts`. With `strong`, each read calls `FlushPuts` first: the pending puts
+	// are then committed on the server, before the caller flushes them itself, so a caller
+	// building a batch meant to be committed as a whole must not read through a strong store
+	// until it is complete.
+	switch consistency := dsn.Query().Get("consistency"); consistency {
+	case "", "eventual":
+	case "strong":
+		zlog.Info("strong consistency requested, reads commit the pending puts", zap.String("dsn", dsnString))
+		s.strongConsistency = true
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("netkv new: invalid consistency %q, expecting 'strong' or 'eventual'", consistency)
+	}
+
 	return s, nil
 }
 
@@ -69,19 +105,113 @@ func (s *Store) Close() error {
 func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
 	zlogger := logging.Logger(ctx, zlog)
 	zlogger.Debug("putting", zap.Stringer("key", store.Key(key)))
+	s.putLock.Lock()
+	defer s.putLock.Unlock()
+
 	s.putBatch = append(s.putBatch, &pbnetkv.KeyValue{Key: key, Value: value})
 	return nil
 }
 
+// FlushPuts sends the pending puts to the server, in as many `BatchPut` calls as needed for
+// each message to stay under `maxBatchPutSize`. When a call fails, the puts of the calls that
+// succeeded are not pending anymore, the others are kept for the next flush.
 func (s *Store) FlushPuts(ctx context.Context) error {
-	if s.putBatch == nil {
-		return nil
+	s.putLock.Lock()
+	defer s.putLock.Unlock()
+
+	for len(s.putBatch) > 0 {
+		chunk := nextPutChunk(s.putBatch)
+		if _, err := s.client.BatchPut(ctx, &pbnetkv.KeyValues{Kvs: chunk}); err != nil {
+			return wrapWriteError(err)
+		}
+		s.putBatch = s.putBatch[len(chunk):]
 	}
-	_, err := s.client.BatchPut(ctx, &pbnetkv.KeyValues{Kvs: s.putBatch})
+
+	s.putBatch = nil
+	return nil
+}
+
+// maxBatchPutSize bounds the size of a `BatchPut` message, under the 4MiB every server accepts
+// whatever its maximum value length, leaving room for the message framing
+const maxBatchPutSize = 4*1024*1024 - 64*1024
+
+// nextPutChunk returns the longest run of `kvs` fitting in a `BatchPut` message of at most
+// `maxBatchPutSize` bytes, at least one put: a larger one is sent alone, servers raising their
+// message size limit to fit their largest value.
+func nextPutChunk(kvs []*pbnetkv.KeyValue) []*pbnetkv.KeyValue {
+	size := 0
+	for i, kv := range kvs {
+		// Each entry of the repeated field adds a tag and a length prefix of at most 6 bytes
+		size += proto.Size(kv) + 6
+		if size > maxBatchPutSize && i > 0 {
+			return kvs[:i]
+		}
+	}
+	return kvs
+}
+
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	logging.Logger(ctx, zlog).Debug("inserting", zap.Stringer("key", store.Key(key)))
+
+	_, err = s.client.Insert(ctx, &pbnetkv.KeyValue{Key: key, Value: value})
+	if status.Code(err) == codes.AlreadyExists {
+		return store.ErrKeyExists
+	}
+	return wrapWriteError(err)
+}
+
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	logging.Logger(ctx, zlog).Debug("incrementing", zap.Stringer("key", store.Key(key)), zap.Int64("delta", delta))
+
+	resp, err := s.client.Increment(ctx, &pbnetkv.IncrementRequest{Key: key, Delta: delta})
 	if err != nil {
-		return err
+		return 0, wrapWriteError(err)
+	}
+	return resp.Value, nil
+}
+
+func (s *Store) CompareAndSwap(ctx context.Context, key, old, new []byte) (swapped bool, err error) {
+	logging.Logger(ctx, zlog).Debug("comparing and swapping", zap.Stringer("key", store.Key(key)), zap.Bool("delete", new == nil))
+	return s.compareAndSwap(ctx, key, old, new, 0)
+}
+
+// CompareAndSwapWithTTL is `CompareAndSwap` with `new` expiring after `ttl`, rounded up to the
+// millisecond, on the clock of the server. The served store must support it, see the
+// `CompareAndSwapTTL` capability, the call fails otherwise.
+func (s *Store) CompareAndSwapWithTTL(ctx context.Context, key, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	logging.Logger(ctx, zlog).Debug("comparing and swapping with ttl", zap.Stringer("key", store.Key(key)), zap.Bool("delete", new == nil), zap.Duration("ttl", ttl))
+	if ttl <= 0 {
+		return false, fmt.Errorf("invalid ttl %s, must be positive", ttl)
+	}
+	return s.compareAndSwap(ctx, key, old, new, uint64((ttl+time.Millisecond-1)/time.Millisecond))
+}
+
+func (s *Store) compareAndSwap(ctx context.Context, key, old, new []byte, ttlMs uint64) (swapped bool, err error) {
+	// Protobuf does not tell `nil` from empty bytes, the absent and delete cases travel as flags
+	resp, err := s.client.CompareAndSwap(ctx, &pbnetkv.CompareAndSwapRequest{
+		Key:       key,
+		OldValue:  old,
+		OldAbsent: old == nil,
+		NewValue:  new,
+		Delete:    new == nil,
+		TtlMs:     ttlMs,
+	})
+	if err != nil {
+		return false, wrapWriteError(err)
+	}
+	return resp.Swapped, nil
+}
+
+// flushBeforeRead flushes the pending puts when strong consistency is requested, so that the
+// upcoming read sees them, committing them as `FlushPuts` does
+func (s *Store) flushBeforeRead(ctx context.Context) error {
+	if !s.strongConsistency {
+		return nil
+	}
+
+	if err := s.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("flush before read: %w", err)
 	}
-	s.putBatch = nil
 	return nil
 }
 
@@ -94,6 +224,10 @@ func wrapNotFoundError(err error) error {
 }
 
 func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	if err := s.flushBeforeRead(ctx); err != nil {
+		return nil, err
+	}
+
 	resp, err := s.client.BatchGet(ctx, &pbnetkv.Keys{Keys: [][]byte{key}})
 	if err != nil {
 		return nil, err
@@ -112,23 +246,74 @@ func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
 		}
 
 		// TODO: we'll check `NotFound` in the `BatchGet` eventually?
-		value = kv.Value
+		value = nonNilValue(kv.Value)
 	}
 	return
 }
 
-func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	if err := s.flushBeforeRead(ctx); err != nil {
+		return 0, err
+	}
+
+	resp, err := s.client.ValueSize(ctx, &pbnetkv.ValueSizeRequest{Key: key})
+	if err != nil {
+		return 0, wrapNotFoundError(err)
+	}
+	return int(resp.Size), nil
+}
+
+// Exists is served by the server, the value is never transferred.
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	if err := s.flushBeforeRead(ctx); err != nil {
+		return false, err
+	}
+
+	resp, err := s.client.Exists(ctx, &pbnetkv.ExistsRequest{Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Exists, nil
+}
+
+// BatchExists is served by the server in a single call, values are never transferred.
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	if err := s.flushBeforeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return []bool{}, nil
+	}
+
+	resp, err := s.client.BatchExists(ctx, &pbnetkv.Keys{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Exists) != len(keys) {
+		return nil, fmt.Errorf("received %d answers for %d keys", len(resp.Exists), len(keys))
+	}
+	return resp.Exists, nil
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
 	it := store.NewIterator(ctx)
+	if err := s.flushBeforeRead(ctx); err != nil {
+		it.PushError(err)
+		return it
+	}
 
+	readOptions := netkvReadOptions(options)
 	go func() {
-		resp, err := s.client.BatchGet(ctx, &pbnetkv.Keys{Keys: keys})
+		resp, err := s.client.BatchGet(ctx, &pbnetkv.Keys{Keys: keys, Options: readOptions})
 		if err != nil {
 			it.PushError(err)
 			return
 		}
 		for {
 			kv, err := resp.Recv()
-			if !pushToIterator(it, kv, err) {
+			if !pushToIterator(it, kv, readOptions.KeyOnly, err) {
 				break
 			}
 		}
@@ -138,23 +323,55 @@ func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
 
 func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
 	if _, err := s.client.BatchDelete(ctx, &pbnetkv.Keys{Keys: keys}); err != nil {
-		return err
+		return wrapWriteError(err)
 	}
 	return nil
 }
 
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	if _, err := s.client.Delete(ctx, &pbnetkv.DeleteRequest{Key: key}); err != nil {
+		return wrapWriteError(err)
+	}
+	return nil
+}
+
+// wrapWriteError turns the statuses the server rejects writes with back into store errors,
+// `FailedPrecondition` when its backing store is read-only into `store.ErrReadOnly` and
+// `ResourceExhausted` carrying a `ValueTooLarge` detail, when a value is larger than it
+// accepts, into `store.ErrValueTooLarge`. Other resource exhaustions are returned as-is.
+func wrapWriteError(err error) error {
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		return store.ErrReadOnly
+	case codes.ResourceExhausted:
+		st := status.Convert(err)
+		for _, detail := range st.Details() {
+			if _, ok := detail.(*pbnetkv.ValueTooLarge); ok {
+				return fmt.Errorf("%s: %w", st.Message(), store.ErrValueTooLarge)
+			}
+		}
+	}
+	return err
+}
+
 func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
 	it := store.NewIterator(ctx)
+	if err := s.flushBeforeRead(ctx); err != nil {
+		it.PushError(err)
+		return it
+	}
+
+	readOptions := netkvReadOptions(options)
 
 	go func() {
-		resp, err := s.client.Scan(ctx, &pbnetkv.ScanRequest{Start: start, ExclusiveEnd: exclusiveEnd, Limit: uint64(limit), Options: netkvReadOptions(options)})
+		resp, err := s.client.Scan(ctx, &pbnetkv.ScanRequest{Start: start, ExclusiveEnd: exclusiveEnd, Limit: uint64(limit), Options: readOptions})
 		if err != nil {
 			it.PushError(err)
 			return
 		}
 		for {
 			kv, err := resp.Recv()
-			if !pushToIterator(it, kv, err) {
+			if !pushToIterator(it, kv, readOptions.KeyOnly, err) {
 				break
 			}
 		}
@@ -162,7 +379,7 @@ func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int,
 	return it
 }
 
-func pushToIterator(it *store.Iterator, kv *pbnetkv.KeyValue, err error) bool {
+func pushToIterator(it *store.Iterator, kv *pbnetkv.KeyValue, keyOnly bool, err error) bool {
 	if err == io.EOF {
 		it.PushFinished()
 		return false
@@ -172,22 +389,42 @@ func pushToIterator(it *store.Iterator, kv *pbnetkv.KeyValue, err error) bool {
 		return false
 	}
 
+	value := kv.Value
+	if !keyOnly {
+		value = nonNilValue(value)
+	}
+
 	// TODO: we'll check `NotFound` in the `BatchGet` eventually?
-	return it.PushItem(store.KV{Key: kv.Key, Value: kv.Value})
+	return it.PushItem(store.KV{Key: kv.Key, Value: value})
+}
+
+// nonNilValue turns a `nil` value into a zero-length one. Protobuf does not make the difference
+// between an empty and a `nil` bytes field, so an empty stored value reaches us as `nil`.
+func nonNilValue(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
 }
 
 func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
 	it := store.NewIterator(ctx)
+	if err := s.flushBeforeRead(ctx); err != nil {
+		it.PushError(err)
+		return it
+	}
+
+	readOptions := netkvReadOptions(options)
 
 	go func() {
-		resp, err := s.client.Prefix(ctx, &pbnetkv.PrefixRequest{Prefix: prefix, Limit: uint64(limit), Options: netkvReadOptions(options)})
+		resp, err := s.client.Prefix(ctx, &pbnetkv.PrefixRequest{Prefix: prefix, Limit: uint64(limit), Options: readOptions})
 		if err != nil {
 			it.PushError(err)
 			return
 		}
 		for {
 			kv, err := resp.Recv()
-			if !pushToIterator(it, kv, err) {
+			if !pushToIterator(it, kv, readOptions.KeyOnly, err) {
 				break
 			}
 		}
@@ -197,16 +434,49 @@ func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ..
 
 func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limitPerPrefix int, options ...store.ReadOption) *store.Iterator {
 	it := store.NewIterator(ctx)
+	if err := s.flushBeforeRead(ctx); err != nil {
+		it.PushError(err)
+		return it
+	}
+
+	readOptions := netkvReadOptions(options)
+
+	go func() {
+		resp, err := s.client.BatchPrefix(ctx, &pbnetkv.BatchPrefixRequest{Prefixes: prefixes, LimitPerPrefix: uint64(limitPerPrefix), Options: readOptions})
+		if err != nil {
+			it.PushError(err)
+			return
+		}
+		for {
+			kv, err := resp.Recv()
+			if !pushToIterator(it, kv, readOptions.KeyOnly, err) {
+				break
+			}
+		}
+	}()
+	return it
+}
+
+// MultiPrefix runs the scans of all `prefixes` server-side in a single call, see
+// `store.MultiPrefixer`, with the backing store running them at once when it can.
+func (s *Store) MultiPrefix(ctx context.Context, prefixes [][]byte, limitEach int, options ...store.ReadOption) *store.Iterator {
+	it := store.NewIterator(ctx)
+	if err := s.flushBeforeRead(ctx); err != nil {
+		it.PushError(err)
+		return it
+	}
+
+	readOptions := netkvReadOptions(options)
 
 	go func() {
-		resp, err := s.client.BatchPrefix(ctx, &pbnetkv.BatchPrefixRequest{Prefixes: prefixes, LimitPerPrefix: uint64(limitPerPrefix), Options: netkvReadOptions(options)})
+		resp, err := s.client.MultiPrefix(ctx, &pbnetkv.MultiPrefixRequest{Prefixes: prefixes, LimitEach: uint64(limitEach), Options: readOptions})
 		if err != nil {
 			it.PushError(err)
 			return
 		}
 		for {
 			kv, err := resp.Recv()
-			if !pushToIterator(it, kv, err) {
+			if !pushToIterator(it, kv, readOptions.KeyOnly, err) {
 				break
 			}
 		}
@@ -230,5 +500,6 @@ func netkvReadOptions(options []store.ReadOption) *pbnetkv.ReadOptions {
 
 	return &pbnetkv.ReadOptions{
 		KeyOnly: readOptions.KeyOnly,
+		Raw:     readOptions.Raw,
 	}
 }
@@ -1,18 +1,26 @@
 package netkv
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
 	"testing"
 	"time"
 
 	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/kvdb/store/acl"
 	_ "github.com/dfuse-io/kvdb/store/badger"
+	pbnetkv "github.com/dfuse-io/kvdb/store/netkv/pb"
 	netkvserver "github.com/dfuse-io/kvdb/store/netkv/server"
 	"github.com/dfuse-io/kvdb/store/storetest"
 	"github.com/dfuse-io/logging"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func init() {
@@ -23,7 +31,11 @@ func TestAll(t *testing.T) {
 	storetest.TestAll(t, "NetKV", newTestNetKVFactory(t))
 }
 
-func newTestNetKVFactory(t *testing.T) storetest.DriverFactory {
+func BenchmarkAll(b *testing.B) {
+	storetest.Benchmark(b, newTestNetKVFactory(b))
+}
+
+func newTestNetKVFactory(t testing.TB) storetest.DriverFactory {
 	return func(opts ...store.Option) (store.KVStore, *storetest.DriverCapabilities, storetest.DriverCleanupFunc) {
 		// Start a server
 		dir, err := ioutil.TempDir("", "kvdb-netkv-server")
@@ -45,3 +57,294 @@ func newTestNetKVFactory(t *testing.T) storetest.DriverFactory {
 		}
 	}
 }
+
+func TestConsistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65113", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+
+	eventual, err := NewStore("netkv://localhost:65113?insecure=true&consistency=eventual")
+	require.NoError(t, err)
+	defer eventual.Close()
+
+	require.NoError(t, eventual.Put(ctx, []byte("eventual"), []byte("1")))
+	_, err = eventual.Get(ctx, []byte("eventual"))
+	assert.Equal(t, store.ErrNotFound, err, "eventual reads must not see unflushed puts")
+
+	strong, err := NewStore("netkv://localhost:65113?insecure=true&consistency=strong")
+	require.NoError(t, err)
+	defer strong.Close()
+
+	require.NoError(t, strong.Put(ctx, []byte("strong/a"), []byte("2")))
+	value, err := strong.Get(ctx, []byte("strong/a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+
+	require.NoError(t, strong.Put(ctx, []byte("strong/b"), []byte("3")))
+	it := strong.Prefix(ctx, []byte("strong/"), store.Unlimited)
+	var keys []string
+	for it.Next() {
+		keys = append(keys, string(it.Item().Key))
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"strong/a", "strong/b"}, keys)
+
+	// Strong reads commit the pending puts, other clients see them before any `FlushPuts`
+	require.NoError(t, strong.Put(ctx, []byte("strong/c"), []byte("4")))
+	_, err = strong.Get(ctx, []byte("strong/a"))
+	require.NoError(t, err)
+	value, err = eventual.Get(ctx, []byte("strong/c"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("4"), value)
+
+	_, err = NewStore("netkv://localhost:65113?insecure=true&consistency=other")
+	assert.Error(t, err)
+}
+
+func TestMaxKeyLen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65114", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")), netkvserver.WithMaxKeyLen(8))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kvStore, err := NewStore("netkv://localhost:65114?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("short"), []byte("1")))
+	require.NoError(t, kvStore.Put(ctx, []byte("much too long"), []byte("2")))
+	err = kvStore.FlushPuts(ctx)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = kvStore.(store.Inserter).Insert(ctx, []byte("much too long"), []byte("3"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The whole batch is rejected
+	reader, err := NewStore("netkv://localhost:65114?insecure=true&consistency=eventual")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	_, err = reader.Get(ctx, []byte("short"))
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestMaxValueLen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65116", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")), netkvserver.WithMaxValueLen(8))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kvStore, err := NewStore("netkv://localhost:65116?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("12345678")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	require.NoError(t, kvStore.Put(ctx, []byte("b"), []byte("123456789")))
+	err = kvStore.FlushPuts(ctx)
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %s", err)
+
+	err = kvStore.(store.Inserter).Insert(ctx, []byte("c"), []byte("123456789"))
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %s", err)
+
+	value, err := kvStore.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12345678"), value)
+}
+
+func TestMaxValueLen_AboveMessageLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65118", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")), netkvserver.WithMaxValueLen(6*1024*1024))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kvStore, err := NewStore("netkv://localhost:65118?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	// Above the 4MiB gRPC default, the server accepts messages fitting its largest value
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), make([]byte, 5*1024*1024)))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	size, err := kvStore.ValueSize(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 5*1024*1024, size)
+
+	require.NoError(t, kvStore.Put(ctx, []byte("b"), make([]byte, 6*1024*1024+1)))
+	err = kvStore.FlushPuts(ctx)
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %s", err)
+}
+
+func TestFlushPuts_SplitsLargeBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65119", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kvStore, err := NewStore("netkv://localhost:65119?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	// Ten times the 4MiB the server accepts in a single message
+	ctx := context.Background()
+	for i := 0; i < 40; i++ {
+		require.NoError(t, kvStore.Put(ctx, []byte(fmt.Sprintf("key%02d", i)), make([]byte, 1024*1024)))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	for _, key := range []string{"key00", "key39"} {
+		size, err := kvStore.ValueSize(ctx, []byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, 1024*1024, size)
+	}
+}
+
+func TestWrapWriteError_ResourceExhausted(t *testing.T) {
+	// Only the status of a value too large carries the detail
+	err := wrapWriteError(status.Error(codes.ResourceExhausted, "grpc: received message larger than max"))
+	assert.False(t, errors.Is(err, store.ErrValueTooLarge), "got %s", err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	st, err := status.New(codes.ResourceExhausted, "value of 9 bytes exceeds maximum of 8").WithDetails(&pbnetkv.ValueTooLarge{Size: 9, MaxSize: 8})
+	require.NoError(t, err)
+	err = wrapWriteError(st.Err())
+	assert.True(t, errors.Is(err, store.ErrValueTooLarge), "got %s", err)
+}
+
+func TestReadOnlyServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	backingDSN := fmt.Sprintf("badger://%s", path.Join(dir, "netkv"))
+
+	// Badger can only be opened read-only once the database exists
+	backing, err := store.New(backingDSN)
+	require.NoError(t, err)
+	require.NoError(t, backing.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, backing.Close())
+
+	server, err := netkvserver.Launch(":65115", backingDSN+"?read_only=true")
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kvStore, err := NewStore("netkv://localhost:65115?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	value, err := kvStore.Get(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	require.NoError(t, kvStore.Put(ctx, []byte("b"), []byte("2")))
+	assert.Equal(t, store.ErrReadOnly, kvStore.FlushPuts(ctx))
+	assert.Equal(t, store.ErrReadOnly, kvStore.Delete(ctx, []byte("a")))
+	assert.Equal(t, store.ErrReadOnly, kvStore.BatchDelete(ctx, [][]byte{[]byte("a")}))
+	assert.Equal(t, store.ErrReadOnly, kvStore.(store.Inserter).Insert(ctx, []byte("c"), []byte("3")))
+	_, err = kvStore.(store.Incrementer).Increment(ctx, []byte("counter"), 1)
+	assert.Equal(t, store.ErrReadOnly, err)
+
+	_, err = kvStore.Get(ctx, []byte("b"))
+	assert.Equal(t, store.ErrNotFound, err)
+
+	// The capabilities are those of the served store
+	capabilities := kvStore.Capabilities()
+	assert.True(t, capabilities.ReadOnly)
+	assert.True(t, capabilities.Insert)
+}
+
+func TestCapabilities_ServerUnreachable(t *testing.T) {
+	kvStore, err := NewStore("netkv://localhost:65120?insecure=true")
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	// Nothing served is reported until the server can be reached
+	assert.Equal(t, store.Capabilities{EmptyValue: true, MultiPrefix: true}, kvStore.Capabilities())
+}
+
+func TestTokenRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-netkv-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server, err := netkvserver.Launch(":65117", fmt.Sprintf("badger://%s", path.Join(dir, "netkv")), netkvserver.WithTokenRules(map[string][]acl.Rule{
+		"writer": {{Prefix: nil, Write: true}},
+		"reader": {{Prefix: []byte("blk:")}},
+	}))
+	require.NoError(t, err)
+	defer server.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+	newStore := func(dsnQuery string) store.KVStore {
+		kvStore, err := NewStore("netkv://localhost:65117?insecure=true" + dsnQuery)
+		require.NoError(t, err)
+		return kvStore
+	}
+
+	writer := newStore("&token=writer")
+	defer writer.Close()
+	require.NoError(t, writer.Put(ctx, []byte("blk:1"), []byte("block")))
+	require.NoError(t, writer.Put(ctx, []byte("acc:1"), []byte("account")))
+	require.NoError(t, writer.FlushPuts(ctx))
+
+	reader := newStore("&token=reader")
+	defer reader.Close()
+
+	value, err := reader.Get(ctx, []byte("blk:1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("block"), value)
+
+	it := reader.Prefix(ctx, []byte("blk:"), store.Unlimited)
+	for it.Next() {
+		assert.Equal(t, []byte("blk:1"), it.Item().Key)
+	}
+	require.NoError(t, it.Err())
+
+	_, err = reader.Get(ctx, []byte("acc:1"))
+	assert.Equal(t, store.ErrForbidden, err)
+
+	it = reader.Prefix(ctx, []byte("acc:"), store.Unlimited)
+	for it.Next() {
+	}
+	assert.Equal(t, store.ErrForbidden, it.Err())
+
+	require.NoError(t, reader.Put(ctx, []byte("blk:2"), []byte("block")))
+	assert.Equal(t, store.ErrForbidden, reader.FlushPuts(ctx))
+
+	for _, dsnQuery := range []string{"", "&token=unknown"} {
+		anonymous := newStore(dsnQuery)
+		_, err = anonymous.Get(ctx, []byte("blk:1"))
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), dsnQuery)
+		anonymous.Close()
+	}
+}
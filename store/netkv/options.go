@@ -1,5 +1,62 @@
 package netkv
 
+import (
+	"context"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	pbnetkv "github.com/dfuse-io/kvdb/store/netkv/pb"
+	"go.uber.org/zap"
+)
+
 func (s *Store) EnableEmpty() {
 	zlog.Info("discarding possible empty value on store implementation, not required for this store")
 }
+
+// Capabilities reports the `Insert`, `Increment`, `CompareAndSwap`, `CompareAndSwapTTL` and
+// `ReadOnly` capabilities of the store served by the server, fetched on the first call and kept
+// afterwards. Until the server could be reached, none of them are reported. Empty values are
+// always supported and `MultiPrefix` is served by the server through `store.MultiPrefix`,
+// whatever its store.
+func (s *Store) Capabilities() store.Capabilities {
+	capabilities := store.Capabilities{
+		EmptyValue:  true,
+		MultiPrefix: true,
+	}
+
+	served, err := s.servedCapabilities()
+	if err != nil {
+		zlog.Warn("unable to fetch the capabilities of the served store", zap.Error(err))
+		return capabilities
+	}
+
+	capabilities.Insert = served.Insert
+	capabilities.Increment = served.Increment
+	capabilities.CompareAndSwap = served.CompareAndSwap
+	capabilities.CompareAndSwapTTL = served.CompareAndSwapTtl
+	capabilities.ReadOnly = served.ReadOnly
+	return capabilities
+}
+
+// capabilitiesTimeout bounds the call fetching the capabilities of the served store
+const capabilitiesTimeout = 5 * time.Second
+
+func (s *Store) servedCapabilities() (*pbnetkv.CapabilitiesResponse, error) {
+	s.capabilitiesLock.Lock()
+	defer s.capabilitiesLock.Unlock()
+
+	if s.capabilities != nil {
+		return s.capabilities, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+	defer cancel()
+
+	capabilities, err := s.client.Capabilities(ctx, &pbnetkv.CapabilitiesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	s.capabilities = capabilities
+	return capabilities, nil
+}
@@ -25,7 +25,9 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type ReadOptions struct {
-	KeyOnly              bool     `protobuf:"varint,1,opt,name=key_only,json=keyOnly,proto3" json:"key_only,omitempty"`
+	KeyOnly bool `protobuf:"varint,1,opt,name=key_only,json=keyOnly,proto3" json:"key_only,omitempty"`
+	// raw returns the values as stored, without decompressing them.
+	Raw                  bool     `protobuf:"varint,2,opt,name=raw,proto3" json:"raw,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -63,6 +65,13 @@ func (m *ReadOptions) GetKeyOnly() bool {
 	return false
 }
 
+func (m *ReadOptions) GetRaw() bool {
+	if m != nil {
+		return m.Raw
+	}
+	return false
+}
+
 type KeyValue struct {
 	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -150,10 +159,12 @@ func (m *KeyValues) GetKvs() []*KeyValue {
 }
 
 type Keys struct {
-	Keys                 [][]byte `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Keys [][]byte `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	// Options only apply to BatchGet
+	Options              *ReadOptions `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *Keys) Reset()         { *m = Keys{} }
@@ -188,6 +199,13 @@ func (m *Keys) GetKeys() [][]byte {
 	return nil
 }
 
+func (m *Keys) GetOptions() *ReadOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
 type Values struct {
 	Values               [][]byte `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -345,6 +363,61 @@ func (m *BatchPrefixRequest) GetOptions() *ReadOptions {
 	return nil
 }
 
+type MultiPrefixRequest struct {
+	Prefixes             [][]byte     `protobuf:"bytes,1,rep,name=prefixes,proto3" json:"prefixes,omitempty"`
+	LimitEach            uint64       `protobuf:"varint,2,opt,name=limit_each,json=limitEach,proto3" json:"limit_each,omitempty"`
+	Options              *ReadOptions `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *MultiPrefixRequest) Reset()         { *m = MultiPrefixRequest{} }
+func (m *MultiPrefixRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiPrefixRequest) ProtoMessage()    {}
+func (*MultiPrefixRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{7}
+}
+
+func (m *MultiPrefixRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiPrefixRequest.Unmarshal(m, b)
+}
+func (m *MultiPrefixRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiPrefixRequest.Marshal(b, m, deterministic)
+}
+func (m *MultiPrefixRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiPrefixRequest.Merge(m, src)
+}
+func (m *MultiPrefixRequest) XXX_Size() int {
+	return xxx_messageInfo_MultiPrefixRequest.Size(m)
+}
+func (m *MultiPrefixRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiPrefixRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiPrefixRequest proto.InternalMessageInfo
+
+func (m *MultiPrefixRequest) GetPrefixes() [][]byte {
+	if m != nil {
+		return m.Prefixes
+	}
+	return nil
+}
+
+func (m *MultiPrefixRequest) GetLimitEach() uint64 {
+	if m != nil {
+		return m.LimitEach
+	}
+	return 0
+}
+
+func (m *MultiPrefixRequest) GetOptions() *ReadOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
 type BatchScanRequest struct {
 	Start                [][]byte `protobuf:"bytes,1,rep,name=start,proto3" json:"start,omitempty"`
 	ExclusiveEnd         [][]byte `protobuf:"bytes,2,rep,name=exclusive_end,json=exclusiveEnd,proto3" json:"exclusive_end,omitempty"`
@@ -358,7 +431,7 @@ func (m *BatchScanRequest) Reset()         { *m = BatchScanRequest{} }
 func (m *BatchScanRequest) String() string { return proto.CompactTextString(m) }
 func (*BatchScanRequest) ProtoMessage()    {}
 func (*BatchScanRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_25aabd6fb5784ada, []int{7}
+	return fileDescriptor_25aabd6fb5784ada, []int{8}
 }
 
 func (m *BatchScanRequest) XXX_Unmarshal(b []byte) error {
@@ -413,7 +486,7 @@ func (m *PrefixRequest) Reset()         { *m = PrefixRequest{} }
 func (m *PrefixRequest) String() string { return proto.CompactTextString(m) }
 func (*PrefixRequest) ProtoMessage()    {}
 func (*PrefixRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_25aabd6fb5784ada, []int{8}
+	return fileDescriptor_25aabd6fb5784ada, []int{9}
 }
 
 func (m *PrefixRequest) XXX_Unmarshal(b []byte) error {
@@ -448,43 +521,632 @@ func (m *PrefixRequest) GetLimit() uint64 {
 	return 0
 }
 
-func (m *PrefixRequest) GetOptions() *ReadOptions {
+func (m *PrefixRequest) GetOptions() *ReadOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type ValueSizeRequest struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValueSizeRequest) Reset()         { *m = ValueSizeRequest{} }
+func (m *ValueSizeRequest) String() string { return proto.CompactTextString(m) }
+func (*ValueSizeRequest) ProtoMessage()    {}
+func (*ValueSizeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{10}
+}
+
+func (m *ValueSizeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValueSizeRequest.Unmarshal(m, b)
+}
+func (m *ValueSizeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValueSizeRequest.Marshal(b, m, deterministic)
+}
+func (m *ValueSizeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValueSizeRequest.Merge(m, src)
+}
+func (m *ValueSizeRequest) XXX_Size() int {
+	return xxx_messageInfo_ValueSizeRequest.Size(m)
+}
+func (m *ValueSizeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValueSizeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValueSizeRequest proto.InternalMessageInfo
+
+func (m *ValueSizeRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type ValueSizeResponse struct {
+	Size                 uint64   `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValueSizeResponse) Reset()         { *m = ValueSizeResponse{} }
+func (m *ValueSizeResponse) String() string { return proto.CompactTextString(m) }
+func (*ValueSizeResponse) ProtoMessage()    {}
+func (*ValueSizeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{11}
+}
+
+func (m *ValueSizeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValueSizeResponse.Unmarshal(m, b)
+}
+func (m *ValueSizeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValueSizeResponse.Marshal(b, m, deterministic)
+}
+func (m *ValueSizeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValueSizeResponse.Merge(m, src)
+}
+func (m *ValueSizeResponse) XXX_Size() int {
+	return xxx_messageInfo_ValueSizeResponse.Size(m)
+}
+func (m *ValueSizeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValueSizeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValueSizeResponse proto.InternalMessageInfo
+
+func (m *ValueSizeResponse) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type ExistsRequest struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExistsRequest) Reset()         { *m = ExistsRequest{} }
+func (m *ExistsRequest) String() string { return proto.CompactTextString(m) }
+func (*ExistsRequest) ProtoMessage()    {}
+func (*ExistsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{12}
+}
+
+func (m *ExistsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExistsRequest.Unmarshal(m, b)
+}
+func (m *ExistsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExistsRequest.Marshal(b, m, deterministic)
+}
+func (m *ExistsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExistsRequest.Merge(m, src)
+}
+func (m *ExistsRequest) XXX_Size() int {
+	return xxx_messageInfo_ExistsRequest.Size(m)
+}
+func (m *ExistsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExistsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExistsRequest proto.InternalMessageInfo
+
+func (m *ExistsRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type ExistsResponse struct {
+	Exists               bool     `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExistsResponse) Reset()         { *m = ExistsResponse{} }
+func (m *ExistsResponse) String() string { return proto.CompactTextString(m) }
+func (*ExistsResponse) ProtoMessage()    {}
+func (*ExistsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{13}
+}
+
+func (m *ExistsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExistsResponse.Unmarshal(m, b)
+}
+func (m *ExistsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExistsResponse.Marshal(b, m, deterministic)
+}
+func (m *ExistsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExistsResponse.Merge(m, src)
+}
+func (m *ExistsResponse) XXX_Size() int {
+	return xxx_messageInfo_ExistsResponse.Size(m)
+}
+func (m *ExistsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExistsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExistsResponse proto.InternalMessageInfo
+
+func (m *ExistsResponse) GetExists() bool {
+	if m != nil {
+		return m.Exists
+	}
+	return false
+}
+
+type BatchExistsResponse struct {
+	Exists               []bool   `protobuf:"varint,1,rep,packed,name=exists,proto3" json:"exists,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchExistsResponse) Reset()         { *m = BatchExistsResponse{} }
+func (m *BatchExistsResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchExistsResponse) ProtoMessage()    {}
+func (*BatchExistsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{14}
+}
+
+func (m *BatchExistsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchExistsResponse.Unmarshal(m, b)
+}
+func (m *BatchExistsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchExistsResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchExistsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchExistsResponse.Merge(m, src)
+}
+func (m *BatchExistsResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchExistsResponse.Size(m)
+}
+func (m *BatchExistsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchExistsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchExistsResponse proto.InternalMessageInfo
+
+func (m *BatchExistsResponse) GetExists() []bool {
+	if m != nil {
+		return m.Exists
+	}
+	return nil
+}
+
+type DeleteRequest struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{15}
+}
+
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type IncrementRequest struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta                int64    `protobuf:"varint,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IncrementRequest) Reset()         { *m = IncrementRequest{} }
+func (m *IncrementRequest) String() string { return proto.CompactTextString(m) }
+func (*IncrementRequest) ProtoMessage()    {}
+func (*IncrementRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{16}
+}
+
+func (m *IncrementRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementRequest.Unmarshal(m, b)
+}
+func (m *IncrementRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementRequest.Marshal(b, m, deterministic)
+}
+func (m *IncrementRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementRequest.Merge(m, src)
+}
+func (m *IncrementRequest) XXX_Size() int {
+	return xxx_messageInfo_IncrementRequest.Size(m)
+}
+func (m *IncrementRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementRequest proto.InternalMessageInfo
+
+func (m *IncrementRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *IncrementRequest) GetDelta() int64 {
+	if m != nil {
+		return m.Delta
+	}
+	return 0
+}
+
+type IncrementResponse struct {
+	Value                int64    `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IncrementResponse) Reset()         { *m = IncrementResponse{} }
+func (m *IncrementResponse) String() string { return proto.CompactTextString(m) }
+func (*IncrementResponse) ProtoMessage()    {}
+func (*IncrementResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{17}
+}
+
+func (m *IncrementResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementResponse.Unmarshal(m, b)
+}
+func (m *IncrementResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementResponse.Marshal(b, m, deterministic)
+}
+func (m *IncrementResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementResponse.Merge(m, src)
+}
+func (m *IncrementResponse) XXX_Size() int {
+	return xxx_messageInfo_IncrementResponse.Size(m)
+}
+func (m *IncrementResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementResponse proto.InternalMessageInfo
+
+func (m *IncrementResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type CompareAndSwapRequest struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	OldValue             []byte   `protobuf:"bytes,2,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	OldAbsent            bool     `protobuf:"varint,3,opt,name=old_absent,json=oldAbsent,proto3" json:"old_absent,omitempty"`
+	NewValue             []byte   `protobuf:"bytes,4,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	Delete               bool     `protobuf:"varint,5,opt,name=delete,proto3" json:"delete,omitempty"`
+	TtlMs                uint64   `protobuf:"varint,6,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompareAndSwapRequest) Reset()         { *m = CompareAndSwapRequest{} }
+func (m *CompareAndSwapRequest) String() string { return proto.CompactTextString(m) }
+func (*CompareAndSwapRequest) ProtoMessage()    {}
+func (*CompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{18}
+}
+
+func (m *CompareAndSwapRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareAndSwapRequest.Unmarshal(m, b)
+}
+func (m *CompareAndSwapRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareAndSwapRequest.Marshal(b, m, deterministic)
+}
+func (m *CompareAndSwapRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareAndSwapRequest.Merge(m, src)
+}
+func (m *CompareAndSwapRequest) XXX_Size() int {
+	return xxx_messageInfo_CompareAndSwapRequest.Size(m)
+}
+func (m *CompareAndSwapRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareAndSwapRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompareAndSwapRequest proto.InternalMessageInfo
+
+func (m *CompareAndSwapRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *CompareAndSwapRequest) GetOldValue() []byte {
+	if m != nil {
+		return m.OldValue
+	}
+	return nil
+}
+
+func (m *CompareAndSwapRequest) GetOldAbsent() bool {
+	if m != nil {
+		return m.OldAbsent
+	}
+	return false
+}
+
+func (m *CompareAndSwapRequest) GetNewValue() []byte {
+	if m != nil {
+		return m.NewValue
+	}
+	return nil
+}
+
+func (m *CompareAndSwapRequest) GetDelete() bool {
+	if m != nil {
+		return m.Delete
+	}
+	return false
+}
+
+func (m *CompareAndSwapRequest) GetTtlMs() uint64 {
+	if m != nil {
+		return m.TtlMs
+	}
+	return 0
+}
+
+type CompareAndSwapResponse struct {
+	Swapped              bool     `protobuf:"varint,1,opt,name=swapped,proto3" json:"swapped,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompareAndSwapResponse) Reset()         { *m = CompareAndSwapResponse{} }
+func (m *CompareAndSwapResponse) String() string { return proto.CompactTextString(m) }
+func (*CompareAndSwapResponse) ProtoMessage()    {}
+func (*CompareAndSwapResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{19}
+}
+
+func (m *CompareAndSwapResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareAndSwapResponse.Unmarshal(m, b)
+}
+func (m *CompareAndSwapResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareAndSwapResponse.Marshal(b, m, deterministic)
+}
+func (m *CompareAndSwapResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareAndSwapResponse.Merge(m, src)
+}
+func (m *CompareAndSwapResponse) XXX_Size() int {
+	return xxx_messageInfo_CompareAndSwapResponse.Size(m)
+}
+func (m *CompareAndSwapResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareAndSwapResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompareAndSwapResponse proto.InternalMessageInfo
+
+func (m *CompareAndSwapResponse) GetSwapped() bool {
+	if m != nil {
+		return m.Swapped
+	}
+	return false
+}
+
+type EmptyResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EmptyResponse) Reset()         { *m = EmptyResponse{} }
+func (m *EmptyResponse) String() string { return proto.CompactTextString(m) }
+func (*EmptyResponse) ProtoMessage()    {}
+func (*EmptyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{20}
+}
+
+func (m *EmptyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EmptyResponse.Unmarshal(m, b)
+}
+func (m *EmptyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EmptyResponse.Marshal(b, m, deterministic)
+}
+func (m *EmptyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EmptyResponse.Merge(m, src)
+}
+func (m *EmptyResponse) XXX_Size() int {
+	return xxx_messageInfo_EmptyResponse.Size(m)
+}
+func (m *EmptyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EmptyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EmptyResponse proto.InternalMessageInfo
+
+type CapabilitiesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapabilitiesRequest) Reset()         { *m = CapabilitiesRequest{} }
+func (m *CapabilitiesRequest) String() string { return proto.CompactTextString(m) }
+func (*CapabilitiesRequest) ProtoMessage()    {}
+func (*CapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{21}
+}
+
+func (m *CapabilitiesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapabilitiesRequest.Unmarshal(m, b)
+}
+func (m *CapabilitiesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapabilitiesRequest.Marshal(b, m, deterministic)
+}
+func (m *CapabilitiesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapabilitiesRequest.Merge(m, src)
+}
+func (m *CapabilitiesRequest) XXX_Size() int {
+	return xxx_messageInfo_CapabilitiesRequest.Size(m)
+}
+func (m *CapabilitiesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapabilitiesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CapabilitiesRequest proto.InternalMessageInfo
+
+type CapabilitiesResponse struct {
+	Insert               bool     `protobuf:"varint,1,opt,name=insert,proto3" json:"insert,omitempty"`
+	Increment            bool     `protobuf:"varint,2,opt,name=increment,proto3" json:"increment,omitempty"`
+	ReadOnly             bool     `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	CompareAndSwap       bool     `protobuf:"varint,4,opt,name=compare_and_swap,json=compareAndSwap,proto3" json:"compare_and_swap,omitempty"`
+	CompareAndSwapTtl    bool     `protobuf:"varint,5,opt,name=compare_and_swap_ttl,json=compareAndSwapTtl,proto3" json:"compare_and_swap_ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapabilitiesResponse) Reset()         { *m = CapabilitiesResponse{} }
+func (m *CapabilitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*CapabilitiesResponse) ProtoMessage()    {}
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{22}
+}
+
+func (m *CapabilitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapabilitiesResponse.Unmarshal(m, b)
+}
+func (m *CapabilitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapabilitiesResponse.Marshal(b, m, deterministic)
+}
+func (m *CapabilitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapabilitiesResponse.Merge(m, src)
+}
+func (m *CapabilitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_CapabilitiesResponse.Size(m)
+}
+func (m *CapabilitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapabilitiesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CapabilitiesResponse proto.InternalMessageInfo
+
+func (m *CapabilitiesResponse) GetInsert() bool {
+	if m != nil {
+		return m.Insert
+	}
+	return false
+}
+
+func (m *CapabilitiesResponse) GetIncrement() bool {
+	if m != nil {
+		return m.Increment
+	}
+	return false
+}
+
+func (m *CapabilitiesResponse) GetReadOnly() bool {
+	if m != nil {
+		return m.ReadOnly
+	}
+	return false
+}
+
+func (m *CapabilitiesResponse) GetCompareAndSwap() bool {
+	if m != nil {
+		return m.CompareAndSwap
+	}
+	return false
+}
+
+func (m *CapabilitiesResponse) GetCompareAndSwapTtl() bool {
 	if m != nil {
-		return m.Options
+		return m.CompareAndSwapTtl
 	}
-	return nil
+	return false
 }
 
-type EmptyResponse struct {
+// ValueTooLarge is attached to the `RESOURCE_EXHAUSTED` status of writes
+// rejected because a value exceeds the maximum size the server accepts.
+type ValueTooLarge struct {
+	Size                 uint64   `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	MaxSize              uint64   `protobuf:"varint,2,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *EmptyResponse) Reset()         { *m = EmptyResponse{} }
-func (m *EmptyResponse) String() string { return proto.CompactTextString(m) }
-func (*EmptyResponse) ProtoMessage()    {}
-func (*EmptyResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_25aabd6fb5784ada, []int{9}
+func (m *ValueTooLarge) Reset()         { *m = ValueTooLarge{} }
+func (m *ValueTooLarge) String() string { return proto.CompactTextString(m) }
+func (*ValueTooLarge) ProtoMessage()    {}
+func (*ValueTooLarge) Descriptor() ([]byte, []int) {
+	return fileDescriptor_25aabd6fb5784ada, []int{23}
 }
 
-func (m *EmptyResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_EmptyResponse.Unmarshal(m, b)
+func (m *ValueTooLarge) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValueTooLarge.Unmarshal(m, b)
 }
-func (m *EmptyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_EmptyResponse.Marshal(b, m, deterministic)
+func (m *ValueTooLarge) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValueTooLarge.Marshal(b, m, deterministic)
 }
-func (m *EmptyResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_EmptyResponse.Merge(m, src)
+func (m *ValueTooLarge) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValueTooLarge.Merge(m, src)
 }
-func (m *EmptyResponse) XXX_Size() int {
-	return xxx_messageInfo_EmptyResponse.Size(m)
+func (m *ValueTooLarge) XXX_Size() int {
+	return xxx_messageInfo_ValueTooLarge.Size(m)
 }
-func (m *EmptyResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_EmptyResponse.DiscardUnknown(m)
+func (m *ValueTooLarge) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValueTooLarge.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_EmptyResponse proto.InternalMessageInfo
+var xxx_messageInfo_ValueTooLarge proto.InternalMessageInfo
+
+func (m *ValueTooLarge) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ValueTooLarge) GetMaxSize() uint64 {
+	if m != nil {
+		return m.MaxSize
+	}
+	return 0
+}
 
 func init() {
 	proto.RegisterType((*ReadOptions)(nil), "dfuse.netkv.v1.ReadOptions")
@@ -494,47 +1156,93 @@ func init() {
 	proto.RegisterType((*Values)(nil), "dfuse.netkv.v1.Values")
 	proto.RegisterType((*ScanRequest)(nil), "dfuse.netkv.v1.ScanRequest")
 	proto.RegisterType((*BatchPrefixRequest)(nil), "dfuse.netkv.v1.BatchPrefixRequest")
+	proto.RegisterType((*MultiPrefixRequest)(nil), "dfuse.netkv.v1.MultiPrefixRequest")
 	proto.RegisterType((*BatchScanRequest)(nil), "dfuse.netkv.v1.BatchScanRequest")
 	proto.RegisterType((*PrefixRequest)(nil), "dfuse.netkv.v1.PrefixRequest")
+	proto.RegisterType((*ValueSizeRequest)(nil), "dfuse.netkv.v1.ValueSizeRequest")
+	proto.RegisterType((*ValueSizeResponse)(nil), "dfuse.netkv.v1.ValueSizeResponse")
+	proto.RegisterType((*ExistsRequest)(nil), "dfuse.netkv.v1.ExistsRequest")
+	proto.RegisterType((*ExistsResponse)(nil), "dfuse.netkv.v1.ExistsResponse")
+	proto.RegisterType((*BatchExistsResponse)(nil), "dfuse.netkv.v1.BatchExistsResponse")
+	proto.RegisterType((*DeleteRequest)(nil), "dfuse.netkv.v1.DeleteRequest")
+	proto.RegisterType((*IncrementRequest)(nil), "dfuse.netkv.v1.IncrementRequest")
+	proto.RegisterType((*IncrementResponse)(nil), "dfuse.netkv.v1.IncrementResponse")
+	proto.RegisterType((*CompareAndSwapRequest)(nil), "dfuse.netkv.v1.CompareAndSwapRequest")
+	proto.RegisterType((*CompareAndSwapResponse)(nil), "dfuse.netkv.v1.CompareAndSwapResponse")
 	proto.RegisterType((*EmptyResponse)(nil), "dfuse.netkv.v1.EmptyResponse")
+	proto.RegisterType((*CapabilitiesRequest)(nil), "dfuse.netkv.v1.CapabilitiesRequest")
+	proto.RegisterType((*CapabilitiesResponse)(nil), "dfuse.netkv.v1.CapabilitiesResponse")
+	proto.RegisterType((*ValueTooLarge)(nil), "dfuse.netkv.v1.ValueTooLarge")
 }
 
 func init() { proto.RegisterFile("netkv.proto", fileDescriptor_25aabd6fb5784ada) }
 
 var fileDescriptor_25aabd6fb5784ada = []byte{
-	// 512 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x4d, 0x6b, 0xdb, 0x40,
-	0x10, 0x45, 0x91, 0x22, 0xcb, 0x23, 0xdb, 0x35, 0x4b, 0x08, 0x8a, 0x4b, 0x41, 0xa8, 0x3d, 0x88,
-	0x1e, 0x4c, 0xeb, 0x52, 0x7a, 0x29, 0x14, 0xdc, 0x84, 0x52, 0x4c, 0x9b, 0xb0, 0x85, 0x1c, 0x7a,
-	0x31, 0x8a, 0x3d, 0xa1, 0x42, 0x8a, 0xa4, 0x6a, 0x57, 0x22, 0xfa, 0x19, 0x3d, 0xf4, 0x77, 0xf6,
-	0x2f, 0x14, 0xed, 0xae, 0x1c, 0x7f, 0x44, 0x6e, 0x7d, 0xd3, 0xcc, 0xbc, 0x7d, 0xfb, 0xde, 0x9b,
-	0x45, 0x60, 0x27, 0xc8, 0xa3, 0x72, 0x9c, 0xe5, 0x29, 0x4f, 0xc9, 0x60, 0x79, 0x5b, 0x30, 0x1c,
-	0xcb, 0x56, 0xf9, 0xda, 0xf3, 0xc1, 0xa6, 0x18, 0x2c, 0x2f, 0x33, 0x1e, 0xa6, 0x09, 0x23, 0x67,
-	0x60, 0x45, 0x58, 0xcd, 0xd3, 0x24, 0xae, 0x1c, 0xcd, 0xd5, 0x7c, 0x8b, 0x76, 0x22, 0xac, 0x2e,
-	0x93, 0xb8, 0xf2, 0x26, 0x60, 0xcd, 0xb0, 0xba, 0x0e, 0xe2, 0x02, 0xc9, 0x10, 0xf4, 0x08, 0x25,
-	0xa2, 0x47, 0xeb, 0x4f, 0x72, 0x02, 0xc7, 0x65, 0x3d, 0x72, 0x8e, 0x44, 0x4f, 0x16, 0xde, 0x3b,
-	0xe8, 0x36, 0x67, 0x18, 0x79, 0x09, 0x7a, 0x54, 0x32, 0x47, 0x73, 0x75, 0xdf, 0x9e, 0x38, 0xe3,
-	0x4d, 0x21, 0xe3, 0x06, 0x47, 0x6b, 0x90, 0x37, 0x02, 0x63, 0x86, 0x15, 0x23, 0x04, 0x8c, 0x08,
-	0x2b, 0x79, 0xa8, 0x47, 0xc5, 0xb7, 0xe7, 0x82, 0xa9, 0x18, 0x4f, 0xc1, 0x14, 0xf7, 0x34, 0x73,
-	0x55, 0x79, 0xbf, 0x35, 0xb0, 0xbf, 0x2d, 0x82, 0x84, 0xe2, 0xcf, 0x02, 0x19, 0xaf, 0xc5, 0x31,
-	0x1e, 0xe4, 0x5c, 0x09, 0x96, 0x05, 0x79, 0x0e, 0x7d, 0xbc, 0x5f, 0xc4, 0x05, 0x0b, 0x4b, 0x9c,
-	0x63, 0xb2, 0x54, 0xd2, 0x7b, 0xab, 0xe6, 0x45, 0xb2, 0xac, 0x8f, 0xc6, 0xe1, 0x5d, 0xc8, 0x1d,
-	0xdd, 0xd5, 0x7c, 0x83, 0xca, 0x82, 0xbc, 0x85, 0x4e, 0x2a, 0x13, 0x73, 0x0c, 0x57, 0xf3, 0xed,
-	0xc9, 0xd3, 0x6d, 0x3b, 0x6b, 0xa1, 0xd2, 0x06, 0xeb, 0xfd, 0xd2, 0x80, 0x4c, 0x03, 0xbe, 0xf8,
-	0x71, 0x95, 0xe3, 0x6d, 0x78, 0xdf, 0xc8, 0x1b, 0x81, 0x95, 0x89, 0xc6, 0xca, 0xc8, 0xaa, 0x26,
-	0x3e, 0x0c, 0xc5, 0x95, 0xf3, 0x0c, 0xf3, 0xb9, 0xec, 0x0a, 0x9d, 0x06, 0x1d, 0x88, 0xfe, 0x15,
-	0xe6, 0x92, 0x6c, 0x5d, 0x93, 0x7e, 0x80, 0x26, 0x06, 0x43, 0x21, 0xa9, 0x25, 0x2f, 0x7d, 0x6f,
-	0x5e, 0xfa, 0x4e, 0x5e, 0x2f, 0x60, 0xf0, 0xa0, 0x97, 0x2d, 0x82, 0x44, 0x05, 0xd7, 0x6b, 0xd4,
-	0xd6, 0xf7, 0x78, 0x1c, 0xfa, 0x9b, 0x11, 0x9c, 0x82, 0xa9, 0xcc, 0xc9, 0x15, 0xa9, 0xea, 0x21,
-	0xfe, 0xa3, 0x96, 0xf8, 0x0f, 0xb1, 0xfa, 0x04, 0xfa, 0x17, 0x77, 0x19, 0xaf, 0x28, 0xb2, 0x2c,
-	0x4d, 0x18, 0x4e, 0xfe, 0xe8, 0x70, 0xfc, 0x15, 0xf9, 0xec, 0x9a, 0x9c, 0x83, 0x25, 0x17, 0x53,
-	0x70, 0x72, 0xd6, 0xf6, 0x34, 0xd9, 0xe8, 0xd9, 0xf6, 0x68, 0x83, 0x8f, 0xbc, 0x57, 0x2c, 0x9f,
-	0x90, 0x93, 0x93, 0x47, 0x58, 0xd8, 0xa8, 0xf5, 0xd9, 0xbf, 0xd2, 0xc8, 0x07, 0x30, 0xea, 0x70,
-	0xc8, 0x8e, 0x99, 0xb5, 0xd5, 0xec, 0x25, 0xf8, 0x0c, 0xdd, 0xd5, 0x2a, 0x89, 0xbb, 0x0d, 0xdc,
-	0xde, 0xf2, 0x5e, 0xaa, 0x29, 0xd8, 0x02, 0x7f, 0x8e, 0x31, 0x72, 0x6c, 0x31, 0xf3, 0x8f, 0x34,
-	0x3e, 0x82, 0xa9, 0x9e, 0xe6, 0x0e, 0x70, 0x63, 0xf9, 0x7b, 0x85, 0x7c, 0x51, 0x42, 0x14, 0x93,
-	0xf7, 0xa8, 0xab, 0xff, 0xa6, 0x9b, 0x76, 0xbf, 0x77, 0xb2, 0x1b, 0x31, 0xb8, 0x31, 0xc5, 0x0f,
-	0xf1, 0xcd, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x20, 0x4f, 0x5f, 0x52, 0x1f, 0x05, 0x00, 0x00,
+	// 1014 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0x5b, 0x6f, 0xe3, 0x44,
+	0x14, 0x96, 0x37, 0xa9, 0xe3, 0x9c, 0x5c, 0x48, 0x67, 0xdb, 0x28, 0x9b, 0xa5, 0x28, 0xf5, 0x16,
+	0x08, 0x48, 0x14, 0x08, 0x42, 0x48, 0x2b, 0x04, 0x6a, 0xbb, 0x65, 0xa9, 0x4a, 0xd9, 0xe2, 0xae,
+	0x56, 0x82, 0x17, 0x6b, 0x1a, 0xcf, 0x52, 0x2b, 0x8e, 0x6d, 0x3c, 0x93, 0x5b, 0x7f, 0x00, 0xef,
+	0x3c, 0xf0, 0x43, 0x78, 0xe7, 0x91, 0x1f, 0x86, 0xe6, 0xe2, 0xd4, 0x97, 0xd8, 0xa5, 0xbc, 0xf9,
+	0x9c, 0xf9, 0xce, 0x99, 0xf3, 0x7d, 0x33, 0x73, 0x4e, 0x02, 0x0d, 0x9f, 0xb0, 0xc9, 0xfc, 0x30,
+	0x8c, 0x02, 0x16, 0xa0, 0xb6, 0xf3, 0x76, 0x46, 0xc9, 0xa1, 0x74, 0xcd, 0x3f, 0x37, 0x9f, 0x43,
+	0xc3, 0x22, 0xd8, 0x79, 0x15, 0x32, 0x37, 0xf0, 0x29, 0x7a, 0x02, 0xc6, 0x84, 0xac, 0xec, 0xc0,
+	0xf7, 0x56, 0x3d, 0x6d, 0xa0, 0x0d, 0x0d, 0xab, 0x36, 0x21, 0xab, 0x57, 0xbe, 0xb7, 0x42, 0x1d,
+	0xa8, 0x44, 0x78, 0xd1, 0x7b, 0x24, 0xbc, 0xfc, 0xd3, 0x1c, 0x81, 0x71, 0x4e, 0x56, 0x6f, 0xb0,
+	0x37, 0x23, 0x7c, 0x75, 0x42, 0x64, 0x4c, 0xd3, 0xe2, 0x9f, 0x68, 0x07, 0xb6, 0xe6, 0x7c, 0x49,
+	0x44, 0x34, 0x2d, 0x69, 0x98, 0x5f, 0x41, 0x3d, 0x8e, 0xa1, 0xe8, 0x63, 0xa8, 0x4c, 0xe6, 0xb4,
+	0xa7, 0x0d, 0x2a, 0xc3, 0xc6, 0xa8, 0x77, 0x98, 0x2e, 0xed, 0x30, 0xc6, 0x59, 0x1c, 0x64, 0xfe,
+	0x04, 0xd5, 0x73, 0xb2, 0xa2, 0x08, 0x41, 0x75, 0x42, 0x56, 0x32, 0xa8, 0x69, 0x89, 0x6f, 0xf4,
+	0x25, 0xd4, 0x02, 0x49, 0x40, 0x6c, 0xd6, 0x18, 0x3d, 0xcd, 0xe6, 0x4a, 0x70, 0xb4, 0x62, 0xac,
+	0x39, 0x00, 0x5d, 0x15, 0xd2, 0x05, 0x5d, 0x94, 0x17, 0xa7, 0x55, 0x96, 0xf9, 0xa7, 0x06, 0x8d,
+	0xab, 0x31, 0xf6, 0x2d, 0xf2, 0xdb, 0x8c, 0x50, 0xc6, 0x39, 0x51, 0x86, 0x23, 0xa6, 0x78, 0x4a,
+	0x03, 0x3d, 0x83, 0x16, 0x59, 0x8e, 0xbd, 0x19, 0x75, 0xe7, 0xc4, 0x26, 0xbe, 0xa3, 0x18, 0x37,
+	0xd7, 0xce, 0x53, 0xdf, 0xe1, 0xa1, 0x9e, 0x3b, 0x75, 0x59, 0xaf, 0x32, 0xd0, 0x86, 0x55, 0x4b,
+	0x1a, 0xc9, 0xca, 0xab, 0x0f, 0xa8, 0xfc, 0x0f, 0x0d, 0xd0, 0x31, 0x66, 0xe3, 0x9b, 0xcb, 0x88,
+	0xbc, 0x75, 0x97, 0x71, 0x79, 0x7d, 0x30, 0x42, 0xe1, 0x58, 0x13, 0x59, 0xdb, 0x68, 0x08, 0x1d,
+	0xb1, 0xa5, 0x1d, 0x92, 0xc8, 0x96, 0x5e, 0x51, 0x67, 0xd5, 0x6a, 0x0b, 0xff, 0x25, 0x89, 0x64,
+	0xb2, 0x64, 0x4d, 0x95, 0x07, 0xd4, 0xf4, 0xbb, 0x06, 0xe8, 0x62, 0xe6, 0x31, 0xf7, 0xbf, 0xd7,
+	0xb4, 0x07, 0x20, 0x6b, 0x22, 0x78, 0x7c, 0xa3, 0xaa, 0xa9, 0x0b, 0xcf, 0x29, 0x1e, 0xdf, 0xfc,
+	0xdf, 0x42, 0x28, 0x74, 0x84, 0x36, 0x05, 0x07, 0x57, 0x29, 0x3d, 0xb8, 0x4a, 0xee, 0xe0, 0x0e,
+	0xa0, 0x7d, 0x27, 0x1c, 0x1d, 0x63, 0x5f, 0x9d, 0x60, 0x33, 0x96, 0x8d, 0xef, 0x63, 0x32, 0x68,
+	0xa5, 0x79, 0x77, 0x41, 0x57, 0x2a, 0xcb, 0xbb, 0xa2, 0xac, 0xbb, 0x7b, 0xf0, 0xa8, 0xe0, 0x1e,
+	0x3c, 0x84, 0xea, 0x01, 0x74, 0xc4, 0x0d, 0xbe, 0x72, 0x6f, 0x49, 0xbc, 0x71, 0xee, 0x25, 0x9a,
+	0x1f, 0xc2, 0x76, 0x02, 0x45, 0xc3, 0xc0, 0xa7, 0x84, 0xbf, 0x23, 0xea, 0xde, 0x12, 0x81, 0xab,
+	0x5a, 0xe2, 0xdb, 0xdc, 0x87, 0xd6, 0xe9, 0xd2, 0xa5, 0x8c, 0x16, 0xe7, 0x1a, 0x42, 0x3b, 0x86,
+	0xa8, 0x44, 0x5d, 0xd0, 0x89, 0xf0, 0xa8, 0x86, 0xa1, 0x2c, 0xf3, 0x13, 0x78, 0x2c, 0x8e, 0xa1,
+	0x04, 0x5e, 0x49, 0xc0, 0xf7, 0xa1, 0xf5, 0x82, 0x78, 0x84, 0x95, 0xf0, 0x78, 0x0e, 0x9d, 0x33,
+	0x7f, 0x1c, 0x91, 0x29, 0xf1, 0x59, 0x21, 0x8a, 0x0b, 0xec, 0x10, 0x8f, 0x61, 0x21, 0x70, 0xc5,
+	0x92, 0x86, 0xf9, 0x11, 0x6c, 0x27, 0x62, 0x55, 0x2d, 0xeb, 0x16, 0xa5, 0x49, 0xa8, 0x30, 0xcc,
+	0xbf, 0x34, 0xd8, 0x3d, 0x09, 0xa6, 0x21, 0x8e, 0xc8, 0x91, 0xef, 0x5c, 0x2d, 0x70, 0x58, 0xbc,
+	0xd9, 0x53, 0xa8, 0x07, 0x9e, 0x63, 0x27, 0x1b, 0x9d, 0x11, 0x78, 0x8e, 0xec, 0x89, 0x7b, 0x00,
+	0x7c, 0x11, 0x5f, 0x53, 0xe2, 0xcb, 0x77, 0x6f, 0x58, 0x1c, 0x7e, 0x24, 0x1c, 0x3c, 0xd6, 0x27,
+	0x0b, 0x15, 0x5b, 0x95, 0xb1, 0x3e, 0x59, 0xc8, 0xd8, 0x2e, 0xe8, 0x8e, 0x90, 0xa3, 0xb7, 0x25,
+	0x55, 0x95, 0x16, 0xda, 0x05, 0x9d, 0x31, 0xcf, 0x9e, 0xd2, 0x9e, 0x2e, 0xef, 0x0f, 0x63, 0xde,
+	0x05, 0x35, 0x47, 0xd0, 0xcd, 0x96, 0xac, 0x38, 0xf6, 0xa0, 0x46, 0x17, 0x38, 0x0c, 0x89, 0x13,
+	0x37, 0x74, 0x65, 0x9a, 0xef, 0x40, 0xeb, 0x74, 0x1a, 0xb2, 0x55, 0x0c, 0x35, 0x77, 0xe1, 0xf1,
+	0x09, 0x0e, 0xf1, 0xb5, 0xeb, 0xb9, 0xcc, 0x25, 0xf1, 0x25, 0x30, 0xff, 0xd1, 0x60, 0x27, 0xed,
+	0xbf, 0x3b, 0x4a, 0xd7, 0xa7, 0x44, 0xb5, 0x43, 0xc3, 0x52, 0x16, 0x7a, 0x17, 0xea, 0x6e, 0xac,
+	0xb5, 0x9a, 0x17, 0x77, 0x0e, 0x4e, 0x3b, 0x22, 0xd8, 0x91, 0x33, 0x46, 0x8a, 0x62, 0x70, 0x87,
+	0x18, 0x32, 0x43, 0xe8, 0x8c, 0x25, 0x0f, 0x1b, 0xfb, 0x8e, 0xcd, 0x4b, 0x15, 0xd2, 0x18, 0x56,
+	0x7b, 0x9c, 0xe2, 0x87, 0x3e, 0x85, 0x9d, 0x2c, 0xd2, 0x66, 0xcc, 0x53, 0x72, 0x6d, 0xa7, 0xd1,
+	0xaf, 0x99, 0x67, 0x7e, 0x03, 0x2d, 0x21, 0xed, 0xeb, 0x20, 0xf8, 0x01, 0x47, 0xbf, 0x6e, 0x7c,
+	0x01, 0x7c, 0xfe, 0x4d, 0xf1, 0xd2, 0x16, 0x7e, 0xf9, 0x40, 0x6b, 0x53, 0xbc, 0xe4, 0x0f, 0x67,
+	0xf4, 0x77, 0x1d, 0xb6, 0x7e, 0x24, 0xec, 0xfc, 0x0d, 0x7a, 0x01, 0x86, 0x6c, 0xbe, 0x33, 0x86,
+	0x9e, 0x14, 0x4d, 0x2d, 0xda, 0xdf, 0xcb, 0x2e, 0xa5, 0xd4, 0x46, 0x47, 0xa0, 0x9f, 0x49, 0xbd,
+	0x0a, 0x27, 0xdf, 0x7d, 0x29, 0x2e, 0xa1, 0xbe, 0xbe, 0xd4, 0x68, 0x90, 0xc5, 0x66, 0xdf, 0x4a,
+	0x7f, 0xbf, 0x04, 0xa1, 0x32, 0xda, 0xd0, 0x4e, 0xdf, 0x23, 0xf4, 0x7e, 0x36, 0x68, 0xe3, 0xd3,
+	0xe8, 0x7f, 0x70, 0x1f, 0x4c, 0x6d, 0xf0, 0xb5, 0xd2, 0xee, 0x25, 0x61, 0x68, 0x67, 0x03, 0x6f,
+	0xda, 0x2f, 0x54, 0xe3, 0x33, 0x8d, 0x13, 0x5e, 0x77, 0xb2, 0x3c, 0xe1, 0x6c, 0x2b, 0xec, 0xef,
+	0x97, 0x20, 0x54, 0x3d, 0x2f, 0x41, 0x97, 0x0d, 0x0a, 0xe5, 0xb5, 0x4e, 0xb6, 0xc2, 0xfe, 0x7b,
+	0x45, 0xcb, 0x2a, 0xd1, 0xf7, 0xd0, 0x48, 0xb4, 0xbb, 0x02, 0x6e, 0xcf, 0xb2, 0xde, 0x4d, 0x1d,
+	0xf2, 0x5b, 0xa8, 0xf2, 0x91, 0x82, 0x72, 0x23, 0x20, 0x31, 0xd0, 0x4a, 0x55, 0x3a, 0x83, 0xfa,
+	0x7a, 0x00, 0xe6, 0x55, 0xca, 0xce, 0xc6, 0xd2, 0x54, 0xc7, 0x8a, 0x95, 0x6c, 0xcd, 0x05, 0xac,
+	0xee, 0xb9, 0xa5, 0xdf, 0x81, 0xae, 0xc2, 0x73, 0xc0, 0x54, 0xc7, 0xbf, 0x2f, 0xcf, 0x09, 0xe8,
+	0xea, 0x17, 0x4a, 0x0e, 0x98, 0x1a, 0xbd, 0xa5, 0x84, 0x2e, 0x14, 0x21, 0x95, 0xc9, 0xdc, 0xa8,
+	0xce, 0x83, 0xd2, 0x25, 0x7e, 0xf3, 0xe4, 0xd3, 0xe5, 0x7f, 0x10, 0x95, 0xa6, 0xfb, 0x19, 0x9a,
+	0xc9, 0x4e, 0x8b, 0x72, 0xf7, 0x65, 0x43, 0x7f, 0xee, 0x1f, 0x94, 0x83, 0xa4, 0x7a, 0xc7, 0xf5,
+	0x5f, 0x6a, 0xe1, 0xb5, 0x80, 0x5c, 0xeb, 0xe2, 0xaf, 0xc0, 0x17, 0xff, 0x0e, 0x00, 0xea, 0x14,
+	0xf3, 0x6b, 0x19, 0x0c, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -550,16 +1258,38 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type NetKVClient interface {
 	BatchPut(ctx context.Context, in *KeyValues, opts ...grpc.CallOption) (*EmptyResponse, error)
+	// Insert writes the key only if it does not exist yet, failing with
+	// `ALREADY_EXISTS` otherwise.
+	Insert(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*EmptyResponse, error)
+	// Increment atomically adds `delta` to the big-endian int64 counter stored
+	// at `key`, a missing key starting at zero.
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	// CompareAndSwap atomically replaces the value of `key` only when it is still
+	// `old_value`, or when it does not exist and `old_absent` is set. The key is
+	// deleted instead when `delete` is set. A non-zero `ttl_ms` makes the new
+	// value expire after that many milliseconds, on the clock of the server.
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error)
 	// TODO: we need to be able to get individual responses
 	// regarding Not-Foundness of each key, otherwise, we'll have
 	// a hard time knowing which key was not found, etc..
 	// This will happen more with sparse trxdb.
 	BatchGet(ctx context.Context, in *Keys, opts ...grpc.CallOption) (NetKV_BatchGetClient, error)
+	ValueSize(ctx context.Context, in *ValueSizeRequest, opts ...grpc.CallOption) (*ValueSizeResponse, error)
+	// Exists only reports whether the key exists, the value is never sent back.
+	Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error)
+	// BatchExists reports, in the order of the keys, whether each of them exists.
+	BatchExists(ctx context.Context, in *Keys, opts ...grpc.CallOption) (*BatchExistsResponse, error)
 	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (NetKV_ScanClient, error)
 	BatchScan(ctx context.Context, in *BatchScanRequest, opts ...grpc.CallOption) (NetKV_BatchScanClient, error)
 	BatchDelete(ctx context.Context, in *Keys, opts ...grpc.CallOption) (*EmptyResponse, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*EmptyResponse, error)
 	Prefix(ctx context.Context, in *PrefixRequest, opts ...grpc.CallOption) (NetKV_PrefixClient, error)
 	BatchPrefix(ctx context.Context, in *BatchPrefixRequest, opts ...grpc.CallOption) (NetKV_BatchPrefixClient, error)
+	// MultiPrefix streams the keys of each prefix in turn, up to `limit_each` per prefix.
+	MultiPrefix(ctx context.Context, in *MultiPrefixRequest, opts ...grpc.CallOption) (NetKV_MultiPrefixClient, error)
+	// Capabilities reports the optional features of the store served to the
+	// client, which the client reports as its own.
+	Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
 }
 
 type netKVClient struct {
@@ -579,6 +1309,33 @@ func (c *netKVClient) BatchPut(ctx context.Context, in *KeyValues, opts ...grpc.
 	return out, nil
 }
 
+func (c *netKVClient) Insert(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/Insert", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *netKVClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/Increment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *netKVClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error) {
+	out := new(CompareAndSwapResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/CompareAndSwap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *netKVClient) BatchGet(ctx context.Context, in *Keys, opts ...grpc.CallOption) (NetKV_BatchGetClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_NetKV_serviceDesc.Streams[0], "/dfuse.netkv.v1.NetKV/BatchGet", opts...)
 	if err != nil {
@@ -611,6 +1368,33 @@ func (x *netKVBatchGetClient) Recv() (*KeyValue, error) {
 	return m, nil
 }
 
+func (c *netKVClient) ValueSize(ctx context.Context, in *ValueSizeRequest, opts ...grpc.CallOption) (*ValueSizeResponse, error) {
+	out := new(ValueSizeResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/ValueSize", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *netKVClient) Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error) {
+	out := new(ExistsResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/Exists", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *netKVClient) BatchExists(ctx context.Context, in *Keys, opts ...grpc.CallOption) (*BatchExistsResponse, error) {
+	out := new(BatchExistsResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/BatchExists", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *netKVClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (NetKV_ScanClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_NetKV_serviceDesc.Streams[1], "/dfuse.netkv.v1.NetKV/Scan", opts...)
 	if err != nil {
@@ -684,6 +1468,15 @@ func (c *netKVClient) BatchDelete(ctx context.Context, in *Keys, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *netKVClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*EmptyResponse, error) {
+	out := new(EmptyResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *netKVClient) Prefix(ctx context.Context, in *PrefixRequest, opts ...grpc.CallOption) (NetKV_PrefixClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_NetKV_serviceDesc.Streams[3], "/dfuse.netkv.v1.NetKV/Prefix", opts...)
 	if err != nil {
@@ -748,19 +1541,82 @@ func (x *netKVBatchPrefixClient) Recv() (*KeyValue, error) {
 	return m, nil
 }
 
+func (c *netKVClient) MultiPrefix(ctx context.Context, in *MultiPrefixRequest, opts ...grpc.CallOption) (NetKV_MultiPrefixClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_NetKV_serviceDesc.Streams[5], "/dfuse.netkv.v1.NetKV/MultiPrefix", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &netKVMultiPrefixClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type NetKV_MultiPrefixClient interface {
+	Recv() (*KeyValue, error)
+	grpc.ClientStream
+}
+
+type netKVMultiPrefixClient struct {
+	grpc.ClientStream
+}
+
+func (x *netKVMultiPrefixClient) Recv() (*KeyValue, error) {
+	m := new(KeyValue)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *netKVClient) Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/dfuse.netkv.v1.NetKV/Capabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NetKVServer is the server API for NetKV service.
 type NetKVServer interface {
 	BatchPut(context.Context, *KeyValues) (*EmptyResponse, error)
+	// Insert writes the key only if it does not exist yet, failing with
+	// `ALREADY_EXISTS` otherwise.
+	Insert(context.Context, *KeyValue) (*EmptyResponse, error)
+	// Increment atomically adds `delta` to the big-endian int64 counter stored
+	// at `key`, a missing key starting at zero.
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	// CompareAndSwap atomically replaces the value of `key` only when it is still
+	// `old_value`, or when it does not exist and `old_absent` is set. The key is
+	// deleted instead when `delete` is set. A non-zero `ttl_ms` makes the new
+	// value expire after that many milliseconds, on the clock of the server.
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error)
 	// TODO: we need to be able to get individual responses
 	// regarding Not-Foundness of each key, otherwise, we'll have
 	// a hard time knowing which key was not found, etc..
 	// This will happen more with sparse trxdb.
 	BatchGet(*Keys, NetKV_BatchGetServer) error
+	ValueSize(context.Context, *ValueSizeRequest) (*ValueSizeResponse, error)
+	// Exists only reports whether the key exists, the value is never sent back.
+	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
+	// BatchExists reports, in the order of the keys, whether each of them exists.
+	BatchExists(context.Context, *Keys) (*BatchExistsResponse, error)
 	Scan(*ScanRequest, NetKV_ScanServer) error
 	BatchScan(*BatchScanRequest, NetKV_BatchScanServer) error
 	BatchDelete(context.Context, *Keys) (*EmptyResponse, error)
+	Delete(context.Context, *DeleteRequest) (*EmptyResponse, error)
 	Prefix(*PrefixRequest, NetKV_PrefixServer) error
 	BatchPrefix(*BatchPrefixRequest, NetKV_BatchPrefixServer) error
+	// MultiPrefix streams the keys of each prefix in turn, up to `limit_each` per prefix.
+	MultiPrefix(*MultiPrefixRequest, NetKV_MultiPrefixServer) error
+	// Capabilities reports the optional features of the store served to the
+	// client, which the client reports as its own.
+	Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
 }
 
 // UnimplementedNetKVServer can be embedded to have forward compatible implementations.
@@ -770,9 +1626,27 @@ type UnimplementedNetKVServer struct {
 func (*UnimplementedNetKVServer) BatchPut(ctx context.Context, req *KeyValues) (*EmptyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchPut not implemented")
 }
+func (*UnimplementedNetKVServer) Insert(ctx context.Context, req *KeyValue) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+}
+func (*UnimplementedNetKVServer) Increment(ctx context.Context, req *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (*UnimplementedNetKVServer) CompareAndSwap(ctx context.Context, req *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
 func (*UnimplementedNetKVServer) BatchGet(req *Keys, srv NetKV_BatchGetServer) error {
 	return status.Errorf(codes.Unimplemented, "method BatchGet not implemented")
 }
+func (*UnimplementedNetKVServer) ValueSize(ctx context.Context, req *ValueSizeRequest) (*ValueSizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValueSize not implemented")
+}
+func (*UnimplementedNetKVServer) Exists(ctx context.Context, req *ExistsRequest) (*ExistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exists not implemented")
+}
+func (*UnimplementedNetKVServer) BatchExists(ctx context.Context, req *Keys) (*BatchExistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchExists not implemented")
+}
 func (*UnimplementedNetKVServer) Scan(req *ScanRequest, srv NetKV_ScanServer) error {
 	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
 }
@@ -782,12 +1656,21 @@ func (*UnimplementedNetKVServer) BatchScan(req *BatchScanRequest, srv NetKV_Batc
 func (*UnimplementedNetKVServer) BatchDelete(ctx context.Context, req *Keys) (*EmptyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchDelete not implemented")
 }
+func (*UnimplementedNetKVServer) Delete(ctx context.Context, req *DeleteRequest) (*EmptyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
 func (*UnimplementedNetKVServer) Prefix(req *PrefixRequest, srv NetKV_PrefixServer) error {
 	return status.Errorf(codes.Unimplemented, "method Prefix not implemented")
 }
 func (*UnimplementedNetKVServer) BatchPrefix(req *BatchPrefixRequest, srv NetKV_BatchPrefixServer) error {
 	return status.Errorf(codes.Unimplemented, "method BatchPrefix not implemented")
 }
+func (*UnimplementedNetKVServer) MultiPrefix(req *MultiPrefixRequest, srv NetKV_MultiPrefixServer) error {
+	return status.Errorf(codes.Unimplemented, "method MultiPrefix not implemented")
+}
+func (*UnimplementedNetKVServer) Capabilities(ctx context.Context, req *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
 
 func RegisterNetKVServer(s *grpc.Server, srv NetKVServer) {
 	s.RegisterService(&_NetKV_serviceDesc, srv)
@@ -811,6 +1694,60 @@ func _NetKV_BatchPut_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NetKV_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/Insert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).Insert(ctx, req.(*KeyValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetKV_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/Increment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetKV_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/CompareAndSwap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NetKV_BatchGet_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(Keys)
 	if err := stream.RecvMsg(m); err != nil {
@@ -832,6 +1769,60 @@ func (x *netKVBatchGetServer) Send(m *KeyValue) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _NetKV_ValueSize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValueSizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).ValueSize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/ValueSize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).ValueSize(ctx, req.(*ValueSizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetKV_Exists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExistsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).Exists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/Exists",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).Exists(ctx, req.(*ExistsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetKV_BatchExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Keys)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).BatchExists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/BatchExists",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).BatchExists(ctx, req.(*Keys))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NetKV_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(ScanRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -892,6 +1883,24 @@ func _NetKV_BatchDelete_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NetKV_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _NetKV_Prefix_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(PrefixRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -934,6 +1943,45 @@ func (x *netKVBatchPrefixServer) Send(m *KeyValue) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _NetKV_MultiPrefix_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MultiPrefixRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NetKVServer).MultiPrefix(m, &netKVMultiPrefixServer{stream})
+}
+
+type NetKV_MultiPrefixServer interface {
+	Send(*KeyValue) error
+	grpc.ServerStream
+}
+
+type netKVMultiPrefixServer struct {
+	grpc.ServerStream
+}
+
+func (x *netKVMultiPrefixServer) Send(m *KeyValue) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _NetKV_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetKVServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfuse.netkv.v1.NetKV/Capabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetKVServer).Capabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _NetKV_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "dfuse.netkv.v1.NetKV",
 	HandlerType: (*NetKVServer)(nil),
@@ -942,10 +1990,42 @@ var _NetKV_serviceDesc = grpc.ServiceDesc{
 			MethodName: "BatchPut",
 			Handler:    _NetKV_BatchPut_Handler,
 		},
+		{
+			MethodName: "Insert",
+			Handler:    _NetKV_Insert_Handler,
+		},
+		{
+			MethodName: "Increment",
+			Handler:    _NetKV_Increment_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _NetKV_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "ValueSize",
+			Handler:    _NetKV_ValueSize_Handler,
+		},
+		{
+			MethodName: "Exists",
+			Handler:    _NetKV_Exists_Handler,
+		},
+		{
+			MethodName: "BatchExists",
+			Handler:    _NetKV_BatchExists_Handler,
+		},
 		{
 			MethodName: "BatchDelete",
 			Handler:    _NetKV_BatchDelete_Handler,
 		},
+		{
+			MethodName: "Delete",
+			Handler:    _NetKV_Delete_Handler,
+		},
+		{
+			MethodName: "Capabilities",
+			Handler:    _NetKV_Capabilities_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -973,6 +2053,11 @@ var _NetKV_serviceDesc = grpc.ServiceDesc{
 			Handler:       _NetKV_BatchPrefix_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "MultiPrefix",
+			Handler:       _NetKV_MultiPrefix_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "netkv.proto",
 }
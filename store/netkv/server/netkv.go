@@ -2,14 +2,19 @@ package netkvserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strings"
+	"time"
 
 	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/kvdb/store/acl"
 	pbnetkv "github.com/dfuse-io/kvdb/store/netkv/pb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
@@ -21,9 +26,62 @@ type Server struct {
 	store      store.KVStore
 	grpcServer *grpc.Server
 	listener   net.Listener
+
+	// tokenRules restricts each token to its own prefixes when set, no token being accepted
+	// otherwise, see `WithTokenRules`
+	tokenRules map[string][]acl.Rule
+	// tokenStores is `store` restricted to the rules of each token of `tokenRules`
+	tokenStores map[string]store.KVStore
+
+	maxKeyLen int
+	// maxValueLen is the largest value accepted by writes, unbounded when 0
+	maxValueLen int
+}
+
+// defaultMaxRecvMsgSize is the gRPC default limit on the size of received messages, and
+// messageOverhead leaves room for the framing of a key and value within a message
+const (
+	defaultMaxRecvMsgSize = 4 * 1024 * 1024
+	messageOverhead       = 1024
+)
+
+// DefaultMaxKeyLen is the longest key accepted by writes unless `WithMaxKeyLen` is used, it
+// matches the limit of badger, the usual backing store.
+const DefaultMaxKeyLen = 65000
+
+type Option func(s *Server)
+
+// WithMaxValueLen rejects writes of values larger than `maxValueLen` bytes, as received from
+// the client, with a `ResourceExhausted` status, which the client turns back into
+// `store.ErrValueTooLarge`, before they reach the backing store. The gRPC message size limit
+// is raised when needed so that a single value of `maxValueLen` bytes can be received. Values
+// are not bounded by default, besides by that limit.
+func WithMaxValueLen(maxValueLen int) Option {
+	return func(s *Server) {
+		s.maxValueLen = maxValueLen
+	}
+}
+
+// WithMaxKeyLen rejects writes of keys longer than `maxKeyLen` bytes with an
+// `InvalidArgument` status, before they reach the backing store.
+func WithMaxKeyLen(maxKeyLen int) Option {
+	return func(s *Server) {
+		s.maxKeyLen = maxKeyLen
+	}
 }
 
-func Launch(listenAddr string, dsn string) (*Server, error) {
+// WithTokenRules restricts each client to the keys granted to its token by `rules`, see
+// `acl.Store`. Clients send their token through the `token` option of their DSN. Requests
+// without a known token are rejected with an `Unauthenticated` status, and accesses outside of
+// the prefixes granted to their token with a `PermissionDenied` one, which the client turns
+// back into `store.ErrForbidden`. Tokens travel in clear unless the server is put behind TLS.
+func WithTokenRules(rules map[string][]acl.Rule) Option {
+	return func(s *Server) {
+		s.tokenRules = rules
+	}
+}
+
+func Launch(listenAddr string, dsn string, opts ...Option) (*Server, error) {
 	str, err := store.New(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("setting up kvdb store: %w", err)
@@ -34,14 +92,30 @@ func Launch(listenAddr string, dsn string) (*Server, error) {
 		return nil, fmt.Errorf("failed listening: %w", err)
 	}
 
-	gsrv := grpc.NewServer()
-
 	s := &Server{
-		store:      str,
-		grpcServer: gsrv,
-		listener:   lis,
+		store:     str,
+		listener:  lis,
+		maxKeyLen: DefaultMaxKeyLen,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
+	if s.tokenRules != nil {
+		s.tokenStores = make(map[string]store.KVStore, len(s.tokenRules))
+		for token, rules := range s.tokenRules {
+			s.tokenStores[token] = acl.Wrap(str, rules)
+		}
+	}
+
+	gsrv := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+		grpc.MaxRecvMsgSize(s.maxRecvMsgSize()),
+	)
+	s.grpcServer = gsrv
+
 	reflection.Register(gsrv)
 	pbnetkv.RegisterNetKVServer(gsrv, s)
 
@@ -63,26 +137,273 @@ func (s *Server) Close() error {
 	return nil
 }
 
+type tokenStoreKey struct{}
+
+// authenticate resolves the store restricted to the token of the request when
+// `WithTokenRules` is used, and returns a context carrying it for `storeFor`
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	if s.tokenStores == nil {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			continue
+		}
+
+		if kvStore, found := s.tokenStores[strings.TrimPrefix(authorization, "Bearer ")]; found {
+			return context.WithValue(ctx, tokenStoreKey{}, kvStore), nil
+		}
+	}
+
+	return nil, status.Newf(codes.Unauthenticated, "a valid token is required").Err()
+}
+
+// storeFor returns the store serving the request of `ctx`, restricted to its token when
+// `WithTokenRules` is used
+func (s *Server) storeFor(ctx context.Context) store.KVStore {
+	if kvStore, ok := ctx.Value(tokenStoreKey{}).(store.KVStore); ok {
+		return kvStore
+	}
+	return s.store
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	return resp, wrapForbiddenError(err)
+}
+
+func (s *Server) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	return wrapForbiddenError(handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx}))
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// wrapForbiddenError maps `store.ErrForbidden`, returned by the stores restricted to a token,
+// to a `PermissionDenied` status, which the client turns back into `store.ErrForbidden`
+func wrapForbiddenError(err error) error {
+	if errors.Is(err, store.ErrForbidden) {
+		return status.Newf(codes.PermissionDenied, err.Error()).Err()
+	}
+	return err
+}
+
 func (s *Server) BatchPut(ctx context.Context, kvs *pbnetkv.KeyValues) (*pbnetkv.EmptyResponse, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	// Checked upfront so that a rejected batch writes nothing
 	for _, kv := range kvs.Kvs {
-		err := s.store.Put(ctx, kv.Key, kv.Value)
-		if err != nil {
+		if err := s.checkKeyLen(kv.Key); err != nil {
+			return nil, err
+		}
+		if err := s.checkValueLen(kv.Value); err != nil {
 			return nil, err
 		}
 	}
-	if err := s.store.FlushPuts(ctx); err != nil {
+
+	kvStore := s.storeFor(ctx)
+	for _, kv := range kvs.Kvs {
+		err := kvStore.Put(ctx, kv.Key, kv.Value)
+		if err != nil {
+			return nil, wrapReadOnlyError(err)
+		}
+	}
+	if err := kvStore.FlushPuts(ctx); err != nil {
+		return nil, wrapReadOnlyError(err)
+	}
+	return &pbnetkv.EmptyResponse{}, nil
+}
+
+func (s *Server) Insert(ctx context.Context, kv *pbnetkv.KeyValue) (*pbnetkv.EmptyResponse, error) {
+	inserter, ok := s.storeFor(ctx).(store.Inserter)
+	if !ok {
+		return nil, status.Newf(codes.Unimplemented, "backing store does not support Insert").Err()
+	}
+
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkKeyLen(kv.Key); err != nil {
+		return nil, err
+	}
+	if err := s.checkValueLen(kv.Value); err != nil {
 		return nil, err
 	}
+
+	if err := inserter.Insert(ctx, kv.Key, kv.Value); err != nil {
+		if err == store.ErrKeyExists {
+			return nil, status.Newf(codes.AlreadyExists, err.Error()).Err()
+		}
+		return nil, wrapReadOnlyError(err)
+	}
+
 	return &pbnetkv.EmptyResponse{}, nil
 }
 
+func (s *Server) Increment(ctx context.Context, req *pbnetkv.IncrementRequest) (*pbnetkv.IncrementResponse, error) {
+	incrementer, ok := s.storeFor(ctx).(store.Incrementer)
+	if !ok {
+		return nil, status.Newf(codes.Unimplemented, "backing store does not support Increment").Err()
+	}
+
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkKeyLen(req.Key); err != nil {
+		return nil, err
+	}
+
+	value, err := incrementer.Increment(ctx, req.Key, req.Delta)
+	if err != nil {
+		return nil, wrapReadOnlyError(err)
+	}
+
+	return &pbnetkv.IncrementResponse{Value: value}, nil
+}
+
+func (s *Server) CompareAndSwap(ctx context.Context, req *pbnetkv.CompareAndSwapRequest) (*pbnetkv.CompareAndSwapResponse, error) {
+	swapper, ok := s.storeFor(ctx).(store.CompareAndSwapper)
+	if !ok {
+		return nil, status.Newf(codes.Unimplemented, "backing store does not support CompareAndSwap").Err()
+	}
+
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkKeyLen(req.Key); err != nil {
+		return nil, err
+	}
+
+	old := req.OldValue
+	if req.OldAbsent {
+		old = nil
+	} else if old == nil {
+		old = []byte{}
+	}
+
+	new := req.NewValue
+	if req.Delete {
+		new = nil
+	} else {
+		if new == nil {
+			new = []byte{}
+		}
+		if err := s.checkValueLen(new); err != nil {
+			return nil, err
+		}
+	}
+
+	var swapped bool
+	var err error
+	if req.TtlMs > 0 {
+		ttlSwapper, ok := swapper.(store.TTLCompareAndSwapper)
+		if !ok {
+			return nil, status.Newf(codes.Unimplemented, "backing store does not support CompareAndSwap with a ttl").Err()
+		}
+		swapped, err = ttlSwapper.CompareAndSwapWithTTL(ctx, req.Key, old, new, time.Duration(req.TtlMs)*time.Millisecond)
+	} else {
+		swapped, err = swapper.CompareAndSwap(ctx, req.Key, old, new)
+	}
+	if err != nil {
+		return nil, wrapReadOnlyError(err)
+	}
+
+	return &pbnetkv.CompareAndSwapResponse{Swapped: swapped}, nil
+}
+
+// Capabilities reports the capabilities of the store served to the client, those of the store
+// restricted to its token when token rules are used.
+func (s *Server) Capabilities(ctx context.Context, _ *pbnetkv.CapabilitiesRequest) (*pbnetkv.CapabilitiesResponse, error) {
+	capabilities := s.storeFor(ctx).Capabilities()
+	return &pbnetkv.CapabilitiesResponse{
+		Insert:            capabilities.Insert,
+		Increment:         capabilities.Increment,
+		CompareAndSwap:    capabilities.CompareAndSwap,
+		CompareAndSwapTtl: capabilities.CompareAndSwapTTL,
+		ReadOnly:          capabilities.ReadOnly,
+	}, nil
+}
+
+// checkWritable rejects writes upfront with a `FailedPrecondition` status, which the client
+// turns back into `store.ErrReadOnly`, when the backing store is read-only
+func (s *Server) checkWritable() error {
+	if s.store.Capabilities().ReadOnly {
+		return status.Newf(codes.FailedPrecondition, "%s", store.ErrReadOnly).Err()
+	}
+	return nil
+}
+
+// wrapReadOnlyError maps `store.ErrReadOnly` returned by backing stores not reporting it
+// through their capabilities (wrappers for example) like `checkWritable` does
+func wrapReadOnlyError(err error) error {
+	if errors.Is(err, store.ErrReadOnly) {
+		return status.Newf(codes.FailedPrecondition, err.Error()).Err()
+	}
+	return err
+}
+
+func (s *Server) checkKeyLen(key []byte) error {
+	if len(key) > s.maxKeyLen {
+		return status.Newf(codes.InvalidArgument, "key of %d bytes exceeds maximum of %d: %s", len(key), s.maxKeyLen, store.ErrKeyTooLong).Err()
+	}
+	return nil
+}
+
+func (s *Server) checkValueLen(value []byte) error {
+	if s.maxValueLen > 0 && len(value) > s.maxValueLen {
+		st := status.Newf(codes.ResourceExhausted, "value of %d bytes exceeds maximum of %d", len(value), s.maxValueLen)
+		// The detail tells the client this status apart from other resource exhaustions, it can
+		// only fail to be marshalled, the status then goes without it
+		if detailed, err := st.WithDetails(&pbnetkv.ValueTooLarge{Size: uint64(len(value)), MaxSize: uint64(s.maxValueLen)}); err == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+	return nil
+}
+
+// maxRecvMsgSize is the gRPC default, raised so that a write of the longest key with the
+// largest value fits in a message when `maxValueLen` is above it
+func (s *Server) maxRecvMsgSize() int {
+	size := s.maxValueLen + s.maxKeyLen + messageOverhead
+	if size < defaultMaxRecvMsgSize {
+		return defaultMaxRecvMsgSize
+	}
+	return size
+}
+
 // BatchGet returns only values, and assumes the same order in values as the order of the input keys.
 func (s *Server) BatchGet(keys *pbnetkv.Keys, stream pbnetkv.NetKV_BatchGetServer) error {
 	if len(keys.Keys) == 0 {
 		return status.Newf(codes.InvalidArgument, "at least one key required for BatchGet").Err()
 	}
-	if len(keys.Keys) == 1 {
-		val, err := s.store.Get(stream.Context(), keys.Keys[0])
+	kvStore := s.storeFor(stream.Context())
+	options := storeReadOptions(keys.Options)
+	if len(keys.Keys) == 1 && len(options) == 0 {
+		val, err := kvStore.Get(stream.Context(), keys.Keys[0])
 		if err != nil {
 			return wrapNotFoundError(err)
 		}
@@ -92,7 +413,7 @@ func (s *Server) BatchGet(keys *pbnetkv.Keys, stream pbnetkv.NetKV_BatchGetServe
 		return nil
 	}
 
-	it := s.store.BatchGet(stream.Context(), keys.Keys)
+	it := kvStore.BatchGet(stream.Context(), keys.Keys, options...)
 
 	for it.Next() {
 		if err := stream.Send(&pbnetkv.KeyValue{Value: it.Item().Value, Key: it.Item().Key}); err != nil {
@@ -105,14 +426,57 @@ func (s *Server) BatchGet(keys *pbnetkv.Keys, stream pbnetkv.NetKV_BatchGetServe
 	return nil
 }
 
+func (s *Server) ValueSize(ctx context.Context, req *pbnetkv.ValueSizeRequest) (*pbnetkv.ValueSizeResponse, error) {
+	size, err := s.storeFor(ctx).ValueSize(ctx, req.Key)
+	if err != nil {
+		return nil, wrapNotFoundError(err)
+	}
+
+	return &pbnetkv.ValueSizeResponse{Size: uint64(size)}, nil
+}
+
+func (s *Server) Exists(ctx context.Context, req *pbnetkv.ExistsRequest) (*pbnetkv.ExistsResponse, error) {
+	exists, err := s.storeFor(ctx).Exists(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbnetkv.ExistsResponse{Exists: exists}, nil
+}
+
+func (s *Server) BatchExists(ctx context.Context, keys *pbnetkv.Keys) (*pbnetkv.BatchExistsResponse, error) {
+	exists, err := s.storeFor(ctx).BatchExists(ctx, keys.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbnetkv.BatchExistsResponse{Exists: exists}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *pbnetkv.DeleteRequest) (*pbnetkv.EmptyResponse, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := s.storeFor(ctx).Delete(ctx, req.Key); err != nil {
+		return nil, wrapReadOnlyError(err)
+	}
+
+	return &pbnetkv.EmptyResponse{}, nil
+}
+
 func (s *Server) BatchDelete(ctx context.Context, keys *pbnetkv.Keys) (*pbnetkv.EmptyResponse, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	if len(keys.Keys) == 0 {
 		return &pbnetkv.EmptyResponse{}, nil
 	}
 
-	err := s.store.BatchDelete(ctx, keys.Keys)
+	err := s.storeFor(ctx).BatchDelete(ctx, keys.Keys)
 	if err != nil {
-		return nil, err
+		return nil, wrapReadOnlyError(err)
 	}
 
 	return &pbnetkv.EmptyResponse{}, nil
@@ -128,7 +492,7 @@ func wrapNotFoundError(err error) error {
 }
 
 func (s *Server) Scan(req *pbnetkv.ScanRequest, stream pbnetkv.NetKV_ScanServer) error {
-	it := s.store.Scan(stream.Context(), req.Start, req.ExclusiveEnd, int(req.Limit), storeReadOptions(req.Options)...)
+	it := s.storeFor(stream.Context()).Scan(stream.Context(), req.Start, req.ExclusiveEnd, int(req.Limit), storeReadOptions(req.Options)...)
 	for it.Next() {
 		item := it.Item()
 		if err := stream.Send(&pbnetkv.KeyValue{Key: item.Key, Value: item.Value}); err != nil {
@@ -146,7 +510,7 @@ func (s *Server) BatchScan(req *pbnetkv.BatchScanRequest, stream pbnetkv.NetKV_B
 }
 
 func (s *Server) Prefix(req *pbnetkv.PrefixRequest, stream pbnetkv.NetKV_PrefixServer) error {
-	it := s.store.Prefix(stream.Context(), req.Prefix, int(req.Limit), storeReadOptions(req.Options)...)
+	it := s.storeFor(stream.Context()).Prefix(stream.Context(), req.Prefix, int(req.Limit), storeReadOptions(req.Options)...)
 	for it.Next() {
 		item := it.Item()
 		if err := stream.Send(&pbnetkv.KeyValue{Key: item.Key, Value: item.Value}); err != nil {
@@ -160,7 +524,21 @@ func (s *Server) Prefix(req *pbnetkv.PrefixRequest, stream pbnetkv.NetKV_PrefixS
 }
 
 func (s *Server) BatchPrefix(req *pbnetkv.BatchPrefixRequest, stream pbnetkv.NetKV_BatchPrefixServer) error {
-	it := s.store.BatchPrefix(stream.Context(), req.Prefixes, int(req.LimitPerPrefix), storeReadOptions(req.Options)...)
+	it := s.storeFor(stream.Context()).BatchPrefix(stream.Context(), req.Prefixes, int(req.LimitPerPrefix), storeReadOptions(req.Options)...)
+	for it.Next() {
+		item := it.Item()
+		if err := stream.Send(&pbnetkv.KeyValue{Key: item.Key, Value: item.Value}); err != nil {
+			return err
+		}
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+	return nil
+}
+
+func (s *Server) MultiPrefix(req *pbnetkv.MultiPrefixRequest, stream pbnetkv.NetKV_MultiPrefixServer) error {
+	it := store.MultiPrefix(stream.Context(), s.storeFor(stream.Context()), req.Prefixes, int(req.LimitEach), storeReadOptions(req.Options)...)
 	for it.Next() {
 		item := it.Item()
 		if err := stream.Send(&pbnetkv.KeyValue{Key: item.Key, Value: item.Value}); err != nil {
@@ -174,10 +552,15 @@ func (s *Server) BatchPrefix(req *pbnetkv.BatchPrefixRequest, stream pbnetkv.Net
 }
 
 func storeReadOptions(options *pbnetkv.ReadOptions) (out []store.ReadOption) {
-	if options != nil && options.KeyOnly {
-		return []store.ReadOption{store.KeyOnly()}
+	if options == nil {
+		return nil
 	}
 
-	// There is not options for now, so we can always return nothing from that point
-	return nil
+	if options.KeyOnly {
+		out = append(out, store.KeyOnly())
+	}
+	if options.Raw {
+		out = append(out, store.Raw())
+	}
+	return out
 }
@@ -17,6 +17,8 @@ import (
 
 var flagBackendDSN = flag.String("backend-dsn", "badger://./netkv", "KVDB storage backing this NetKV instance")
 var flagListenAddr = flag.String("listen-addr", ":65211", "gRPC listening address (insecure)")
+var flagMaxKeyLen = flag.Int("max-key-len", netkvserver.DefaultMaxKeyLen, "Writes of keys longer than this many bytes are rejected")
+var flagMaxValueLen = flag.Int("max-value-len", 0, "Writes of values larger than this many bytes are rejected, 0 for no limit")
 
 func main() {
 	flag.Parse()
@@ -24,7 +26,7 @@ func main() {
 	pwd, _ := os.Getwd()
 	backendDSN := strings.Replace(*flagBackendDSN, "//./", fmt.Sprintf("//%s/", pwd), 1)
 
-	srv, err := netkvserver.Launch(*flagListenAddr, backendDSN)
+	srv, err := netkvserver.Launch(*flagListenAddr, backendDSN, netkvserver.WithMaxKeyLen(*flagMaxKeyLen), netkvserver.WithMaxValueLen(*flagMaxValueLen))
 	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
@@ -32,7 +34,7 @@ func main() {
 
 	fmt.Println("Listening", *flagListenAddr)
 
-	signals := make(chan os.Signal)
+	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	<-signals
 
@@ -1,9 +1,27 @@
 package store
 
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
 type EmtpyValueEnabler interface {
 	EnableEmpty()
 }
 
+// LoggerSetter is implemented by stores accepting an instance specific logger, used in place
+// of their package level logger.
+type LoggerSetter interface {
+	SetLogger(logger *zap.Logger)
+}
+
+// IteratorObserverSetter is implemented by stores able to report each completed iteration to
+// an `IteratorObserver`.
+type IteratorObserverSetter interface {
+	SetIteratorObserver(observer IteratorObserver)
+}
+
 type Option interface {
 	apply(s KVStore)
 }
@@ -21,8 +39,70 @@ func (e emptyValueOpt) apply(s KVStore) {
 	}
 }
 
+type loggerOpt struct {
+	logger *zap.Logger
+}
+
+// WithLogger makes the store log through `logger` instead of its package level logger, which
+// remains the default when the option is not used. A logger attached to the context of a call
+// (via `logging.WithLogger`) still has precedence over it. Stores not implementing
+// `LoggerSetter` ignore this option.
+func WithLogger(logger *zap.Logger) Option {
+	return loggerOpt{logger: logger}
+}
+
+func (o loggerOpt) apply(s KVStore) {
+	if f, ok := s.(LoggerSetter); ok && o.logger != nil {
+		f.SetLogger(o.logger)
+	}
+}
+
+// IteratorStats describes an iteration once it completed, successfully or not.
+type IteratorStats struct {
+	// Operation is the store method that created the iterator, like `Scan` or `Prefix`.
+	Operation string
+	// Items is the number of items pushed to the iterator.
+	Items uint64
+	// Bytes is the total size of the keys and values pushed to the iterator.
+	Bytes uint64
+	// Duration is the time elapsed from the creation of the iterator to its completion.
+	Duration time.Duration
+	// Canceled is true when the iteration stopped early because the context of the iterator
+	// was done, the consumer not wanting more items (or not draining them in time).
+	Canceled bool
+	// Err is the error the iteration failed with, nil when it completed or when it was
+	// canceled while pushing items (`Canceled` tells).
+	Err error
+}
+
+// IteratorObserver is called by stores with the statistics of each completed iteration. It is
+// called from the goroutine feeding the iterator, it must not block.
+type IteratorObserver func(stats IteratorStats)
+
+type iteratorObserverOpt struct {
+	observer IteratorObserver
+}
+
+// WithIteratorObserver makes the store report the statistics of each completed iteration to
+// `observer`, to spot the scans producing much more than their consumer uses for example.
+// Stores not implementing `IteratorObserverSetter` ignore this option.
+func WithIteratorObserver(observer IteratorObserver) Option {
+	return iteratorObserverOpt{observer: observer}
+}
+
+func (o iteratorObserverOpt) apply(s KVStore) {
+	if f, ok := s.(IteratorObserverSetter); ok && o.observer != nil {
+		f.SetIteratorObserver(o.observer)
+	}
+}
+
 type ReadOptions struct {
 	KeyOnly bool
+	// PrefetchSize is the number of values read ahead by stores that prefetch while
+	// iterating, 0 keeping the store's default.
+	PrefetchSize int
+	// Raw returns values as stored, without decompressing them.
+	Raw bool
 }
 
 type ReadOption interface {
@@ -38,3 +118,34 @@ type keyOnlyReadOption struct{}
 func (o keyOnlyReadOption) Apply(opts *ReadOptions) {
 	opts.KeyOnly = true
 }
+
+// PrefetchSize sets how many values are read ahead while iterating, bigger values speed up
+// long sequential scans while smaller ones save memory on short ones. It is a hint, ignored by
+// stores that do not prefetch (only `badger` does today).
+func PrefetchSize(size int) ReadOption {
+	return prefetchSizeReadOption{size: size}
+}
+
+type prefetchSizeReadOption struct {
+	size int
+}
+
+func (o prefetchSizeReadOption) Apply(opts *ReadOptions) {
+	opts.PrefetchSize = o.size
+}
+
+// Raw returns the values as they are stored, skipping their decompression, so a proxy can
+// forward them without paying for a decompression and recompression round trip. Values
+// compressed with `ZstdCompressor` are self-describing, `Decompress` of a `ZstdCompressor`
+// restores them and leaves values stored uncompressed untouched. It is ignored by stores
+// that do not compress values (only `badger` honors it today, `netkv` forwards it to its
+// server).
+func Raw() ReadOption {
+	return rawReadOption{}
+}
+
+type rawReadOption struct{}
+
+func (o rawReadOption) Apply(opts *ReadOptions) {
+	opts.Raw = true
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Insert calls `Insert` on `kv`, see `Inserter`, it fails when `kv` does not implement it.
+// Wrapper stores use it to forward `Insert` to their backing store.
+func Insert(ctx context.Context, kv KVStore, key, value []byte) error {
+	inserter, ok := kv.(Inserter)
+	if !ok {
+		return fmt.Errorf("store does not support insert")
+	}
+	return inserter.Insert(ctx, key, value)
+}
+
+// Increment calls `Increment` on `kv`, see `Incrementer`, it fails when `kv` does not
+// implement it. Wrapper stores use it to forward `Increment` to their backing store.
+func Increment(ctx context.Context, kv KVStore, key []byte, delta int64) (newValue int64, err error) {
+	incrementer, ok := kv.(Incrementer)
+	if !ok {
+		return 0, fmt.Errorf("store does not support increment")
+	}
+	return incrementer.Increment(ctx, key, delta)
+}
+
+// CompareAndSwap calls `CompareAndSwap` on `kv`, see `CompareAndSwapper`, it fails when `kv`
+// does not implement it. Wrapper stores use it to forward `CompareAndSwap` to their backing
+// store.
+func CompareAndSwap(ctx context.Context, kv KVStore, key, old, new []byte) (swapped bool, err error) {
+	swapper, ok := kv.(CompareAndSwapper)
+	if !ok {
+		return false, fmt.Errorf("store does not support compare and swap")
+	}
+	return swapper.CompareAndSwap(ctx, key, old, new)
+}
+
+// CompareAndSwapWithTTL calls `CompareAndSwapWithTTL` on `kv`, see `TTLCompareAndSwapper`, it
+// fails when `kv` does not implement it. Wrapper stores use it to forward
+// `CompareAndSwapWithTTL` to their backing store.
+func CompareAndSwapWithTTL(ctx context.Context, kv KVStore, key, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	swapper, ok := kv.(TTLCompareAndSwapper)
+	if !ok {
+		return false, fmt.Errorf("store does not support compare and swap with ttl")
+	}
+	return swapper.CompareAndSwapWithTTL(ctx, key, old, new, ttl)
+}
@@ -42,6 +42,12 @@ func (s *PurgeableKVStore) Put(ctx context.Context, key, value []byte) error {
 	return nil
 }
 
+// Capabilities only keeps the plain ones of the wrapped store, its optional interfaces are
+// hidden by the embedding and would bypass the deletion keys anyway.
+func (s *PurgeableKVStore) Capabilities() Capabilities {
+	return s.KVStore.Capabilities().Intersect(Capabilities{EmptyValue: true, ReadOnly: true})
+}
+
 func (s *PurgeableKVStore) MarkCurrentHeight(height uint64) {
 	if traceEnabled {
 		zlog.Debug("setting purgeable store height",
@@ -0,0 +1,24 @@
+package ratelimit
+
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) EnableEmpty() {
+	if enabler, ok := s.backing.(store.EmtpyValueEnabler); ok {
+		enabler.EnableEmpty()
+	}
+}
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	if setter, ok := s.backing.(store.LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+// Capabilities only keeps the plain ones of the backing store, its optional interfaces are not
+// implemented by the wrapper, they would otherwise bypass the rate limit.
+func (s *Store) Capabilities() store.Capabilities {
+	return s.backing.Capabilities().Intersect(store.Capabilities{EmptyValue: true, ReadOnly: true})
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Store wraps a backing store and limits the rate of operations sent to it. `Put`, `Get`,
+// `BatchGet`, `ValueSize`, `Exists`, `BatchExists`, `Delete`, `BatchDelete`, `Scan`, `Prefix`
+// and `BatchPrefix` each consume one token, counted when the operation starts, regardless of the
+// number of keys involved.
+//
+// By default, an operation exceeding the limit waits until a token is available (or until
+// its context is done). With `fail_fast=true`, it fails right away with `store.ErrRateLimited`
+// instead.
+type Store struct {
+	dsn      string
+	backing  store.KVStore
+	limiter  *rate.Limiter
+	failFast bool
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("rate limited kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "ratelimit",
+		Title:       "Rate Limit",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports ratelimit://?qps=5000&burst=10000&fail_fast=false&backing=<url escaped dsn>,
+// `burst` defaults to `qps` when not set.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+	qps, err := strconv.ParseFloat(query.Get("qps"), 64)
+	if err != nil || qps <= 0 {
+		return nil, fmt.Errorf("ratelimit new: invalid qps %q, expecting a positive number", query.Get("qps"))
+	}
+
+	burst := int(qps)
+	if query.Get("burst") != "" {
+		burst, err = strconv.Atoi(query.Get("burst"))
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("ratelimit new: invalid burst %q, expecting a positive integer", query.Get("burst"))
+		}
+	}
+
+	// A burst lower than 1 would prevent any operation from ever going through
+	if burst < 1 {
+		burst = 1
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("ratelimit new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Float64("qps", qps), zap.Int("burst", burst))
+
+	return &Store{
+		dsn:      dsnString,
+		backing:  backing,
+		limiter:  rate.NewLimiter(rate.Limit(qps), burst),
+		failFast: query.Get("fail_fast") == "true",
+	}, nil
+}
+
+func (s *Store) acquire(ctx context.Context) error {
+	if s.failFast {
+		if !s.limiter.Allow() {
+			return store.ErrRateLimited
+		}
+		return nil
+	}
+
+	return s.limiter.Wait(ctx)
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	return s.backing.Put(ctx, key, value)
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	return s.backing.FlushPuts(ctx)
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return s.backing.Get(ctx, key)
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	if err := s.acquire(ctx); err != nil {
+		return 0, err
+	}
+	return s.backing.ValueSize(ctx, key)
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	if err := s.acquire(ctx); err != nil {
+		return false, err
+	}
+	return s.backing.Exists(ctx, key)
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return s.backing.BatchExists(ctx, keys)
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	if err := s.acquire(ctx); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.backing.BatchGet(ctx, keys, options...)
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	return s.backing.BatchDelete(ctx, keys)
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	return s.backing.Delete(ctx, key)
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.acquire(ctx); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.backing.Scan(ctx, start, exclusiveEnd, limit, options...)
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.acquire(ctx); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.backing.Prefix(ctx, prefix, limit, options...)
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	if err := s.acquire(ctx); err != nil {
+		return failedIterator(ctx, err)
+	}
+	return s.backing.BatchPrefix(ctx, prefixes, limit, options...)
+}
+
+func failedIterator(ctx context.Context, err error) *store.Iterator {
+	it := store.NewIterator(ctx)
+	it.PushError(err)
+	return it
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "RateLimit", storetest.NewBadgerBackedFactory(t, "ratelimit", "qps=100000"))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "ratelimit", "qps=10",
+		"",
+		"qps=abc",
+		"qps=10&burst=-1",
+	)
+}
+
+func TestThrottled(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "ratelimit", "qps=50&burst=1")
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, kvStore.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	// 1 operation above, 1 scan and 24 gets at 50 QPS take at least 0.5s once the first token is used
+	start := time.Now()
+	it := kvStore.Prefix(ctx, []byte("a"), store.Unlimited)
+	for it.Next() {
+	}
+	require.NoError(t, it.Err())
+
+	for i := 0; i < 24; i++ {
+		_, err := kvStore.Get(ctx, []byte("a"))
+		require.NoError(t, err)
+	}
+
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 450*time.Millisecond, "expected operations to be throttled, took %s", elapsed)
+	assert.True(t, elapsed < 2*time.Second, "expected operations to be throttled to about 50 QPS, took %s", elapsed)
+}
+
+func TestFailFast(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "ratelimit", "qps=1&burst=1&fail_fast=true")
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := kvStore.Get(ctx, []byte("a"))
+	assert.Equal(t, store.ErrNotFound, err)
+
+	_, err = kvStore.Get(ctx, []byte("a"))
+	assert.Equal(t, store.ErrRateLimited, err)
+
+	it := kvStore.Scan(ctx, []byte("a"), []byte("b"), store.Unlimited)
+	assert.False(t, it.Next())
+	assert.Equal(t, store.ErrRateLimited, it.Err())
+}
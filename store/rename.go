@@ -0,0 +1,90 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// RenamePrefix moves all the keys starting with `from` under `to`, after having deleted all
+// the keys starting with `to`, see `PrefixRenamer`. The rename is native when `kv` implements
+// `PrefixRenamer`.
+//
+// Otherwise, it is not atomic: the keys under `to` are deleted first, then the keys under
+// `from` are put under `to` and flushed, and only then deleted. A failure midway can leave
+// `to` empty or partially filled, `from` is only deleted once all of its keys were copied, so
+// calling `RenamePrefix` again completes the rename. Pending puts are flushed first, those
+// under `from` are renamed along.
+func RenamePrefix(ctx context.Context, kv KVStore, from, to []byte) error {
+	if renamer, ok := kv.(PrefixRenamer); ok {
+		return renamer.RenamePrefix(ctx, from, to)
+	}
+
+	if err := CheckRenamePrefixes(from, to); err != nil {
+		return err
+	}
+
+	if err := kv.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("rename prefix: flush pending puts: %w", err)
+	}
+
+	staleKeys, err := prefixKeys(ctx, kv, to)
+	if err != nil {
+		return fmt.Errorf("rename prefix: list %s keys: %w", Key(to), err)
+	}
+
+	if len(staleKeys) > 0 {
+		if err := kv.BatchDelete(ctx, staleKeys); err != nil {
+			return fmt.Errorf("rename prefix: delete %s keys: %w", Key(to), err)
+		}
+	}
+
+	var movedKeys [][]byte
+	it := kv.Prefix(ctx, from, Unlimited)
+	for it.Next() {
+		item := it.Item()
+		if err := kv.Put(ctx, renamedKey(item.Key, from, to), item.Value); err != nil {
+			return fmt.Errorf("rename prefix: put: %w", err)
+		}
+		movedKeys = append(movedKeys, item.Key)
+	}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("rename prefix: read %s keys: %w", Key(from), err)
+	}
+
+	if err := kv.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("rename prefix: flush: %w", err)
+	}
+
+	if len(movedKeys) > 0 {
+		if err := kv.BatchDelete(ctx, movedKeys); err != nil {
+			return fmt.Errorf("rename prefix: delete %s keys: %w", Key(from), err)
+		}
+	}
+
+	return nil
+}
+
+// CheckRenamePrefixes returns an error when `from` and `to` cannot be used to rename a prefix,
+// that is when one of them is a prefix of the other.
+func CheckRenamePrefixes(from, to []byte) error {
+	if bytes.HasPrefix(from, to) || bytes.HasPrefix(to, from) {
+		return fmt.Errorf("rename prefix: prefixes %s and %s overlap", Key(from), Key(to))
+	}
+	return nil
+}
+
+func renamedKey(key, from, to []byte) []byte {
+	renamed := make([]byte, len(to)+len(key)-len(from))
+	copy(renamed, to)
+	copy(renamed[len(to):], key[len(from):])
+	return renamed
+}
+
+func prefixKeys(ctx context.Context, kv KVStore, prefix []byte) (out [][]byte, err error) {
+	it := kv.Prefix(ctx, prefix, Unlimited, KeyOnly())
+	for it.Next() {
+		out = append(out, it.Item().Key)
+	}
+	return out, it.Err()
+}
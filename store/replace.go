@@ -0,0 +1,58 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ReplacePrefix deletes all the keys starting with `prefix` and writes `kvs` instead, to
+// rebuild an index for example, see `PrefixReplacer`. The replacement is native and atomic
+// when `kv` implements `PrefixReplacer`.
+//
+// Otherwise, the keys of the prefix are listed then deleted along with the writes of `kvs`
+// through `Apply`, which is atomic for stores implementing `Applier`, keys written under the
+// prefix by others in between surviving the replacement. On other stores, readers can observe
+// the prefix empty or partially rebuilt, and a failure midway can leave it so, a warning is
+// logged in that case. Pending puts are flushed first, those under the prefix are replaced.
+func ReplacePrefix(ctx context.Context, kv KVStore, prefix []byte, kvs []KV) error {
+	if replacer, ok := kv.(PrefixReplacer); ok {
+		return replacer.ReplacePrefix(ctx, prefix, kvs)
+	}
+
+	if err := CheckReplacePrefix(prefix, kvs); err != nil {
+		return err
+	}
+
+	if !kv.Capabilities().Apply {
+		zlog.Warn("store does not apply mutations atomically, readers can observe the prefix partially replaced", zap.Stringer("prefix", Key(prefix)), zap.Int("kv_count", len(kvs)))
+	}
+
+	// Flushed first so that the pending puts under the prefix are listed and deleted too
+	if err := kv.FlushPuts(ctx); err != nil {
+		return fmt.Errorf("replace prefix: flush pending puts: %w", err)
+	}
+
+	staleKeys, err := prefixKeys(ctx, kv, prefix)
+	if err != nil {
+		return fmt.Errorf("replace prefix: list %s keys: %w", Key(prefix), err)
+	}
+
+	if err := Apply(ctx, kv, kvs, staleKeys); err != nil {
+		return fmt.Errorf("replace prefix: %w", err)
+	}
+
+	return nil
+}
+
+// CheckReplacePrefix returns an error when one of `kvs` does not start with `prefix`.
+func CheckReplacePrefix(prefix []byte, kvs []KV) error {
+	for _, kv := range kvs {
+		if !bytes.HasPrefix(kv.Key, prefix) {
+			return fmt.Errorf("replace prefix: key %s is not under prefix %s", Key(kv.Key), Key(prefix))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package salted
+
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) EnableEmpty() {
+	if enabler, ok := s.backing.(store.EmtpyValueEnabler); ok {
+		enabler.EnableEmpty()
+	}
+}
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	if setter, ok := s.backing.(store.LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+// Capabilities keeps `Insert` and `Increment` of the backing store, forwarded with salted keys.
+// Its other optional interfaces take key ranges or prefixes, which are spread over all the
+// buckets, and are not exposed.
+func (s *Store) Capabilities() store.Capabilities {
+	return s.backing.Capabilities().Intersect(store.Capabilities{EmptyValue: true, Insert: true, Increment: true, ReadOnly: true})
+}
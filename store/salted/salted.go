@@ -0,0 +1,263 @@
+package salted
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"go.uber.org/zap"
+)
+
+const defaultBucketCount = 16
+
+// Store wraps a backing store and prefixes each key with a one byte salt, the bucket of the
+// key, computed from a hash of the key. Sequential keys (block numbers for example) written to
+// a range partitioned backend like bigtable all land on the same tablet, salting them spreads
+// the writes over up to `buckets` key ranges instead. This is transparent to callers, the salt
+// is stripped from keys when read.
+//
+// The salt is derived from the key itself, so point operations (`Put`, `Get`, `BatchGet`,
+// `Exists`, `BatchExists`, `Delete` and `BatchDelete` for example) reach the right bucket
+// directly. Range operations (`Scan`, `Prefix` and `BatchPrefix`) however cannot know which
+// buckets hold the keys, they fan out to all of them and merge the results back in key order,
+// each bucket performing the scan with the full limit. Only salt keys when writes matter more
+// than range scans.
+type Store struct {
+	dsn         string
+	backing     store.KVStore
+	bucketCount int
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("salted kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "salted",
+		Title:       "Salted",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports salted://?buckets=<count>&backing=<url escaped dsn>, `buckets` (between 1
+// and 256, 16 when not given) determines the salt of every key, it must not change once data
+// has been written.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("salted new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+	bucketCount := defaultBucketCount
+	if raw := query.Get("buckets"); raw != "" {
+		bucketCount, err = strconv.Atoi(raw)
+		if err != nil || bucketCount < 1 || bucketCount > 256 {
+			return nil, fmt.Errorf("salted new: invalid buckets %q, expecting a number between 1 and 256", raw)
+		}
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("salted new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("salted new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Int("bucket_count", bucketCount))
+
+	return &Store{
+		dsn:         dsnString,
+		backing:     backing,
+		bucketCount: bucketCount,
+	}, nil
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) bucket(key []byte) byte {
+	hasher := fnv.New64a()
+	hasher.Write(key)
+
+	return byte(hasher.Sum64() % uint64(s.bucketCount))
+}
+
+func (s *Store) encodeKey(key []byte) []byte {
+	return salt(s.bucket(key), key)
+}
+
+func (s *Store) encodeKeys(keys [][]byte) [][]byte {
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		encoded[i] = s.encodeKey(key)
+	}
+	return encoded
+}
+
+func salt(bucket byte, key []byte) []byte {
+	salted := make([]byte, 1+len(key))
+	salted[0] = bucket
+	copy(salted[1:], key)
+
+	return salted
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	return s.backing.Put(ctx, s.encodeKey(key), value)
+}
+
+// Insert salts the key like `Put` does, a key always landing in the same bucket.
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	return store.Insert(ctx, s.backing, s.encodeKey(key), value)
+}
+
+// Increment salts the key like `Put` does, counters being stored as-is.
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	return store.Increment(ctx, s.backing, s.encodeKey(key), delta)
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	return s.backing.FlushPuts(ctx)
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	return s.backing.Get(ctx, s.encodeKey(key))
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	return s.backing.ValueSize(ctx, s.encodeKey(key))
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	return s.backing.Exists(ctx, s.encodeKey(key))
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	return s.backing.BatchExists(ctx, s.encodeKeys(keys))
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	return unsalted(ctx, s.backing.BatchGet(ctx, s.encodeKeys(keys), options...))
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	return s.backing.BatchDelete(ctx, s.encodeKeys(keys))
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	return s.backing.Delete(ctx, s.encodeKey(key))
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("scanning", zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)))
+
+	// Like the other stores, an empty exclusive end bounds nothing
+	if len(exclusiveEnd) == 0 {
+		kr := store.NewIterator(ctx)
+		go kr.PushFinished()
+		return kr
+	}
+
+	return store.MergeScans(ctx, limit, s.bucketScans(func(ctx context.Context, bucket byte) *store.Iterator {
+		return s.backing.Scan(ctx, salt(bucket, start), salt(bucket, exclusiveEnd), limit, options...)
+	})...)
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("prefix scanning", zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)))
+
+	return store.MergeScans(ctx, limit, s.bucketScans(func(ctx context.Context, bucket byte) *store.Iterator {
+		return s.backing.Prefix(ctx, salt(bucket, prefix), limit, options...)
+	})...)
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("batch prefix scanning", zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit", store.Limit(limit)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		count := uint64(0)
+		for _, prefix := range prefixes {
+			prefix := prefix
+
+			prefixLimit := store.Limit(limit)
+			if prefixLimit.Bounded() {
+				prefixLimit = store.Limit(uint64(limit) - count)
+			}
+
+			it := store.MergeScans(ctx, int(prefixLimit), s.bucketScans(func(ctx context.Context, bucket byte) *store.Iterator {
+				return s.backing.Prefix(ctx, salt(bucket, prefix), int(prefixLimit), options...)
+			})...)
+
+			for it.Next() {
+				if !kr.PushItem(it.Item()) {
+					return
+				}
+				count++
+			}
+
+			if err := it.Err(); err != nil {
+				kr.PushError(err)
+				return
+			}
+
+			if store.Limit(limit).Reached(count) {
+				break
+			}
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// bucketScans returns one scan per bucket, each yielding its keys with the salt stripped, so
+// that they can be merged back in the order of the original keys.
+func (s *Store) bucketScans(scan func(ctx context.Context, bucket byte) *store.Iterator) []store.ScanFunc {
+	scans := make([]store.ScanFunc, s.bucketCount)
+	for i := range scans {
+		bucket := byte(i)
+		scans[i] = func(ctx context.Context) *store.Iterator {
+			return unsalted(ctx, scan(ctx, bucket))
+		}
+	}
+	return scans
+}
+
+// unsalted returns an iterator yielding the items of `it` with the salt stripped from their key
+func unsalted(ctx context.Context, it *store.Iterator) *store.Iterator {
+	kr := store.NewIterator(ctx)
+	go func() {
+		for it.Next() {
+			item := it.Item()
+			if len(item.Key) == 0 {
+				kr.PushError(fmt.Errorf("invalid salted key, missing salt"))
+				return
+			}
+
+			if !kr.PushItem(store.KV{Key: item.Key[1:], Value: item.Value}) {
+				return
+			}
+		}
+
+		if err := it.Err(); err != nil {
+			kr.PushError(err)
+			return
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
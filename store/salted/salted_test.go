@@ -0,0 +1,73 @@
+package salted
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "Salted", storetest.NewBadgerBackedFactory(t, "salted", "buckets=4"))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "salted", "buckets=4",
+		"buckets=0",
+		"buckets=257",
+		"buckets=many",
+	)
+}
+
+func TestSequentialKeys_Distribution(t *testing.T) {
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "salted", "buckets=8")
+	defer cleanup()
+
+	ctx := context.Background()
+	blockCount := 4000
+	var keys [][]byte
+	for blockNum := 0; blockNum < blockCount; blockNum++ {
+		key := make([]byte, 5)
+		key[0] = 'b'
+		binary.BigEndian.PutUint32(key[1:], uint32(blockNum))
+		keys = append(keys, key)
+
+		require.NoError(t, kvStore.Put(ctx, key, []byte(fmt.Sprintf("block %d", blockNum))))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	// Every bucket receives a fair share of the sequential keys
+	backing := kvStore.(*Store).backing
+	total := 0
+	for bucket := byte(0); bucket < 8; bucket++ {
+		count := len(collectKeys(t, backing.Prefix(ctx, []byte{bucket}, store.Unlimited, store.KeyOnly())))
+		assert.InDelta(t, blockCount/8, count, float64(blockCount/8)/4, "bucket %d", bucket)
+		total += count
+	}
+	assert.Equal(t, blockCount, total)
+
+	// Point lookups resolve without scanning
+	for _, blockNum := range []int{0, 1, blockCount / 2, blockCount - 1} {
+		value, err := kvStore.Get(ctx, keys[blockNum])
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("block %d", blockNum), string(value))
+	}
+
+	// Range scans fan out to all buckets and come back in key order
+	assert.Equal(t, keys[10:20], collectKeys(t, kvStore.Scan(ctx, keys[10], keys[20], store.Unlimited)))
+	assert.Equal(t, keys[:5], collectKeys(t, kvStore.Prefix(ctx, []byte("b"), 5)))
+}
+
+func collectKeys(t *testing.T, it *store.Iterator) (out [][]byte) {
+	for it.Next() {
+		out = append(out, it.Item().Key)
+	}
+	require.NoError(t, it.Err())
+	return out
+}
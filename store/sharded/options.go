@@ -0,0 +1,36 @@
+package sharded
+
+import (
+	"github.com/dfuse-io/kvdb/store"
+	"go.uber.org/zap"
+)
+
+func (s *Store) EnableEmpty() {
+	zlog.Info("forwarding possible empty value to all shards")
+	for _, shard := range s.shards {
+		if enabler, ok := shard.(store.EmtpyValueEnabler); ok {
+			enabler.EnableEmpty()
+		}
+	}
+}
+
+func (s *Store) SetLogger(logger *zap.Logger) {
+	for i, shard := range s.shards {
+		if setter, ok := shard.(store.LoggerSetter); ok {
+			setter.SetLogger(logger.With(zap.Int("shard", i)))
+		}
+	}
+}
+
+// Capabilities reports the capabilities supported by all the shards, `ReverseScan` and `Stats`
+// are never reported as the sharded store does not implement them itself.
+func (s *Store) Capabilities() store.Capabilities {
+	capabilities := store.Capabilities{EmptyValue: true, Insert: true, Increment: true}
+	for _, shard := range s.shards {
+		shardCapabilities := shard.Capabilities()
+		capabilities.EmptyValue = capabilities.EmptyValue && shardCapabilities.EmptyValue
+		capabilities.Insert = capabilities.Insert && shardCapabilities.Insert
+		capabilities.Increment = capabilities.Increment && shardCapabilities.Increment
+	}
+	return capabilities
+}
@@ -0,0 +1,299 @@
+package sharded
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Store distributes keys across multiple backing stores (shards), each key being owned by
+// a single shard picked from a hash of the key. Point operations (`Put`, `Get`, `BatchGet`,
+// `Exists`, `BatchExists`, `Delete` and `BatchDelete` for example) are routed to the owning
+// shard(s) only.
+//
+// Range operations (`Scan`, `Prefix` and `BatchPrefix`) have no way to know which shards
+// hold the keys, so they fan out to all shards and merge the results back in key order.
+// Those are hence more expensive than on a single store, every shard performing the scan
+// with the full limit.
+type Store struct {
+	dsn    string
+	shards []store.KVStore
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("sharded kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "sharded",
+		Title:       "Sharded",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports sharded://?backing=<url escaped dsn>&backing=<url escaped dsn>, the
+// order of the `backing` options is significant, it must stay the same across restarts
+// as it determines which shard owns a given key.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("sharded new: dsn: %w", err)
+	}
+
+	backingDSNs := dsn.Query()["backing"]
+	if len(backingDSNs) == 0 {
+		return nil, fmt.Errorf("sharded new: at least one 'backing' dsn is required")
+	}
+
+	shards := make([]store.KVStore, len(backingDSNs))
+	for i, backingDSN := range backingDSNs {
+		shards[i], err = store.New(backingDSN)
+		if err != nil {
+			closeAll(shards[:i])
+			return nil, fmt.Errorf("sharded new: shard #%d: %w", i, err)
+		}
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Int("shard_count", len(shards)))
+
+	return &Store{
+		dsn:    dsnString,
+		shards: shards,
+	}, nil
+}
+
+func (s *Store) Close() error {
+	return closeAll(s.shards)
+}
+
+func closeAll(shards []store.KVStore) (err error) {
+	for _, shard := range shards {
+		err = multierr.Append(err, shard.Close())
+	}
+	return err
+}
+
+func (s *Store) shardIndex(key []byte) int {
+	hasher := fnv.New64a()
+	hasher.Write(key)
+
+	return int(hasher.Sum64() % uint64(len(s.shards)))
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	return s.shards[s.shardIndex(key)].Put(ctx, key, value)
+}
+
+// Insert forwards to the owning shard, which must implement `store.Inserter`.
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	inserter, ok := s.shards[s.shardIndex(key)].(store.Inserter)
+	if !ok {
+		return fmt.Errorf("shard #%d does not support insert", s.shardIndex(key))
+	}
+
+	return inserter.Insert(ctx, key, value)
+}
+
+// Increment forwards to the owning shard, which must implement `store.Incrementer`.
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	incrementer, ok := s.shards[s.shardIndex(key)].(store.Incrementer)
+	if !ok {
+		return 0, fmt.Errorf("shard #%d does not support increment", s.shardIndex(key))
+	}
+
+	return incrementer.Increment(ctx, key, delta)
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	for i, shard := range s.shards {
+		if err := shard.FlushPuts(ctx); err != nil {
+			return fmt.Errorf("flush shard #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	return s.shards[s.shardIndex(key)].Get(ctx, key)
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	return s.shards[s.shardIndex(key)].ValueSize(ctx, key)
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	return s.shards[s.shardIndex(key)].Exists(ctx, key)
+}
+
+// BatchExists sends each shard its own keys in a single call, answers are then re-ordered to
+// respect the order of the received keys.
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	shardKeys := make([][][]byte, len(s.shards))
+	shardPositions := make([][]int, len(s.shards))
+	for i, key := range keys {
+		shardIndex := s.shardIndex(key)
+		shardKeys[shardIndex] = append(shardKeys[shardIndex], key)
+		shardPositions[shardIndex] = append(shardPositions[shardIndex], i)
+	}
+
+	exists = make([]bool, len(keys))
+	for shardIndex, shard := range s.shards {
+		if len(shardKeys[shardIndex]) == 0 {
+			continue
+		}
+
+		shardExists, err := shard.BatchExists(ctx, shardKeys[shardIndex])
+		if err != nil {
+			return nil, err
+		}
+
+		for i, position := range shardPositions[shardIndex] {
+			exists[position] = shardExists[i]
+		}
+	}
+	return exists, nil
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("batch get", zap.Int("key_count", len(keys)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		// Each shard receives its own keys in a single call, results are then re-ordered to
+		// respect the order of the received keys as required by `BatchGet`.
+		shardKeys := make([][][]byte, len(s.shards))
+		shardPositions := make([][]int, len(s.shards))
+		for i, key := range keys {
+			shardIndex := s.shardIndex(key)
+			shardKeys[shardIndex] = append(shardKeys[shardIndex], key)
+			shardPositions[shardIndex] = append(shardPositions[shardIndex], i)
+		}
+
+		results := make([]store.KV, len(keys))
+		for shardIndex, shard := range s.shards {
+			if len(shardKeys[shardIndex]) == 0 {
+				continue
+			}
+
+			it := shard.BatchGet(ctx, shardKeys[shardIndex], options...)
+			count := 0
+			for it.Next() {
+				results[shardPositions[shardIndex][count]] = it.Item()
+				count++
+			}
+
+			if err := it.Err(); err != nil {
+				kr.PushError(err)
+				return
+			}
+		}
+
+		for _, result := range results {
+			if !kr.PushItem(result) {
+				return
+			}
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	shardKeys := make([][][]byte, len(s.shards))
+	for _, key := range keys {
+		shardIndex := s.shardIndex(key)
+		shardKeys[shardIndex] = append(shardKeys[shardIndex], key)
+	}
+
+	for shardIndex, shard := range s.shards {
+		if len(shardKeys[shardIndex]) == 0 {
+			continue
+		}
+
+		if err := shard.BatchDelete(ctx, shardKeys[shardIndex]); err != nil {
+			return fmt.Errorf("batch delete shard #%d: %w", shardIndex, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	return s.shards[s.shardIndex(key)].Delete(ctx, key)
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("scanning", zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)))
+
+	return store.MergeScans(ctx, limit, s.shardScans(func(ctx context.Context, shard store.KVStore) *store.Iterator {
+		return shard.Scan(ctx, start, exclusiveEnd, limit, options...)
+	})...)
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("prefix scanning", zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)))
+
+	return store.MergeScans(ctx, limit, s.shardScans(func(ctx context.Context, shard store.KVStore) *store.Iterator {
+		return shard.Prefix(ctx, prefix, limit, options...)
+	})...)
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("batch prefix scanning", zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit", store.Limit(limit)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		count := uint64(0)
+		for _, prefix := range prefixes {
+			prefix := prefix
+
+			prefixLimit := store.Limit(limit)
+			if prefixLimit.Bounded() {
+				prefixLimit = store.Limit(uint64(limit) - count)
+			}
+
+			it := store.MergeScans(ctx, int(prefixLimit), s.shardScans(func(ctx context.Context, shard store.KVStore) *store.Iterator {
+				return shard.Prefix(ctx, prefix, int(prefixLimit), options...)
+			})...)
+
+			for it.Next() {
+				if !kr.PushItem(it.Item()) {
+					return
+				}
+				count++
+			}
+
+			if err := it.Err(); err != nil {
+				kr.PushError(err)
+				return
+			}
+
+			if store.Limit(limit).Reached(count) {
+				break
+			}
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+func (s *Store) shardScans(scan func(ctx context.Context, shard store.KVStore) *store.Iterator) []store.ScanFunc {
+	scans := make([]store.ScanFunc, len(s.shards))
+	for i, shard := range s.shards {
+		shard := shard
+		scans[i] = func(ctx context.Context) *store.Iterator {
+			return scan(ctx, shard)
+		}
+	}
+	return scans
+}
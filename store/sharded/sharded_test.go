@@ -0,0 +1,89 @@
+package sharded
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/dfuse-io/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logging.TestingOverride()
+}
+
+func TestAll(t *testing.T) {
+	storetest.TestAll(t, "Sharded", newTestShardedFactory(t, 3))
+}
+
+func newTestShardedFactory(t *testing.T, shardCount int) storetest.DriverFactory {
+	return func(opts ...store.Option) (store.KVStore, *storetest.DriverCapabilities, storetest.DriverCleanupFunc) {
+		dir, err := ioutil.TempDir("", "kvdb-sharded")
+		require.NoError(t, err)
+
+		kvStore, err := store.New(testShardedDSN(dir, shardCount), opts...)
+		require.NoError(t, err)
+
+		return kvStore, storetest.NewDriverCapabilities(), func() {
+			err := os.RemoveAll(dir)
+			require.NoError(t, err)
+		}
+	}
+}
+
+func testShardedDSN(dir string, shardCount int) string {
+	backings := make([]string, shardCount)
+	for i := 0; i < shardCount; i++ {
+		backings[i] = "backing=" + url.QueryEscape(fmt.Sprintf("badger://%s", path.Join(dir, fmt.Sprintf("shard-%d.db", i))))
+	}
+
+	return "sharded://?" + strings.Join(backings, "&")
+}
+
+func TestNewStore_NoBacking(t *testing.T) {
+	_, err := NewStore("sharded://")
+	require.Error(t, err)
+}
+
+func TestScan_AcrossShards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvdb-sharded")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kvStore, err := NewStore(testShardedDSN(dir, 3))
+	require.NoError(t, err)
+	defer kvStore.Close()
+
+	ctx := context.Background()
+	var expected []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%02d", i)
+		expected = append(expected, key)
+		require.NoError(t, kvStore.Put(ctx, []byte(key), []byte("value")))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	shardsUsed := map[int]bool{}
+	for _, key := range expected {
+		shardsUsed[kvStore.(*Store).shardIndex([]byte(key))] = true
+	}
+	require.Len(t, shardsUsed, 3, "test keys should be spread over all shards")
+
+	var keys []string
+	it := kvStore.Prefix(ctx, []byte("key"), 7)
+	for it.Next() {
+		keys = append(keys, string(it.Item().Key))
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, expected[:7], keys)
+}
@@ -0,0 +1,425 @@
+package shortkey
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"go.uber.org/zap"
+)
+
+// maxPrefixCount is the number of prefixes that can be mapped, codes 1 to 254 are used so
+// that `code + 1` always fits in a byte, `0x00` being reserved to flag keys left untouched.
+const maxPrefixCount = 254
+
+const unmappedCode = 0x00
+
+// Store wraps a backing store and shortens the keys starting with one of a known set of
+// prefixes, the prefix being replaced by a single byte code before reaching the backing store.
+// Keys matching none of the prefixes are stored prefixed by a `0x00` byte instead, so they
+// cost one more byte. This is transparent to callers, keys are mapped back when read.
+//
+// The encoding only preserves the key order within a mapped prefix and within the unmapped
+// key ranges between them. Range operations (`Scan`, `Prefix` and `BatchPrefix`) hence
+// split the requested range into those segments and scan them one after the other, in the
+// order of the original keys.
+type Store struct {
+	dsn     string
+	backing store.KVStore
+
+	// prefixes are indexed by `code - 1`
+	prefixes [][]byte
+	// segments partition the whole original key space in ascending order
+	segments []segment
+}
+
+// segment is a range of original keys, [start, end[, all encoded the same way, either all
+// starting with `prefix` and mapped to `code`, or all unmapped. A nil end means unbounded.
+type segment struct {
+	start  []byte
+	end    []byte
+	code   byte
+	prefix []byte
+}
+
+func (s *Store) String() string {
+	return fmt.Sprintf("short key kv store with dsn: %q", s.dsn)
+}
+
+func init() {
+	store.Register(&store.Registration{
+		Name:        "shortkey",
+		Title:       "Short Key",
+		FactoryFunc: NewStore,
+	})
+}
+
+// NewStore supports shortkey://?prefix=<hex>&prefix=<hex>&backing=<url escaped dsn>. Each
+// `prefix` receives a code from its position in the DSN, so prefixes can only be appended,
+// never reordered nor removed, once data has been written. No prefix can be a prefix of
+// another one.
+func NewStore(dsnString string) (store.KVStore, error) {
+	dsn, err := url.Parse(dsnString)
+	if err != nil {
+		return nil, fmt.Errorf("shortkey new: dsn: %w", err)
+	}
+
+	query := dsn.Query()
+	prefixes, err := parsePrefixes(query["prefix"])
+	if err != nil {
+		return nil, fmt.Errorf("shortkey new: %w", err)
+	}
+
+	backingDSN := query.Get("backing")
+	if backingDSN == "" {
+		return nil, fmt.Errorf("shortkey new: a 'backing' dsn is required")
+	}
+
+	backing, err := store.New(backingDSN)
+	if err != nil {
+		return nil, fmt.Errorf("shortkey new: backing store: %w", err)
+	}
+
+	zlog.Info("creating store instance", zap.String("dsn", dsnString), zap.Int("prefix_count", len(prefixes)))
+
+	return &Store{
+		dsn:      dsnString,
+		backing:  backing,
+		prefixes: prefixes,
+		segments: buildSegments(prefixes),
+	}, nil
+}
+
+func parsePrefixes(hexPrefixes []string) ([][]byte, error) {
+	if len(hexPrefixes) > maxPrefixCount {
+		return nil, fmt.Errorf("too many prefixes, got %d, at most %d are supported", len(hexPrefixes), maxPrefixCount)
+	}
+
+	prefixes := make([][]byte, len(hexPrefixes))
+	for i, hexPrefix := range hexPrefixes {
+		prefix, err := hex.DecodeString(hexPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", hexPrefix, err)
+		}
+		if len(prefix) == 0 {
+			return nil, fmt.Errorf("invalid prefix, it cannot be empty")
+		}
+		prefixes[i] = prefix
+	}
+
+	sorted := sortedPrefixes(prefixes)
+	for i := 1; i < len(sorted); i++ {
+		if bytes.HasPrefix(sorted[i], sorted[i-1]) {
+			return nil, fmt.Errorf("prefix %x overlaps with prefix %x", sorted[i], sorted[i-1])
+		}
+	}
+
+	return prefixes, nil
+}
+
+func sortedPrefixes(prefixes [][]byte) [][]byte {
+	sorted := make([][]byte, len(prefixes))
+	copy(sorted, prefixes)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return sorted
+}
+
+func buildSegments(prefixes [][]byte) (out []segment) {
+	codes := map[string]byte{}
+	for i, prefix := range prefixes {
+		codes[string(prefix)] = byte(i + 1)
+	}
+
+	cursor := []byte{}
+	for _, prefix := range sortedPrefixes(prefixes) {
+		if bytes.Compare(cursor, prefix) < 0 {
+			out = append(out, segment{start: cursor, end: prefix, code: unmappedCode})
+		}
+
+		cursor = prefixSuccessor(prefix)
+		out = append(out, segment{start: prefix, end: cursor, code: codes[string(prefix)], prefix: prefix})
+
+		// Only made of 0xFF bytes, no key can be greater than those starting with this prefix
+		if cursor == nil {
+			return out
+		}
+	}
+
+	return append(out, segment{start: cursor, end: nil, code: unmappedCode})
+}
+
+func (s *Store) encodeKey(key []byte) []byte {
+	for i, prefix := range s.prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return append([]byte{byte(i + 1)}, key[len(prefix):]...)
+		}
+	}
+
+	return append([]byte{unmappedCode}, key...)
+}
+
+func (s *Store) encodeKeys(keys [][]byte) [][]byte {
+	out := make([][]byte, len(keys))
+	for i, key := range keys {
+		out[i] = s.encodeKey(key)
+	}
+	return out
+}
+
+func (s *Store) decodeKey(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("invalid empty encoded key")
+	}
+
+	code := key[0]
+	if code == unmappedCode {
+		return key[1:], nil
+	}
+
+	if int(code) > len(s.prefixes) {
+		return nil, fmt.Errorf("unknown prefix code %d in key %s, was a prefix removed from the dsn?", code, store.Key(key))
+	}
+
+	prefix := s.prefixes[code-1]
+	decoded := make([]byte, len(prefix)+len(key)-1)
+	copy(decoded, prefix)
+	copy(decoded[len(prefix):], key[1:])
+
+	return decoded, nil
+}
+
+func (s *Store) Close() error {
+	return s.backing.Close()
+}
+
+func (s *Store) Put(ctx context.Context, key, value []byte) (err error) {
+	return s.backing.Put(ctx, s.encodeKey(key), value)
+}
+
+// Insert shortens the key like `Put` does, the backing store checking its existence.
+func (s *Store) Insert(ctx context.Context, key, value []byte) (err error) {
+	return store.Insert(ctx, s.backing, s.encodeKey(key), value)
+}
+
+// Increment shortens the key like `Put` does, counters being stored as-is.
+func (s *Store) Increment(ctx context.Context, key []byte, delta int64) (newValue int64, err error) {
+	return store.Increment(ctx, s.backing, s.encodeKey(key), delta)
+}
+
+func (s *Store) FlushPuts(ctx context.Context) error {
+	return s.backing.FlushPuts(ctx)
+}
+
+func (s *Store) Get(ctx context.Context, key []byte) (value []byte, err error) {
+	return s.backing.Get(ctx, s.encodeKey(key))
+}
+
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	return s.backing.ValueSize(ctx, s.encodeKey(key))
+}
+
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	return s.backing.Exists(ctx, s.encodeKey(key))
+}
+
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	return s.backing.BatchExists(ctx, s.encodeKeys(keys))
+}
+
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
+	kr := store.NewIterator(ctx)
+	go func() {
+		if s.pushDecoded(kr, s.backing.BatchGet(ctx, s.encodeKeys(keys), options...), nil) {
+			kr.PushFinished()
+		}
+	}()
+
+	return kr
+}
+
+func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) (err error) {
+	return s.backing.BatchDelete(ctx, s.encodeKeys(keys))
+}
+
+func (s *Store) Delete(ctx context.Context, key []byte) (err error) {
+	return s.backing.Delete(ctx, s.encodeKey(key))
+}
+
+func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("scanning", zap.Stringer("start", store.Key(start)), zap.Stringer("exclusive_end", store.Key(exclusiveEnd)), zap.Stringer("limit", store.Limit(limit)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		// Like the other stores, an empty exclusive end bounds nothing
+		if len(exclusiveEnd) == 0 {
+			kr.PushFinished()
+			return
+		}
+
+		count := uint64(0)
+		if s.scanSegments(ctx, kr, start, exclusiveEnd, store.Limit(limit), &count, options) {
+			kr.PushFinished()
+		}
+	}()
+
+	return kr
+}
+
+func (s *Store) Prefix(ctx context.Context, prefix []byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("prefix scanning", zap.Stringer("prefix", store.Key(prefix)), zap.Stringer("limit", store.Limit(limit)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		count := uint64(0)
+		if s.scanSegments(ctx, kr, prefix, prefixSuccessor(prefix), store.Limit(limit), &count, options) {
+			kr.PushFinished()
+		}
+	}()
+
+	return kr
+}
+
+func (s *Store) BatchPrefix(ctx context.Context, prefixes [][]byte, limit int, options ...store.ReadOption) *store.Iterator {
+	logging.Logger(ctx, zlog).Debug("batch prefix scanning", zap.Int("prefix_count", len(prefixes)), zap.Stringer("limit", store.Limit(limit)))
+
+	kr := store.NewIterator(ctx)
+	go func() {
+		count := uint64(0)
+		for _, prefix := range prefixes {
+			if !s.scanSegments(ctx, kr, prefix, prefixSuccessor(prefix), store.Limit(limit), &count, options) {
+				return
+			}
+
+			if store.Limit(limit).Reached(count) {
+				break
+			}
+		}
+
+		kr.PushFinished()
+	}()
+
+	return kr
+}
+
+// scanSegments pushes to `kr` the keys of the range [start, end[ (end being unbounded when
+// nil), scanning each segment overlapping the range in turn. The `count` of pushed items is
+// shared with the caller, so that `limit` can apply across multiple calls. Returns false if
+// the iteration must stop, either because an error was pushed or because the consumer is gone.
+func (s *Store) scanSegments(ctx context.Context, kr *store.Iterator, start, end []byte, limit store.Limit, count *uint64, options []store.ReadOption) bool {
+	for _, seg := range s.segments {
+		if limit.Reached(*count) {
+			return true
+		}
+
+		// Segments are sorted, none of the remaining ones overlaps the range
+		if end != nil && bytes.Compare(seg.start, end) >= 0 {
+			return true
+		}
+
+		low := maxKey(start, seg.start)
+		high := minEnd(end, seg.end)
+		if high != nil && bytes.Compare(low, high) >= 0 {
+			continue
+		}
+
+		segmentLimit := limit
+		if limit.Bounded() {
+			segmentLimit = store.Limit(uint64(limit) - *count)
+		}
+
+		it := s.backing.Scan(ctx, seg.encodeStart(low), seg.encodeEnd(high), int(segmentLimit), options...)
+		if !s.pushDecoded(kr, it, count) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pushDecoded pushes the items of `it` to `kr` with their keys decoded, incrementing `count`
+// when not nil. Returns false if the iteration must stop.
+func (s *Store) pushDecoded(kr *store.Iterator, it *store.Iterator, count *uint64) bool {
+	for it.Next() {
+		item := it.Item()
+		key, err := s.decodeKey(item.Key)
+		if err != nil {
+			kr.PushError(err)
+			return false
+		}
+
+		if !kr.PushItem(store.KV{Key: key, Value: item.Value}) {
+			return false
+		}
+
+		if count != nil {
+			*count++
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		kr.PushError(err)
+		return false
+	}
+
+	return true
+}
+
+// encodeStart encodes `key`, which must be within the segment
+func (seg segment) encodeStart(key []byte) []byte {
+	if seg.code == unmappedCode {
+		return append([]byte{unmappedCode}, key...)
+	}
+
+	return append([]byte{seg.code}, key[len(seg.prefix):]...)
+}
+
+// encodeEnd encodes the exclusive end `key`, which must be within the segment or be its end
+func (seg segment) encodeEnd(key []byte) []byte {
+	if seg.code != unmappedCode && (key == nil || bytes.Equal(key, seg.end)) {
+		return []byte{seg.code + 1}
+	}
+
+	// The last unmapped segment is unbounded
+	if key == nil {
+		return []byte{unmappedCode + 1}
+	}
+
+	return seg.encodeStart(key)
+}
+
+func maxKey(a, b []byte) []byte {
+	if bytes.Compare(a, b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// minEnd returns the lowest of two exclusive ends, nil meaning unbounded
+func minEnd(a, b []byte) []byte {
+	if a == nil {
+		return b
+	}
+	if b == nil || bytes.Compare(a, b) < 0 {
+		return a
+	}
+	return b
+}
+
+// prefixSuccessor returns the smallest key greater than all keys starting with `prefix`, or
+// nil when there is none (empty prefix or only 0xFF bytes).
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			successor := make([]byte, i+1)
+			copy(successor, prefix)
+			successor[i]++
+			return successor
+		}
+	}
+	return nil
+}
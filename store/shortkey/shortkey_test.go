@@ -0,0 +1,96 @@
+package shortkey
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	_ "github.com/dfuse-io/kvdb/store/badger"
+	"github.com/dfuse-io/kvdb/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	// Maps "ba" and "c", which the test keys start with
+	storetest.TestAll(t, "ShortKey", storetest.NewBadgerBackedFactory(t, "shortkey", "prefix=6261&prefix=63"))
+}
+
+func TestNewStore_InvalidOptions(t *testing.T) {
+	storetest.TestBackedInvalidOptions(t, "shortkey", "prefix=01",
+		"prefix=zz",
+		"prefix=",
+		"prefix=0102&prefix=01",
+	)
+}
+
+func TestKeys_RoundTrip(t *testing.T) {
+	s := &Store{prefixes: [][]byte{{0x02, 0xaa}, {0x01}, {0xff, 0xff}}}
+
+	tests := []struct {
+		key     []byte
+		encoded []byte
+	}{
+		{[]byte{0x02, 0xaa, 0x01, 0x02}, []byte{0x01, 0x01, 0x02}},
+		{[]byte{0x02, 0xaa}, []byte{0x01}},
+		{[]byte{0x01, 0x05}, []byte{0x02, 0x05}},
+		{[]byte{0xff, 0xff, 0x00}, []byte{0x03, 0x00}},
+		{[]byte{0x02, 0xab}, []byte{0x00, 0x02, 0xab}},
+		{[]byte{0x00}, []byte{0x00, 0x00}},
+		{[]byte{}, []byte{0x00}},
+	}
+
+	for _, test := range tests {
+		encoded := s.encodeKey(test.key)
+		assert.Equal(t, test.encoded, encoded, "key %x", test.key)
+
+		decoded, err := s.decodeKey(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, test.key, decoded, "key %x", test.key)
+	}
+
+	_, err := s.decodeKey([]byte{0x04, 0x01})
+	assert.Error(t, err)
+}
+
+func TestScan_Ordering(t *testing.T) {
+	// Mapped prefixes are listed in a different order than their key order on purpose
+	kvStore, cleanup := storetest.NewBadgerBackedStore(t, "shortkey", "prefix=ff&prefix=02aa&prefix=01&prefix=02ab")
+	defer cleanup()
+
+	ctx := context.Background()
+	var keys [][]byte
+	for _, key := range [][]byte{
+		{0x00}, {0x00, 0xff}, {0x01}, {0x01, 0x00}, {0x01, 0xff}, {0x01, 0xff, 0xff}, {0x02},
+		{0x02, 0x01}, {0x02, 0xaa}, {0x02, 0xaa, 0x00}, {0x02, 0xaa, 0xff}, {0x02, 0xab, 0x01},
+		{0x02, 0xac}, {0x03}, {0xfe, 0xff}, {0xff}, {0xff, 0x00}, {0xff, 0xff, 0xff},
+	} {
+		keys = append(keys, key)
+		require.NoError(t, kvStore.Put(ctx, key, []byte{0x01}))
+	}
+	require.NoError(t, kvStore.FlushPuts(ctx))
+
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	// Keys starting with the first prefix (0xff) are stored with it replaced by code 0x01
+	assert.Equal(t, [][]byte{{0x01}, {0x01, 0x00}, {0x01, 0xff, 0xff}}, collectKeys(t, kvStore.(*Store).backing.Prefix(ctx, []byte{0x01}, store.Unlimited)))
+
+	assert.Equal(t, keys, collectKeys(t, kvStore.Prefix(ctx, nil, store.Unlimited)))
+	assert.Equal(t, keys[:7], collectKeys(t, kvStore.Prefix(ctx, nil, 7)))
+	assert.Equal(t, keys[1:17], collectKeys(t, kvStore.Scan(ctx, []byte{0x00, 0x01}, []byte{0xff, 0xff}, store.Unlimited)))
+	assert.Equal(t, keys[3:8], collectKeys(t, kvStore.Scan(ctx, []byte{0x01, 0x00}, []byte{0x02, 0x01, 0x00}, store.Unlimited)))
+	assert.Equal(t, keys[6:13], collectKeys(t, kvStore.Prefix(ctx, []byte{0x02}, store.Unlimited)))
+	assert.Equal(t, keys[8:11], collectKeys(t, kvStore.Prefix(ctx, []byte{0x02, 0xaa}, store.Unlimited)))
+	assert.Equal(t, keys[15:], collectKeys(t, kvStore.Prefix(ctx, []byte{0xff}, store.Unlimited)))
+	assert.Equal(t, [][]byte{keys[9], keys[10], keys[15]}, collectKeys(t, kvStore.BatchPrefix(ctx, [][]byte{{0x02, 0xaa, 0x00}, {0x02, 0xaa, 0xff}, {0xff}}, 3)))
+}
+
+func collectKeys(t *testing.T, it *store.Iterator) (out [][]byte) {
+	for it.Next() {
+		out = append(out, it.Item().Key)
+	}
+	require.NoError(t, it.Err())
+	return out
+}
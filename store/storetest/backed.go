@@ -0,0 +1,63 @@
+package storetest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/dfuse-io/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loggingOverride applies `logging.TestingOverride` once the loggers of the tested packages are
+// registered, which the `init` of this package would run too early for
+var loggingOverride sync.Once
+
+// NewBadgerBackedStore creates the wrapper store registered as `scheme` with the `query` DSN
+// options, over a badger store living in a temporary directory that the returned cleanup
+// function removes. This package does not depend on the badger driver, callers must import it.
+func NewBadgerBackedStore(t testing.TB, scheme, query string, opts ...store.Option) (store.KVStore, DriverCleanupFunc) {
+	loggingOverride.Do(logging.TestingOverride)
+
+	dir, err := ioutil.TempDir("", "kvdb-"+scheme)
+	require.NoError(t, err)
+
+	backing := url.QueryEscape(fmt.Sprintf("badger://%s", path.Join(dir, "test.db")))
+	kvStore, err := store.New(scheme+"://?"+query+"&backing="+backing, opts...)
+	require.NoError(t, err)
+
+	return kvStore, func() {
+		kvStore.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// NewBadgerBackedFactory is a `DriverFactory` of `NewBadgerBackedStore` stores, to run `TestAll`
+// against a wrapper store.
+func NewBadgerBackedFactory(t testing.TB, scheme, query string) DriverFactory {
+	return func(opts ...store.Option) (store.KVStore, *DriverCapabilities, DriverCleanupFunc) {
+		kvStore, cleanup := NewBadgerBackedStore(t, scheme, query, opts...)
+		return kvStore, NewDriverCapabilities(), cleanup
+	}
+}
+
+// TestBackedInvalidOptions checks that the wrapper store registered as `scheme` rejects the
+// otherwise valid `query` without a backing store, and each of `invalidQueries` with one. The
+// backing store never gets opened, options being checked first.
+func TestBackedInvalidOptions(t *testing.T, scheme, query string, invalidQueries ...string) {
+	dsns := []string{scheme + "://?" + query}
+	for _, invalidQuery := range invalidQueries {
+		dsns = append(dsns, scheme+"://?"+invalidQuery+"&backing="+url.QueryEscape("badger:///tmp/none"))
+	}
+
+	for _, dsn := range dsns {
+		_, err := store.New(dsn)
+		assert.Error(t, err, dsn)
+	}
+}
@@ -0,0 +1,171 @@
+package storetest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/dfuse-io/kvdb/store"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkValueSizes are representative of the values stored, small index rows on one end
+// and block or transaction payloads on the other.
+var benchmarkValueSizes = []int{128, 4096}
+
+// benchmarkKeyCount is the number of keys loaded before read benchmarks
+const benchmarkKeyCount = 10000
+
+// benchmarkPrefixSize is the number of keys under each prefix scanned by the prefix benchmark
+const benchmarkPrefixSize = 100
+
+const benchmarkBatchSize = 100
+
+// benchmarkFlushEvery is the number of puts between flushes, kept low enough that a flush of
+// the largest values fits in a single netkv request
+const benchmarkFlushEvery = 500
+
+var kvstoreBenchmarks = []struct {
+	name  string
+	bench func(b *testing.B, driver store.KVStore, valueSize int)
+}{
+	{
+		name:  "sequential puts",
+		bench: benchmarkSequentialPuts,
+	},
+	{
+		name:  "random gets",
+		bench: benchmarkRandomGets,
+	},
+	{
+		name:  "prefix scans",
+		bench: benchmarkPrefixScans,
+	},
+	{
+		name:  "batch gets",
+		bench: benchmarkBatchGets,
+	},
+}
+
+// Benchmark runs the standard benchmarks against the store created by `driverFactory`, a fresh
+// store being created for each benchmark and value size, so that backends can be compared on
+// the same workloads.
+func Benchmark(b *testing.B, driverFactory DriverFactory) {
+	for _, benchmark := range kvstoreBenchmarks {
+		for _, valueSize := range benchmarkValueSizes {
+			b.Run(fmt.Sprintf("%s/%dB", benchmark.name, valueSize), func(b *testing.B) {
+				driver, _, closer := driverFactory()
+				defer closer()
+				defer driver.Close()
+
+				benchmark.bench(b, driver, valueSize)
+			})
+		}
+	}
+}
+
+func benchmarkSequentialPuts(b *testing.B, driver store.KVStore, valueSize int) {
+	ctx := context.Background()
+	value := benchmarkValue(rand.New(rand.NewSource(1)), valueSize)
+
+	b.SetBytes(int64(valueSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, driver.Put(ctx, benchmarkKey(i), value))
+		if (i+1)%benchmarkFlushEvery == 0 {
+			require.NoError(b, driver.FlushPuts(ctx))
+		}
+	}
+	require.NoError(b, driver.FlushPuts(ctx))
+}
+
+func benchmarkRandomGets(b *testing.B, driver store.KVStore, valueSize int) {
+	ctx := context.Background()
+	random := loadBenchmarkKeys(b, driver, valueSize)
+
+	b.SetBytes(int64(valueSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := driver.Get(ctx, benchmarkKey(random.Intn(benchmarkKeyCount)))
+		require.NoError(b, err)
+	}
+}
+
+func benchmarkPrefixScans(b *testing.B, driver store.KVStore, valueSize int) {
+	ctx := context.Background()
+	random := loadBenchmarkKeys(b, driver, valueSize)
+
+	// Keys are big endian numbers, all but their last byte form a prefix of 256 keys, of which
+	// the first `benchmarkPrefixSize` are scanned
+	prefixCount := benchmarkKeyCount / 256
+
+	b.SetBytes(int64(valueSize * benchmarkPrefixSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prefix := benchmarkKey(random.Intn(prefixCount) * 256)[:7]
+
+		count := 0
+		it := driver.Prefix(ctx, prefix, benchmarkPrefixSize)
+		for it.Next() {
+			count++
+		}
+		require.NoError(b, it.Err())
+		require.Equal(b, benchmarkPrefixSize, count)
+	}
+}
+
+func benchmarkBatchGets(b *testing.B, driver store.KVStore, valueSize int) {
+	ctx := context.Background()
+	random := loadBenchmarkKeys(b, driver, valueSize)
+
+	keys := make([][]byte, benchmarkBatchSize)
+
+	b.SetBytes(int64(valueSize * benchmarkBatchSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range keys {
+			keys[j] = benchmarkKey(random.Intn(benchmarkKeyCount))
+		}
+
+		count := 0
+		it := driver.BatchGet(ctx, keys)
+		for it.Next() {
+			count++
+		}
+		require.NoError(b, it.Err())
+		require.Equal(b, benchmarkBatchSize, count)
+	}
+}
+
+// loadBenchmarkKeys writes `benchmarkKeyCount` keys with values of `valueSize` bytes and
+// returns the random source to pick keys from.
+func loadBenchmarkKeys(b *testing.B, driver store.KVStore, valueSize int) *rand.Rand {
+	ctx := context.Background()
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < benchmarkKeyCount; i++ {
+		require.NoError(b, driver.Put(ctx, benchmarkKey(i), benchmarkValue(random, valueSize)))
+		if (i+1)%benchmarkFlushEvery == 0 {
+			require.NoError(b, driver.FlushPuts(ctx))
+		}
+	}
+	require.NoError(b, driver.FlushPuts(ctx))
+
+	return random
+}
+
+func benchmarkKey(i int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(i))
+	return key
+}
+
+// benchmarkValue returns random bytes, so that compression does not make values unrealistically
+// cheap to store
+func benchmarkValue(random *rand.Rand, size int) []byte {
+	value := make([]byte, size)
+	random.Read(value)
+	return value
+}
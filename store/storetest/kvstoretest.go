@@ -3,9 +3,12 @@ package storetest
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dfuse-io/kvdb/store"
 	"github.com/stretchr/testify/assert"
@@ -38,6 +41,65 @@ var kvstoreTests = []struct {
 			enableEmptyValue: true,
 		},
 	},
+	{
+		name: "capabilities",
+		test: testCapabilities,
+		options: kvStoreOptions{
+			enableEmptyValue: true,
+		},
+	},
+	{
+		name: "insert",
+		test: testInsert,
+	},
+	{
+		name: "increment",
+		test: testIncrement,
+	},
+	{
+		name: "compare and swap",
+		test: testCompareAndSwap,
+	},
+	{
+		name: "compare and swap with ttl",
+		test: testCompareAndSwapTTL,
+	},
+	{
+		name: "lease",
+		test: testLease,
+	},
+	{
+		name: "delete and exists",
+		test: testDeleteExists,
+	},
+	{
+		name: "batch exists",
+		test: testBatchExists,
+	},
+	{
+		name: "seek",
+		test: testSeek,
+	},
+	{
+		name: "rename prefix",
+		test: testRenamePrefix,
+	},
+	{
+		name: "apply",
+		test: testApply,
+	},
+	{
+		name: "replace prefix",
+		test: testReplacePrefix,
+	},
+	{
+		name: "multi prefix",
+		test: testMultiPrefix,
+	},
+	{
+		name: "key distribution",
+		test: testKeyDistribution,
+	},
 	{
 		name: "purgeable",
 		test: testPurgeable,
@@ -196,6 +258,16 @@ func testBasic(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvSt
 	_, err = driver.Get(context.Background(), []byte("keydoesnotexists"))
 	require.Equal(t, store.ErrNotFound, err)
 
+	// testing ValueSize, test drivers use the identity compressor so stored size is the written one
+	for _, kv := range all {
+		size, err := driver.ValueSize(context.Background(), kv.Key)
+		require.NoError(t, err)
+		require.Equal(t, len(kv.Value), size, "value size of key %q", string(kv.Key))
+	}
+
+	_, err = driver.ValueSize(context.Background(), []byte("keydoesnotexists"))
+	require.Equal(t, store.ErrNotFound, err)
+
 	// testing Prefix without limit
 	testPrefix(t, driver, nil, store.Unlimited, all)
 	testPrefix(t, driver, []byte("a"), store.Unlimited, all[:1])
@@ -314,7 +386,8 @@ func testEmtpyValue(t *testing.T, driver store.KVStore, capabilities *DriverCapa
 		require.Equal(t, err, store.ErrNotFound)
 	} else {
 		require.NoError(t, err)
-		require.Equal(t, []byte(nil), v)
+		require.NotNil(t, v, "an empty value must be returned as a zero-length, non-nil slice")
+		require.Equal(t, []byte{}, v)
 	}
 
 	var got []store.KV
@@ -328,8 +401,626 @@ func testEmtpyValue(t *testing.T, driver store.KVStore, capabilities *DriverCapa
 		require.Len(t, got, 0)
 	} else {
 		require.Len(t, got, 1)
-		require.Equal(t, []byte(nil), got[0].Value)
+		require.NotNil(t, got[0].Value, "an empty value must be returned as a zero-length, non-nil slice")
+		require.Equal(t, []byte{}, got[0].Value)
+	}
+
+	if canAddEmptyValue {
+		otherKey := []byte("randomkey2")
+		require.NoError(t, driver.Put(context.Background(), otherKey, []byte{}))
+		require.NoError(t, driver.FlushPuts(context.Background()))
+
+		got = nil
+		it = driver.BatchGet(context.Background(), [][]byte{key, otherKey})
+		for it.Next() {
+			got = append(got, it.Item())
+		}
+		require.NoError(t, it.Err())
+		require.Equal(t, []store.KV{{Key: key, Value: []byte{}}, {Key: otherKey, Value: []byte{}}}, got)
+
+		testPrefix(t, driver, []byte("randomkey"), store.Unlimited, []store.KV{{Key: key, Value: []byte{}}, {Key: otherKey, Value: []byte{}}})
+	}
+}
+
+func testCapabilities(t *testing.T, driver store.KVStore, _ *DriverCapabilities, options kvStoreOptions) {
+	capabilities := driver.Capabilities()
+
+	if options.enableEmptyValue {
+		assert.True(t, capabilities.EmptyValue, "empty values must be supported once store.WithEmptyValue is used")
 	}
+
+	err := driver.BatchDelete(context.Background(), [][]byte{[]byte("missing")})
+	assert.Equal(t, capabilities.ReadOnly, errors.Is(err, store.ErrReadOnly), "ReadOnly capability must match writes failing with store.ErrReadOnly")
+
+	_, ok := driver.(store.Inserter)
+	assert.Equal(t, capabilities.Insert, ok, "Insert capability must match store.Inserter implementation")
+
+	_, ok = driver.(store.Incrementer)
+	assert.Equal(t, capabilities.Increment, ok, "Increment capability must match store.Incrementer implementation")
+
+	_, ok = driver.(store.CompareAndSwapper)
+	assert.Equal(t, capabilities.CompareAndSwap, ok, "CompareAndSwap capability must match store.CompareAndSwapper implementation")
+
+	_, ok = driver.(store.TTLCompareAndSwapper)
+	assert.Equal(t, capabilities.CompareAndSwapTTL, ok, "CompareAndSwapTTL capability must match store.TTLCompareAndSwapper implementation")
+
+	_, ok = driver.(store.ReversibleKVStore)
+	assert.Equal(t, capabilities.ReverseScan, ok, "ReverseScan capability must match store.ReversibleKVStore implementation")
+
+	_, ok = driver.(store.StatsReporter)
+	assert.Equal(t, capabilities.Stats, ok, "Stats capability must match store.StatsReporter implementation")
+
+	_, ok = driver.(store.Streamer)
+	assert.Equal(t, capabilities.Stream, ok, "Stream capability must match store.Streamer implementation")
+
+	_, ok = driver.(store.PrefixRenamer)
+	assert.Equal(t, capabilities.RenamePrefix, ok, "RenamePrefix capability must match store.PrefixRenamer implementation")
+
+	_, ok = driver.(store.PrefixReplacer)
+	assert.Equal(t, capabilities.ReplacePrefix, ok, "ReplacePrefix capability must match store.PrefixReplacer implementation")
+
+	_, ok = driver.(store.Seeker)
+	assert.Equal(t, capabilities.Seek, ok, "Seek capability must match store.Seeker implementation")
+
+	_, ok = driver.(store.Applier)
+	assert.Equal(t, capabilities.Apply, ok, "Apply capability must match store.Applier implementation")
+}
+
+func testDeleteExists(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	require.NoError(t, driver.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, driver.Put(ctx, []byte("b"), []byte("2")))
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	exists, err := driver.Exists(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = driver.Exists(ctx, []byte("missing"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, driver.Delete(ctx, []byte("a")))
+
+	exists, err = driver.Exists(ctx, []byte("a"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = driver.Get(ctx, []byte("a"))
+	require.Equal(t, store.ErrNotFound, err)
+
+	// Only the given key is deleted, and deleting a missing key is not an error
+	value, err := driver.Get(ctx, []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+
+	require.NoError(t, driver.Delete(ctx, []byte("missing")))
+}
+
+func testBatchExists(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	for _, key := range []string{"a", "c", "e"} {
+		require.NoError(t, driver.Put(ctx, []byte(key), []byte("v"+key)))
+	}
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	// Answers follow the order of the keys, which is not the key order, and repeat for duplicates
+	keys := [][]byte{[]byte("e"), []byte("b"), []byte("a"), []byte("d"), []byte("c"), []byte("a")}
+	exists, err := driver.BatchExists(ctx, keys)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true, false, true, true}, exists)
+
+	exists, err = driver.BatchExists(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, exists, 0)
+
+	// The same lookups through `BatchGet` without values, missing keys remain fatal there
+	for _, keys := range [][][]byte{{[]byte("c")}, {[]byte("e"), []byte("a"), []byte("c")}} {
+		var got []store.KV
+		it := driver.BatchGet(ctx, keys, store.KeyOnly())
+		for it.Next() {
+			got = append(got, it.Item())
+		}
+		require.NoError(t, it.Err())
+
+		require.Len(t, got, len(keys))
+		for i, kv := range got {
+			assert.Equal(t, keys[i], kv.Key)
+			assert.Nil(t, kv.Value, "key-only items must have a nil value")
+		}
+	}
+}
+
+func testSeek(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	seeker, ok := driver.(store.Seeker)
+	if !ok {
+		t.Skip("driver does not implement store.Seeker")
+	}
+
+	// Keys with gaps between them, and one key being a prefix of another
+	ctx := context.Background()
+	for _, key := range []string{"b", "d", "d1", "f"} {
+		require.NoError(t, driver.Put(ctx, []byte(key), []byte("v"+key)))
+	}
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	tests := []struct {
+		key   string
+		floor string
+		ceil  string
+	}{
+		{key: "a", floor: "", ceil: "b"},
+		{key: "b", floor: "b", ceil: "b"},
+		{key: "c", floor: "b", ceil: "d"},
+		{key: "d", floor: "d", ceil: "d"},
+		{key: "d0", floor: "d", ceil: "d1"},
+		{key: "d2", floor: "d1", ceil: "f"},
+		{key: "e", floor: "d1", ceil: "f"},
+		{key: "f", floor: "f", ceil: "f"},
+		{key: "g", floor: "f", ceil: ""},
+	}
+
+	for _, test := range tests {
+		floor, err := seeker.SeekFloor(ctx, []byte(test.key))
+		if test.floor == "" {
+			assert.Equal(t, store.ErrNotFound, err, "floor of %q", test.key)
+		} else {
+			require.NoError(t, err, "floor of %q", test.key)
+			assert.Equal(t, store.KV{Key: []byte(test.floor), Value: []byte("v" + test.floor)}, floor, "floor of %q", test.key)
+		}
+
+		ceil, err := seeker.SeekCeil(ctx, []byte(test.key))
+		if test.ceil == "" {
+			assert.Equal(t, store.ErrNotFound, err, "ceil of %q", test.key)
+		} else {
+			require.NoError(t, err, "ceil of %q", test.key)
+			assert.Equal(t, store.KV{Key: []byte(test.ceil), Value: []byte("v" + test.ceil)}, ceil, "ceil of %q", test.key)
+		}
+	}
+}
+
+func testRenamePrefix(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	put := func(kvs ...store.KV) {
+		for _, kv := range kvs {
+			require.NoError(t, driver.Put(ctx, kv.Key, kv.Value))
+		}
+		require.NoError(t, driver.FlushPuts(ctx))
+	}
+
+	// Current index, a new one is built under a temporary prefix then promoted
+	put(
+		store.KV{Key: []byte("idx:a"), Value: []byte("old-a")},
+		store.KV{Key: []byte("idx:z"), Value: []byte("old-z")},
+		store.KV{Key: []byte("idy"), Value: []byte("untouched")},
+		store.KV{Key: []byte("tmp:a"), Value: []byte("new-a")},
+		store.KV{Key: []byte("tmp:b"), Value: []byte("new-b")},
+	)
+
+	require.NoError(t, store.RenamePrefix(ctx, driver, []byte("tmp:"), []byte("idx:")))
+
+	assert.Equal(t, []store.KV{
+		{Key: []byte("idx:a"), Value: []byte("new-a")},
+		{Key: []byte("idx:b"), Value: []byte("new-b")},
+	}, readAll(t, driver.Prefix(ctx, []byte("idx:"), store.Unlimited)))
+	assert.Len(t, readAll(t, driver.Prefix(ctx, []byte("tmp:"), store.Unlimited)), 0)
+
+	value, err := driver.Get(ctx, []byte("idy"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("untouched"), value)
+
+	// Renaming an empty prefix clears the destination
+	require.NoError(t, store.RenamePrefix(ctx, driver, []byte("tmp:"), []byte("idx:")))
+	assert.Len(t, readAll(t, driver.Prefix(ctx, []byte("idx:"), store.Unlimited)), 0)
+
+	// Pending puts are renamed along, or deleted when under the destination
+	require.NoError(t, driver.Put(ctx, []byte("tmp:pending"), []byte("moved")))
+	require.NoError(t, driver.Put(ctx, []byte("idx:stale"), []byte("stale")))
+	require.NoError(t, store.RenamePrefix(ctx, driver, []byte("tmp:"), []byte("idx:")))
+	require.NoError(t, driver.FlushPuts(ctx))
+	assert.Equal(t, []store.KV{{Key: []byte("idx:pending"), Value: []byte("moved")}}, readAll(t, driver.Prefix(ctx, []byte("idx:"), store.Unlimited)))
+	assert.Len(t, readAll(t, driver.Prefix(ctx, []byte("tmp:"), store.Unlimited)), 0)
+	require.NoError(t, driver.BatchDelete(ctx, [][]byte{[]byte("idx:pending")}))
+
+	assert.Error(t, store.RenamePrefix(ctx, driver, []byte("idx:"), []byte("idx:sub")))
+	assert.Error(t, store.RenamePrefix(ctx, driver, []byte("idx:sub"), []byte("idx:")))
+}
+
+func testApply(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	for _, key := range []string{"blk:orphan:a", "blk:orphan:b", "blk:shared"} {
+		require.NoError(t, driver.Put(ctx, []byte(key), []byte("orphan")))
+	}
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	// A reorg replaces the rows of the orphaned block, a key both deleted and put is kept
+	require.NoError(t, store.Apply(ctx, driver,
+		[]store.KV{
+			{Key: []byte("blk:replacement:a"), Value: []byte("replacement")},
+			{Key: []byte("blk:shared"), Value: []byte("replacement")},
+		},
+		[][]byte{[]byte("blk:orphan:a"), []byte("blk:orphan:b"), []byte("blk:shared"), []byte("blk:missing")},
+	))
+
+	assert.Equal(t, []store.KV{
+		{Key: []byte("blk:replacement:a"), Value: []byte("replacement")},
+		{Key: []byte("blk:shared"), Value: []byte("replacement")},
+	}, readAll(t, driver.Prefix(ctx, []byte("blk:"), store.Unlimited)))
+
+	require.NoError(t, store.Apply(ctx, driver, nil, nil))
+
+	// A pending put of a deleted key must not write it back once flushed
+	require.NoError(t, driver.Put(ctx, []byte("blk:pending"), []byte("pending")))
+	require.NoError(t, store.Apply(ctx, driver, nil, [][]byte{[]byte("blk:pending")}))
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	_, err := driver.Get(ctx, []byte("blk:pending"))
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func testReplacePrefix(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	for _, key := range []string{"idx:alice", "idx:bob", "idxother", "acc:alice"} {
+		require.NoError(t, driver.Put(ctx, []byte(key), []byte("old")))
+	}
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	// Rebuilding the index drops its stale keys, a key both stale and rebuilt is kept
+	require.NoError(t, store.ReplacePrefix(ctx, driver, []byte("idx:"), []store.KV{
+		{Key: []byte("idx:bob"), Value: []byte("new")},
+		{Key: []byte("idx:carol"), Value: []byte("new")},
+	}))
+
+	assert.Equal(t, []store.KV{
+		{Key: []byte("acc:alice"), Value: []byte("old")},
+		{Key: []byte("idx:bob"), Value: []byte("new")},
+		{Key: []byte("idx:carol"), Value: []byte("new")},
+		{Key: []byte("idxother"), Value: []byte("old")},
+	}, readAll(t, driver.Scan(ctx, []byte("a"), []byte("j"), store.Unlimited)))
+
+	// Keys outside of the prefix are rejected, nothing is replaced
+	err := store.ReplacePrefix(ctx, driver, []byte("idx:"), []store.KV{{Key: []byte("acc:bob"), Value: []byte("new")}})
+	assert.Error(t, err)
+	assert.Len(t, readAll(t, driver.Prefix(ctx, []byte("idx:"), store.Unlimited)), 2)
+
+	// A pending put under the prefix is replaced too, it must not land over the replacement
+	require.NoError(t, driver.Put(ctx, []byte("idx:pending"), []byte("old")))
+	require.NoError(t, store.ReplacePrefix(ctx, driver, []byte("idx:"), nil))
+	require.NoError(t, driver.FlushPuts(ctx))
+	assert.Len(t, readAll(t, driver.Prefix(ctx, []byte("idx:"), store.Unlimited)), 0)
+}
+
+func testKeyDistribution(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+
+	// Block numbers are big-endian, the first two bytes group them by ranges of 65536 blocks
+	put := func(blockNum uint32) {
+		key := make([]byte, 8)
+		copy(key, "blk:")
+		binary.BigEndian.PutUint32(key[4:], blockNum)
+		require.NoError(t, driver.Put(ctx, key, []byte("block")))
+	}
+	for blockNum := uint32(0); blockNum < 10; blockNum++ {
+		put(blockNum)
+	}
+	for blockNum := uint32(0x00010000); blockNum < 0x00010003; blockNum++ {
+		put(blockNum)
+	}
+	put(0x00050000)
+	require.NoError(t, driver.Put(ctx, []byte("blk:\x00"), []byte("short")))
+	require.NoError(t, driver.Put(ctx, []byte("other"), []byte("outside")))
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	counts, err := store.KeyDistribution(ctx, driver, []byte("blk:"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"0000": 10, "0001": 3, "0005": 1, "00": 1}, counts)
+
+	counts, err = store.KeyDistribution(ctx, driver, []byte("blk:"), 4)
+	require.NoError(t, err)
+	assert.Len(t, counts, 15)
+	assert.Equal(t, uint64(1), counts["00050000"])
+
+	_, err = store.KeyDistribution(ctx, driver, []byte("blk:"), 0)
+	assert.Error(t, err)
+}
+
+func testMultiPrefix(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	ctx := context.Background()
+	for _, key := range []string{"act:alice:1", "act:alice:2", "act:alice:3", "act:bob:1", "act:carol:1", "act:carol:2", "act:carol:3", "act:carol:4"} {
+		require.NoError(t, driver.Put(ctx, []byte(key), []byte(key)))
+	}
+	require.NoError(t, driver.FlushPuts(ctx))
+
+	keys := func(it *store.Iterator) (out []string) {
+		for _, kv := range readAll(t, it) {
+			out = append(out, string(kv.Key))
+		}
+		return out
+	}
+
+	// Grouped in the order of the prefixes, each one limited on its own
+	prefixes := [][]byte{[]byte("act:carol:"), []byte("act:alice:"), []byte("act:dave:"), []byte("act:bob:")}
+	assert.Equal(t, []string{
+		"act:carol:1", "act:carol:2", "act:carol:3",
+		"act:alice:1", "act:alice:2", "act:alice:3",
+		"act:bob:1",
+	}, keys(store.MultiPrefix(ctx, driver, prefixes, 3)))
+
+	assert.Equal(t, []string{"act:carol:1", "act:carol:2", "act:carol:3", "act:carol:4", "act:alice:1", "act:alice:2", "act:alice:3", "act:bob:1"}, keys(store.MultiPrefix(ctx, driver, prefixes, store.Unlimited)))
+
+	for _, kv := range readAll(t, store.MultiPrefix(ctx, driver, prefixes, 1, store.KeyOnly())) {
+		assert.Nil(t, kv.Value)
+	}
+
+	it := store.MultiPrefix(ctx, driver, [][]byte{[]byte("act:"), []byte("act:bob:")}, store.Unlimited)
+	for it.Next() {
+	}
+	assert.Error(t, it.Err())
+}
+
+func readAll(t *testing.T, it *store.Iterator) (out []store.KV) {
+	for it.Next() {
+		out = append(out, it.Item())
+	}
+	require.NoError(t, it.Err())
+	return out
+}
+
+func testInsert(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	inserter, ok := driver.(store.Inserter)
+	if !ok {
+		t.Skip("driver does not implement store.Inserter")
+	}
+
+	key := []byte("insertkey")
+
+	// New key
+	err := inserter.Insert(context.Background(), key, []byte("first"))
+	require.NoError(t, err)
+
+	v, err := driver.Get(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), v)
+
+	// Existing key, the value must be left untouched
+	err = inserter.Insert(context.Background(), key, []byte("second"))
+	require.Equal(t, store.ErrKeyExists, err)
+
+	v, err = driver.Get(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), v)
+
+	// Key written through a flushed `Put` also exists
+	putKey := []byte("insertputkey")
+	require.NoError(t, driver.Put(context.Background(), putKey, []byte("put")))
+	require.NoError(t, driver.FlushPuts(context.Background()))
+
+	err = inserter.Insert(context.Background(), putKey, []byte("insert"))
+	require.Equal(t, store.ErrKeyExists, err)
+}
+
+func testIncrement(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	incrementer, ok := driver.(store.Incrementer)
+	if !ok {
+		t.Skip("driver does not implement store.Incrementer")
+	}
+
+	key := []byte("counter")
+
+	// Missing key starts at zero
+	value, err := incrementer.Increment(context.Background(), key, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), value)
+
+	value, err = incrementer.Increment(context.Background(), key, -7)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), value)
+
+	raw, err := driver.Get(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE}, raw)
+
+	// Concurrent increments must all be accounted for
+	concurrentKey := []byte("concurrentcounter")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				_, err := incrementer.Increment(context.Background(), concurrentKey, 1)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err = incrementer.Increment(context.Background(), concurrentKey, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(200), value)
+
+	// Values that are not counters are rejected
+	require.NoError(t, driver.Put(context.Background(), []byte("notcounter"), []byte("abc")))
+	require.NoError(t, driver.FlushPuts(context.Background()))
+
+	_, err = incrementer.Increment(context.Background(), []byte("notcounter"), 1)
+	require.Error(t, err)
+}
+
+func testCompareAndSwap(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	swapper, ok := driver.(store.CompareAndSwapper)
+	if !ok {
+		t.Skip("driver does not implement store.CompareAndSwapper")
+	}
+
+	ctx := context.Background()
+	key := []byte("caskey")
+
+	// A `nil` old value expects the key not to exist
+	swapped, err := swapper.CompareAndSwap(ctx, key, nil, []byte("first"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	swapped, err = swapper.CompareAndSwap(ctx, key, nil, []byte("other"))
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	// A mismatching old value leaves the stored value untouched
+	swapped, err = swapper.CompareAndSwap(ctx, key, []byte("wrong"), []byte("other"))
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	v, err := driver.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), v)
+
+	swapped, err = swapper.CompareAndSwap(ctx, key, []byte("first"), []byte("second"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	v, err = driver.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), v)
+
+	// A `nil` new value deletes the key
+	swapped, err = swapper.CompareAndSwap(ctx, key, []byte("second"), nil)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	_, err = driver.Get(ctx, key)
+	require.Equal(t, store.ErrNotFound, err)
+
+	// A missing key does not match an existing old value
+	swapped, err = swapper.CompareAndSwap(ctx, key, []byte("second"), []byte("third"))
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	// Concurrent swaps from the same old value have a single winner
+	concurrentKey := []byte("concurrentcaskey")
+	winners := make(chan int, 8)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			swapped, err := swapper.CompareAndSwap(ctx, concurrentKey, nil, []byte{byte(i)})
+			assert.NoError(t, err)
+			if swapped {
+				winners <- i
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(winners)
+
+	require.Len(t, winners, 1)
+	v, err = driver.Get(ctx, concurrentKey)
+	require.NoError(t, err)
+	require.Equal(t, []byte{byte(<-winners)}, v)
+}
+
+func testCompareAndSwapTTL(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	swapper, ok := driver.(store.TTLCompareAndSwapper)
+	if !ok {
+		t.Skip("driver does not implement store.TTLCompareAndSwapper")
+	}
+
+	ctx := context.Background()
+
+	// A long ttl keeps the value around, and the key can be swapped again
+	key := []byte("casttlkey")
+	swapped, err := swapper.CompareAndSwapWithTTL(ctx, key, nil, []byte("first"), time.Hour)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	swapped, err = swapper.CompareAndSwapWithTTL(ctx, key, []byte("first"), []byte("second"), time.Hour)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	v, err := driver.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), v)
+
+	// Once expired, the key behaves as if it was deleted, stores might round the ttl up
+	expiringKey := []byte("casttlexpiringkey")
+	swapped, err = swapper.CompareAndSwapWithTTL(ctx, expiringKey, nil, []byte("first"), time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	require.Eventually(t, func() bool {
+		_, err := driver.Get(ctx, expiringKey)
+		return err == store.ErrNotFound
+	}, 5*time.Second, 50*time.Millisecond)
+
+	swapped, err = swapper.CompareAndSwapWithTTL(ctx, expiringKey, nil, []byte("second"), time.Hour)
+	require.NoError(t, err)
+	require.True(t, swapped)
+}
+
+func testLease(t *testing.T, driver store.KVStore, _ *DriverCapabilities, _ kvStoreOptions) {
+	if _, ok := driver.(store.CompareAndSwapper); !ok {
+		t.Skip("driver does not implement store.CompareAndSwapper")
+	}
+
+	ctx := context.Background()
+	key := []byte("leasekey")
+
+	// Contention, only one holder wins
+	acquired := make(chan string, 8)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(holder string) {
+			defer wg.Done()
+			ok, err := store.AcquireLease(ctx, driver, key, holder, time.Minute)
+			assert.NoError(t, err)
+			if ok {
+				acquired <- holder
+			}
+		}(fmt.Sprintf("holder-%d", i))
+	}
+	wg.Wait()
+	close(acquired)
+
+	require.Len(t, acquired, 1)
+	winner := <-acquired
+
+	// Renewal is reserved to the holder
+	ok, err := store.RenewLease(ctx, driver, key, winner, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.RenewLease(ctx, driver, key, "other", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = store.ReleaseLease(ctx, driver, key, "other")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = store.ReleaseLease(ctx, driver, key, winner)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Expired leases are taken over, their previous holder can no longer renew them
+	ok, err = store.AcquireLease(ctx, driver, key, "first", 50*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.AcquireLease(ctx, driver, key, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Stores expiring leases themselves might round their ttl up
+	require.Eventually(t, func() bool {
+		ok, err := store.AcquireLease(ctx, driver, key, "second", time.Minute)
+		assert.NoError(t, err)
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+
+	ok, err = store.RenewLease(ctx, driver, key, "first", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
 }
 
 func testPrefix(t *testing.T, driver store.KVStore, prefix []byte, limit int, exp []store.KV, options ...store.ReadOption) {
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+)
+
+// GetStream returns a reader over the value of `key`, streamed when `kv` implements `Streamer`
+// and fully read through `Get` otherwise. Returns `store.ErrNotFound` if the key does not
+// exist. The returned reader must always be closed.
+func GetStream(ctx context.Context, kv KVStore, key []byte) (io.ReadCloser, error) {
+	if streamer, ok := kv.(Streamer); ok {
+		return streamer.GetStream(ctx, key)
+	}
+
+	value, err := kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(value)), nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testGetOnlyDriver struct {
+	TestKVDBDriver
+	values map[string][]byte
+}
+
+func (d *testGetOnlyDriver) Get(ctx context.Context, key []byte) ([]byte, error) {
+	value, found := d.values[string(key)]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func TestGetStream_BufferedFallback(t *testing.T) {
+	driver := &testGetOnlyDriver{values: map[string][]byte{"a": []byte("value")}}
+
+	reader, err := GetStream(context.Background(), driver, []byte("a"))
+	require.NoError(t, err)
+	defer reader.Close()
+
+	value, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	_, err = GetStream(context.Background(), driver, []byte("b"))
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -59,7 +59,23 @@ func (t *TestKVDBDriver) Get(ctx context.Context, key []byte) (value []byte, err
 	panic("test driver, not callable")
 }
 
-func (t *TestKVDBDriver) BatchGet(ctx context.Context, keys [][]byte) *Iterator {
+func (t *TestKVDBDriver) BatchGet(ctx context.Context, keys [][]byte, options ...ReadOption) *Iterator {
+	panic("test driver, not callable")
+}
+
+func (t *TestKVDBDriver) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	panic("test driver, not callable")
+}
+
+func (t *TestKVDBDriver) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	panic("test driver, not callable")
+}
+
+func (t *TestKVDBDriver) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	panic("test driver, not callable")
+}
+
+func (t *TestKVDBDriver) Delete(ctx context.Context, key []byte) (err error) {
 	panic("test driver, not callable")
 }
 
@@ -79,6 +95,10 @@ func (t *TestKVDBDriver) BatchDelete(ctx context.Context, keys [][]byte) (err er
 	panic("test driver, not callable")
 }
 
+func (t *TestKVDBDriver) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
 func (t *TestKVDBDriver) Close() error {
 	return nil
 }
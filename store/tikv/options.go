@@ -1,6 +1,15 @@
 package tikv
 
+import "github.com/dfuse-io/kvdb/store"
+
 func (s *Store) EnableEmpty() {
 	zlog.Info("enabling possible empty value on store implementation")
 	s.emptyValuePossible = true
 }
+
+func (s *Store) Capabilities() store.Capabilities {
+	return store.Capabilities{
+		EmptyValue: s.emptyValuePossible,
+		Stats:      true,
+	}
+}
@@ -95,6 +95,14 @@ func NewStore(dsnString string) (store.KVStore, error) {
 		return nil, fmt.Errorf("new compressor: %w", err)
 	}
 
+	if compressionStats, _ := dsnQuery.StringOption("compression_stats", ""); compressionStats == "true" {
+		reporter, ok := compressor.(store.CompressionStatsReporter)
+		if !ok {
+			return nil, fmt.Errorf("compression stats option requires a compression mode, got %q", compression)
+		}
+		reporter.EnableStats()
+	}
+
 	// Use batch size threshold (in bytes) if present, otherwise use ~7MiB
 	batchSizeThreshold, rawValue, err := dsnQuery.IntOption("batch_size_threshold", 7*1024*1024)
 	if err != nil {
@@ -140,6 +148,18 @@ func NewStore(dsnString string) (store.KVStore, error) {
 	return s, nil
 }
 
+// Stats reports the cumulative compression statistics when `compression_stats=true` is
+// part of the DSN, zero values otherwise.
+func (s *Store) Stats() store.Stats {
+	reporter, ok := s.compressor.(store.CompressionStatsReporter)
+	if !ok {
+		return store.Stats{}
+	}
+
+	in, out := reporter.Stats()
+	return store.Stats{CompressionInBytes: in, CompressionOutBytes: out}
+}
+
 func (s *Store) Close() error {
 	return s.client.Close()
 }
@@ -218,7 +238,58 @@ func (s *Store) Get(ctx context.Context, key []byte) ([]byte, error) {
 	return val, nil
 }
 
-func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
+// ValueSize needs to fetch the raw value since TiKV has no way to only get its size, it is still
+// not decompressed. The size includes the empty value marker when `WithEmptyValue` is used.
+func (s *Store) ValueSize(ctx context.Context, key []byte) (size int, err error) {
+	val, err := s.client.Get(ctx, s.withPrefix(key))
+	if err != nil {
+		return 0, err
+	}
+
+	if val == nil {
+		return 0, store.ErrNotFound
+	}
+
+	return len(val), nil
+}
+
+// Exists needs to fetch the raw value since TiKV has no way to only check for a key.
+func (s *Store) Exists(ctx context.Context, key []byte) (exists bool, err error) {
+	val, err := s.client.Get(ctx, s.withPrefix(key))
+	if err != nil {
+		return false, err
+	}
+
+	return val != nil, nil
+}
+
+// BatchExists needs to fetch the raw values since TiKV has no way to only check for keys, they
+// are all fetched in a single batch.
+func (s *Store) BatchExists(ctx context.Context, keys [][]byte) (exists []bool, err error) {
+	prefixedKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		prefixedKeys[i] = s.withPrefix(key)
+	}
+
+	rawValues, err := s.client.BatchGet(ctx, prefixedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rawValues) != len(keys) {
+		return nil, fmt.Errorf("no enough values received from cluster, have %d keys but got only %d values", len(keys), len(rawValues))
+	}
+
+	exists = make([]bool, len(keys))
+	for i, rawValue := range rawValues {
+		exists[i] = rawValue != nil
+	}
+	return exists, nil
+}
+
+// BatchGet still fetches the values with `KeyOnly()` since TiKV has no way to only check for
+// keys, they are dropped before reaching the caller.
+func (s *Store) BatchGet(ctx context.Context, keys [][]byte, options ...store.ReadOption) *store.Iterator {
 	if traceEnabled {
 		logging.Debug(ctx, zlog, "batch get", zap.Int("key_count", len(keys)))
 	}
@@ -228,6 +299,8 @@ func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
 		prefixedKeys[i] = s.withPrefix(key)
 	}
 
+	keyOnly := tikvScanOption(options).KeyOnly
+
 	kr := store.NewIterator(ctx)
 	go func() {
 		rawValues, err := s.client.BatchGet(ctx, prefixedKeys)
@@ -249,6 +322,10 @@ func (s *Store) BatchGet(ctx context.Context, keys [][]byte) *store.Iterator {
 				return
 			}
 
+			if keyOnly {
+				value = nil
+			}
+
 			if !kr.PushItem(store.KV{Key: key, Value: value}) {
 				break
 			}
@@ -267,6 +344,10 @@ func (s *Store) BatchDelete(ctx context.Context, keys [][]byte) error {
 	return s.client.BatchDelete(ctx, prefixedKeys)
 }
 
+func (s *Store) Delete(ctx context.Context, key []byte) error {
+	return s.client.Delete(ctx, s.withPrefix(key))
+}
+
 func (s *Store) Scan(ctx context.Context, start, exclusiveEnd []byte, limit int, options ...store.ReadOption) *store.Iterator {
 	zlogger := logging.Logger(ctx, zlog)
 	if traceEnabled {
@@ -474,9 +555,9 @@ func (s *Store) unformatValue(v []byte) (out []byte, err error) {
 
 	byteCount := len(v)
 	if s.emptyValuePossible && byteCount >= 1 {
-		// We have a single byte and about to strip 1 byte, results in a nil output
+		// We have a single byte and about to strip 1 byte, results in an empty output
 		if byteCount == 1 {
-			return nil, nil
+			return []byte{}, nil
 		}
 
 		return v[0 : byteCount-1], nil
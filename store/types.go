@@ -19,6 +19,63 @@ func (kv *KV) Size() int {
 	return len(kv.Key) + len(kv.Value)
 }
 
+// Capabilities lists the optional features a `KVStore` supports. Each flag set to `true`
+// guarantees the store implements the matching optional interface.
+type Capabilities struct {
+	// EmptyValue is true when 0-length values can be written, either natively or because
+	// `WithEmptyValue` was used.
+	EmptyValue bool
+	// Insert is true when the store implements `Inserter`.
+	Insert bool
+	// Increment is true when the store implements `Incrementer`.
+	Increment bool
+	// CompareAndSwap is true when the store implements `CompareAndSwapper`.
+	CompareAndSwap bool
+	// CompareAndSwapTTL is true when the store implements `TTLCompareAndSwapper`.
+	CompareAndSwapTTL bool
+	// ReverseScan is true when the store implements `ReversibleKVStore`.
+	ReverseScan bool
+	// Stats is true when the store implements `StatsReporter`.
+	Stats bool
+	// Stream is true when the store implements `Streamer`.
+	Stream bool
+	// RenamePrefix is true when the store implements `PrefixRenamer`.
+	RenamePrefix bool
+	// ReplacePrefix is true when the store implements `PrefixReplacer`.
+	ReplacePrefix bool
+	// Seek is true when the store implements `Seeker`.
+	Seek bool
+	// Apply is true when the store implements `Applier`, mutations given to `store.Apply` are
+	// then applied atomically.
+	Apply bool
+	// ReadOnly is true when the store rejects all writes with `ErrReadOnly`.
+	ReadOnly bool
+	// MultiPrefix is true when the store implements `MultiPrefixer`.
+	MultiPrefix bool
+}
+
+// Intersect returns the capabilities set in both `c` and `other`. Wrapper stores report the
+// capabilities of their backing store intersected with the ones they forward, the optional
+// interfaces they do not implement themselves must never be reported.
+func (c Capabilities) Intersect(other Capabilities) Capabilities {
+	return Capabilities{
+		EmptyValue:        c.EmptyValue && other.EmptyValue,
+		Insert:            c.Insert && other.Insert,
+		Increment:         c.Increment && other.Increment,
+		CompareAndSwap:    c.CompareAndSwap && other.CompareAndSwap,
+		CompareAndSwapTTL: c.CompareAndSwapTTL && other.CompareAndSwapTTL,
+		ReverseScan:       c.ReverseScan && other.ReverseScan,
+		Stats:             c.Stats && other.Stats,
+		Stream:            c.Stream && other.Stream,
+		RenamePrefix:      c.RenamePrefix && other.RenamePrefix,
+		ReplacePrefix:     c.ReplacePrefix && other.ReplacePrefix,
+		Seek:              c.Seek && other.Seek,
+		Apply:             c.Apply && other.Apply,
+		ReadOnly:          c.ReadOnly && other.ReadOnly,
+		MultiPrefix:       c.MultiPrefix && other.MultiPrefix,
+	}
+}
+
 type Key []byte
 
 func (k Key) String() string {
@@ -86,3 +143,22 @@ func (l Limit) String() string {
 
 	return strconv.FormatInt(int64(l), 10)
 }
+
+// Stats holds runtime statistics reported by a store implementing `StatsReporter`.
+type Stats struct {
+	// CompressionInBytes is the cumulative number of value bytes handed to the compressor.
+	CompressionInBytes uint64
+	// CompressionOutBytes is the cumulative number of value bytes produced by the compressor.
+	CompressionOutBytes uint64
+}
+
+// CompressionRatio returns the achieved compression ratio, i.e. the compressed size over
+// the original size. A value of 1.0 means no gain at all, it's also the value returned
+// when no bytes were recorded yet.
+func (s Stats) CompressionRatio() float64 {
+	if s.CompressionInBytes == 0 {
+		return 1.0
+	}
+
+	return float64(s.CompressionOutBytes) / float64(s.CompressionInBytes)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"net/url"
 	"strconv"
 	"time"
@@ -56,6 +57,60 @@ func removeDSNOptionsFromURL(dsnURL *url.URL, keys []string) {
 	dsnURL.RawQuery = query.Encode()
 }
 
+// DSN is the parsed form of a store DSN, `<scheme>://<location>?<options>`, where the scheme is
+// the name of a registered store.
+type DSN struct {
+	Scheme string
+	// Location is everything between `://` and the options, unescaped: a file path for
+	// `badger`, a host and port for `netkv`, a project, instance and table for `bigkv`, etc.
+	Location string
+	Options  url.Values
+}
+
+// BuildDSN assembles a DSN from its parts, escaping the location and the options, like the
+// DSN of a backing store passed as an option of a wrapper store. Options are sorted by name
+// so the same parts always give the same DSN. Use `DSN.String()` for options given more than
+// once.
+func BuildDSN(scheme, location string, opts map[string]string) string {
+	options := url.Values{}
+	for name, value := range opts {
+		options.Set(name, value)
+	}
+
+	return (&DSN{Scheme: scheme, Location: location, Options: options}).String()
+}
+
+// ParseDSN splits `dsn` into its parts, unescaping them.
+func ParseDSN(dsn string) (*DSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse dsn: %w", err)
+	}
+
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("parse dsn: missing scheme in %q", dsn)
+	}
+
+	return &DSN{
+		Scheme:   u.Scheme,
+		Location: u.Host + u.Path,
+		Options:  u.Query(),
+	}, nil
+}
+
+// Query gives access to the typed option getters of `DSNQuery`
+func (d *DSN) Query() DSNQuery {
+	return DSNQuery(d.Options)
+}
+
+func (d *DSN) String() string {
+	dsn := d.Scheme + "://" + (&url.URL{Path: d.Location}).EscapedPath()
+	if len(d.Options) > 0 {
+		dsn += "?" + d.Options.Encode()
+	}
+	return dsn
+}
+
 type DSNQuery url.Values
 
 func (q DSNQuery) StringOption(name string, defaultValue string) (value string, rawValue string) {
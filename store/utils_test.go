@@ -1,6 +1,7 @@
 package store
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,3 +40,55 @@ func TestRemoveDSNOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		scheme   string
+		location string
+		opts     map[string]string
+		expected string
+	}{
+		{"badger absolute path", "badger", "/data/kvdb/db", nil, "badger:///data/kvdb/db"},
+		{"badger relative path", "badger", "./db", nil, "badger://./db"},
+		{"badger options", "badger", "/data/db", map[string]string{"compression": "zstd", "block_cache_size": "1024"}, "badger:///data/db?block_cache_size=1024&compression=zstd"},
+		{"badger escaped path", "badger", "/data/my db#1", nil, "badger:///data/my%20db%231"},
+		{"netkv", "netkv", "localhost:6789", map[string]string{"insecure": "true"}, "netkv://localhost:6789?insecure=true"},
+		{"bigkv", "bigkv", "project.instance/table", map[string]string{"createTable": "true"}, "bigkv://project.instance/table?createTable=true"},
+		{"wrapper", "ratelimit", "", map[string]string{"rate": "100", "backing": "badger:///data/db?compression=zstd"}, "ratelimit://?backing=badger%3A%2F%2F%2Fdata%2Fdb%3Fcompression%3Dzstd&rate=100"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dsn := BuildDSN(test.scheme, test.location, test.opts)
+			assert.Equal(t, test.expected, dsn)
+
+			parsed, err := ParseDSN(dsn)
+			require.NoError(t, err)
+			assert.Equal(t, test.scheme, parsed.Scheme)
+			assert.Equal(t, test.location, parsed.Location)
+			for name, value := range test.opts {
+				assert.Equal(t, value, parsed.Options.Get(name), name)
+			}
+			assert.Equal(t, dsn, parsed.String())
+		})
+	}
+}
+
+func TestParseDSN_RepeatedOptions(t *testing.T) {
+	dsn := (&DSN{Scheme: "sharded", Options: url.Values{"backing": {
+		BuildDSN("badger", "/data/shard0", nil),
+		BuildDSN("badger", "/data/shard1", nil),
+	}}}).String()
+
+	parsed, err := ParseDSN(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"badger:///data/shard0", "badger:///data/shard1"}, parsed.Options["backing"])
+
+	value, _, err := parsed.Query().IntOption("missing", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, value)
+
+	_, err = ParseDSN("/data/db")
+	assert.Error(t, err)
+}